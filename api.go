@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vulpemventures/go-elements/address"
+	"github.com/vulpemventures/go-elements/network"
+	"github.com/vulpemventures/go-elements/taproot"
+)
+
+const (
+	defaultOrdersLimit = 50
+	maxOrdersLimit     = 500
+)
+
+// API serves the versioned JSON API under /api/v1. Errors are returned as
+// {"error": "message"} along with the matching HTTP status.
+type API struct {
+	repo         OrderRepository
+	walletSvc    WalletService
+	rates        RatesClient
+	net          *network.Network
+	orderExpiry  time.Duration
+	confidential bool
+}
+
+func NewAPI(repo OrderRepository, walletSvc WalletService, rates RatesClient, net *network.Network, orderExpiry time.Duration, confidential bool) *API {
+	return &API{
+		repo:         repo,
+		walletSvc:    walletSvc,
+		rates:        rates,
+		net:          net,
+		orderExpiry:  orderExpiry,
+		confidential: confidential,
+	}
+}
+
+func (a *API) Register(router gin.IRouter) {
+	v1 := router.Group("/api/v1")
+	v1.GET("/markets", a.listMarkets)
+	v1.GET("/quote", a.quote)
+	v1.POST("/orders", a.createOrder)
+	v1.GET("/orders", a.listOrders)
+	v1.GET("/orders/:id", a.getOrder)
+}
+
+type MarketResponse struct {
+	Pair         string  `json:"pair"`
+	BaseAsset    string  `json:"base_asset"`
+	QuoteAsset   string  `json:"quote_asset"`
+	BaseAssetID  string  `json:"base_asset_id"`
+	QuoteAssetID string  `json:"quote_asset_id"`
+	BuyLimit     uint64  `json:"buy_limit"`
+	SellLimit    uint64  `json:"sell_limit"`
+	Price        float64 `json:"price,omitempty"`
+	Bid          float64 `json:"bid,omitempty"`
+	Ask          float64 `json:"ask,omitempty"`
+	// added to the ask to buy and taken from the bid to sell
+	SpreadPercentage float64 `json:"spread_percentage"`
+	// the fee policy of the market, charged in the quote asset
+	BuyFeePercentage  float64           `json:"buy_fee_percentage"`
+	SellFeePercentage float64           `json:"sell_fee_percentage"`
+	FixedFee          float64           `json:"fixed_fee,omitempty"`
+	FeeTiers          []FeeTierResponse `json:"fee_tiers,omitempty"`
+	// halted while no fresh price is available, see HaltReason
+	Halted     bool   `json:"halted"`
+	HaltReason string `json:"halt_reason,omitempty"`
+}
+
+type FeeTierResponse struct {
+	MinAmount         float64 `json:"min_amount"`
+	BuyFeePercentage  float64 `json:"buy_fee_percentage"`
+	SellFeePercentage float64 `json:"sell_fee_percentage"`
+}
+
+type QuoteResponse struct {
+	Pair          string     `json:"pair"`
+	Type          string     `json:"type"`
+	Amount        float64    `json:"amount"`
+	Price         float64    `json:"price"`
+	FeePercentage float64    `json:"fee_percentage"`
+	Fee           AssetValue `json:"fee"`
+	Input         AssetValue `json:"input"`
+	Output        AssetValue `json:"output"`
+}
+
+type CreateOrderRequest struct {
+	Pair             string  `json:"pair"`
+	Type             string  `json:"type"`
+	Amount           float64 `json:"amount"`
+	TraderScript     string  `json:"trader_script"`
+	MakerPubKey      string  `json:"maker_pubkey"`
+	MakerBlindingKey string  `json:"maker_blinding_key"`
+	PartialFill      bool    `json:"partial_fill"`
+}
+
+// AssetValue is an amount of an asset, both in satoshis and in units of its
+// currency.
+type AssetValue struct {
+	Currency string  `json:"currency"`
+	AssetID  string  `json:"asset_id"`
+	Amount   uint64  `json:"amount"`
+	Value    float64 `json:"value"`
+}
+
+type LeafResponse struct {
+	Name     string `json:"name"`
+	Script   string `json:"script"`
+	LeafHash string `json:"leaf_hash"`
+}
+
+type StatusResponse struct {
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+	TxID      string    `json:"txid,omitempty"`
+}
+
+type OrderResponse struct {
+	ID                 string           `json:"id"`
+	Network            string           `json:"network"`
+	Address            string           `json:"address"`
+	Status             string           `json:"status"`
+	CreatedAt          time.Time        `json:"created_at"`
+	ExpiresAt          *time.Time       `json:"expires_at,omitempty"`
+	Input              AssetValue       `json:"input"`
+	Output             AssetValue       `json:"output"`
+	Fee                *AssetValue      `json:"fee,omitempty"`
+	FilledAmount       uint64           `json:"filled_amount"`
+	PartialFill        bool             `json:"partial_fill"`
+	Confidential       bool             `json:"confidential"`
+	MismatchedDeposits int              `json:"mismatched_deposits"`
+	ScriptPubKey       string           `json:"script_pubkey"`
+	TraderScript       string           `json:"trader_script"`
+	MakerPubKey        string           `json:"maker_pubkey,omitempty"`
+	Leaves             []LeafResponse   `json:"leaves"`
+	Statuses           []StatusResponse `json:"statuses,omitempty"`
+}
+
+func (a *API) listMarkets(c *gin.Context) {
+	response, err := a.markets(c.Request.Context())
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"markets": response})
+}
+
+// markets returns the markets with their limits, prices and fee policy, as
+// served by both the JSON and the gRPC APIs.
+func (a *API) markets(ctx context.Context) ([]MarketResponse, error) {
+	markets, err := GetMarketsWithLimits(ctx, a.walletSvc)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]MarketResponse, 0, len(markets))
+	for _, mkt := range markets {
+		// the prices are left out while the market is halted
+		ticker, err := a.rates.MarketTicker(mkt.BaseAsset, mkt.QuoteAsset)
+		haltReason := ""
+		if err != nil {
+			haltReason = err.Error()
+		}
+		tiers := []FeeTierResponse{}
+		for _, tier := range mkt.Fee.Tiers {
+			tiers = append(tiers, FeeTierResponse{
+				MinAmount:         tier.MinAmount,
+				BuyFeePercentage:  tier.BuyPercentageFee,
+				SellFeePercentage: tier.SellPercentageFee,
+			})
+		}
+		response = append(response, MarketResponse{
+			Pair:              mkt.BaseAsset + "/" + mkt.QuoteAsset,
+			BaseAsset:         mkt.BaseAsset,
+			QuoteAsset:        mkt.QuoteAsset,
+			BaseAssetID:       currencyToAsset[mkt.BaseAsset].AssetHash,
+			QuoteAssetID:      currencyToAsset[mkt.QuoteAsset].AssetHash,
+			BuyLimit:          mkt.BuyLimit,
+			SellLimit:         mkt.SellLimit,
+			Price:             ticker.Ask,
+			Bid:               ticker.Bid,
+			Ask:               ticker.Ask,
+			SpreadPercentage:  mkt.SpreadPercentage,
+			BuyFeePercentage:  mkt.Fee.BuyPercentageFee,
+			SellFeePercentage: mkt.Fee.SellPercentageFee,
+			FixedFee:          mkt.Fee.FixedFee,
+			FeeTiers:          tiers,
+			Halted:            err != nil,
+			HaltReason:        haltReason,
+		})
+	}
+	return response, nil
+}
+
+func (a *API) quote(c *gin.Context) {
+	amount, err := strconv.ParseFloat(c.Query("amount"), 64)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, fmt.Errorf("invalid amount: %s", c.Query("amount")))
+		return
+	}
+
+	pair := c.Query("pair")
+	tradeType := c.Query("type")
+	quote, status, err := a.quoteTrade(pair, tradeType, amount)
+	if err != nil {
+		apiError(c, status, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, QuoteResponse{
+		Pair:          pair,
+		Type:          tradeType,
+		Amount:        amount,
+		Price:         quote.Price,
+		FeePercentage: quote.FeePercentage,
+		Fee:           currencyValue(quote.FeeCurrency, quote.Fee),
+		Input:         currencyValue(quote.InputCurrency, quote.InputValue),
+		Output:        currencyValue(quote.OutputCurrency, quote.OutputValue),
+	})
+}
+
+func (a *API) createOrder(c *gin.Context) {
+	var req CreateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiError(c, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if req.TraderScript == "" {
+		apiError(c, http.StatusBadRequest, fmt.Errorf("missing trader_script"))
+		return
+	}
+	// confidential orders need Ocean's blinded addresses to blind our outputs
+	if req.MakerBlindingKey != "" && !a.confidential {
+		apiError(c, http.StatusBadRequest, fmt.Errorf("confidential trades are not enabled"))
+		return
+	}
+
+	quote, status, err := a.quoteTrade(req.Pair, req.Type, req.Amount)
+	if err != nil {
+		apiError(c, status, err)
+		return
+	}
+
+	order, status, err := a.newOrder(req.Pair, req.TraderScript, req.MakerPubKey, req.MakerBlindingKey, req.PartialFill, quote)
+	if err != nil {
+		apiError(c, status, err)
+		return
+	}
+
+	err = saveAndWatchOrder(c.Request.Context(), a.repo, a.walletSvc, order)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response, err := newOrderResponse(order, "Pending")
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+func (a *API) getOrder(c *gin.Context) {
+	order, status, err := a.repo.FetchOrderByID(c.Param("id"))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !order.IsOnNetwork(a.net)) {
+		apiError(c, http.StatusNotFound, fmt.Errorf("order not found"))
+		return
+	}
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response, err := newOrderResponse(order, status)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	statuses, err := a.repo.FetchOrderStatuses(order.ID)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Statuses = make([]StatusResponse, 0, len(statuses))
+	for _, change := range statuses {
+		response.Statuses = append(response.Statuses, StatusResponse{
+			Status:    change.Status,
+			Timestamp: change.Timestamp.UTC(),
+			TxID:      change.TxHash,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (a *API) listOrders(c *gin.Context) {
+	limit, err := queryInt(c, "limit", defaultOrdersLimit)
+	if err != nil || limit <= 0 || limit > maxOrdersLimit {
+		apiError(c, http.StatusBadRequest, fmt.Errorf("limit must be between 1 and %d", maxOrdersLimit))
+		return
+	}
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil || offset < 0 {
+		apiError(c, http.StatusBadRequest, fmt.Errorf("offset must be a non-negative integer"))
+		return
+	}
+
+	orders, err := a.repo.ListOrders(a.net.Name, c.Query("status"), limit, offset)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := make([]*OrderResponse, 0, len(orders))
+	for _, o := range orders {
+		order, err := newOrderResponse(o.Order, o.Status)
+		if err != nil {
+			apiError(c, http.StatusInternalServerError, err)
+			return
+		}
+		response = append(response, order)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"orders": response,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// quoteTrade validates the pair, trade type and amount and quotes the trade,
+// returning the HTTP status of the error, if any.
+func (a *API) quoteTrade(pair, tradeType string, amount float64) (*Quote, int, error) {
+	mkt := getTradingPair(GetMarkets(), pair)
+	if mkt == nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid trading pair %s", pair)
+	}
+	if tradeType != "Buy" && tradeType != "Sell" {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid trade type %s, expected Buy or Sell", tradeType)
+	}
+	if amount <= 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("amount must be positive")
+	}
+
+	quote, err := QuoteTrade(a.rates, mkt, tradeType, amount)
+	if errors.Is(err, ErrStalePrice) {
+		return nil, http.StatusServiceUnavailable, err
+	}
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return quote, http.StatusOK, nil
+}
+
+// newOrder creates the order of the quote, checked against the current rate
+// of the pair, returning the HTTP status of the error, if any.
+func (a *API) newOrder(pair, traderScript, makerPubKey, makerBlindingKey string, partialFill bool, quote *Quote) (*Order, int, error) {
+	rate, err := MarketRate(a.rates, getTradingPair(GetMarkets(), pair), quote.Type)
+	if err != nil {
+		return nil, http.StatusServiceUnavailable, err
+	}
+	order, err := NewOrder(traderScript, makerPubKey, makerBlindingKey, partialFill, a.orderExpiry, quote, rate, a.net)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return order, http.StatusOK, nil
+}
+
+func newOrderResponse(order *Order, status string) (*OrderResponse, error) {
+	scriptPubKey, err := address.ToOutputScript(order.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid order address: %w", err)
+	}
+
+	response := &OrderResponse{
+		ID:                 order.ID,
+		Address:            order.Address,
+		Status:             status,
+		CreatedAt:          order.Timestamp.UTC(),
+		Input:              assetValue(order.Input.Asset, order.Input.Amount, order.InputValue()),
+		Output:             assetValue(order.Output.Asset, order.Output.Amount, order.OutputValue()),
+		FilledAmount:       order.FilledAmount,
+		PartialFill:        order.IsPartial(),
+		Confidential:       order.IsConfidential(),
+		MismatchedDeposits: order.Mismatched,
+		ScriptPubKey:       hex.EncodeToString(scriptPubKey),
+		TraderScript:       hex.EncodeToString(order.TraderScript),
+		MakerPubKey:        hex.EncodeToString(order.MakerPubKey),
+		Leaves:             orderLeaves(order),
+	}
+	if order.Network != nil {
+		response.Network = order.Network.Name
+	}
+	if !order.Expiry.IsZero() {
+		expiry := order.Expiry.UTC()
+		response.ExpiresAt = &expiry
+	}
+	// unknown for the orders created before fees were recorded
+	if order.Fee.Asset != "" {
+		fee := assetValue(order.Fee.Asset, order.Fee.Amount, order.FeeValue())
+		response.Fee = &fee
+	}
+	return response, nil
+}
+
+// orderLeaves returns the taproot leaves of the order contract, in the order
+// they're added to the tree by CreateFundingOutput.
+func orderLeaves(order *Order) []LeafResponse {
+	scripts := []struct {
+		name   string
+		script []byte
+	}{
+		{"fulfill", order.FulfillScript},
+		{"refund", order.RefundScript},
+		{"partial_fill", order.PartialFillScript},
+		{"expiry", order.ExpiryScript},
+		{"batch_fulfill", order.BatchFulfillScript},
+	}
+
+	leaves := []LeafResponse{}
+	for _, s := range scripts {
+		if len(s.script) == 0 {
+			continue
+		}
+		leafHash := taproot.NewBaseTapElementsLeaf(s.script).TapHash()
+		leaves = append(leaves, LeafResponse{
+			Name:     s.name,
+			Script:   hex.EncodeToString(s.script),
+			LeafHash: hex.EncodeToString(leafHash[:]),
+		})
+	}
+	return leaves
+}
+
+func assetValue(assetID string, amount uint64, value float64) AssetValue {
+	return AssetValue{
+		Currency: assetToCurrency[assetID],
+		AssetID:  assetID,
+		Amount:   amount,
+		Value:    value,
+	}
+}
+
+func currencyValue(currency string, value float64) AssetValue {
+	asset := currencyToAsset[currency]
+	return AssetValue{
+		Currency: currency,
+		AssetID:  asset.AssetHash,
+		Amount:   uint64(value * math.Pow10(asset.Precision)),
+		Value:    value,
+	}
+}
+
+func queryInt(c *gin.Context, key string, defaultValue int) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func apiError(c *gin.Context, status int, err error) {
+	c.JSON(status, gin.H{"error": err.Error()})
+}
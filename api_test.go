@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/vulpemventures/go-elements/address"
+	"github.com/vulpemventures/go-elements/network"
+)
+
+// apiWallet has the same balance of every asset and records the watched
+// scripts.
+type apiWallet struct {
+	WalletService
+	balance uint64
+	watched []string
+}
+
+func (w *apiWallet) Balance(ctx context.Context, assetHash string) (Balance, error) {
+	return Balance{AvailableBalance: w.balance}, nil
+}
+
+func (w *apiWallet) WatchScript(ctx context.Context, script string) error {
+	w.watched = append(w.watched, script)
+	return nil
+}
+
+func (w *apiWallet) TransactionNotifications(ctx context.Context) (<-chan *TransactionNotification, error) {
+	return nil, nil
+}
+
+// apiRatesClient quotes every market at a fixed price, with an order book of
+// depth units of the base asset at that price, if any.
+type apiRatesClient struct {
+	MockRatesClient
+	price float64
+	depth float64
+}
+
+func (c *apiRatesClient) MarketPrice(base, quote string) (float64, error) {
+	if c.price == 0 {
+		return 0, fmt.Errorf("no price available for market pair: %s/%s", base, quote)
+	}
+	return c.price, nil
+}
+
+func (c *apiRatesClient) MarketTicker(base, quote string) (Ticker, error) {
+	price, err := c.MarketPrice(base, quote)
+	if err != nil {
+		return Ticker{}, err
+	}
+	ticker := Ticker{Pair: base + "/" + quote, Bid: price, Ask: price, Last: price}
+	if c.depth > 0 {
+		ticker.Bids = []PriceLevel{{Price: price, Volume: c.depth}}
+		ticker.Asks = []PriceLevel{{Price: price, Volume: c.depth}}
+	}
+	return ticker, nil
+}
+
+func newTestAPI(t *testing.T, price float64) (*gin.Engine, OrderRepository, *apiWallet) {
+	gin.SetMode(gin.TestMode)
+	repo := NewInMemoryOrderRepository()
+	wallet := &apiWallet{balance: 100000}
+	router := gin.New()
+	NewAPI(repo, wallet, &apiRatesClient{price: price}, &network.Testnet, time.Hour, false).Register(router)
+	return router, repo, wallet
+}
+
+func doAPIRequest(t *testing.T, router *gin.Engine, method, path string, body interface{}, response interface{}) int {
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &reqBody)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if response != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), response); err != nil {
+			t.Fatalf("invalid JSON response %s: %v", rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestAPI_Markets(t *testing.T) {
+	router, _, _ := newTestAPI(t, 1)
+
+	var response struct {
+		Markets []MarketResponse `json:"markets"`
+	}
+	code := doAPIRequest(t, router, http.MethodGet, "/api/v1/markets", nil, &response)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, response.Markets, len(GetMarkets()))
+	assert.Equal(t, "L-BTC/L-BTC", response.Markets[0].Pair)
+	assert.Equal(t, uint64(100000), response.Markets[0].BuyLimit)
+	assert.Equal(t, uint64(100000), response.Markets[0].SellLimit)
+	assert.Equal(t, 1.0, response.Markets[0].Price)
+	assert.Equal(t, 0.1, response.Markets[1].BuyFeePercentage)
+	assert.Equal(t, 0.75, response.Markets[1].SellFeePercentage)
+}
+
+func TestAPI_Quote(t *testing.T) {
+	router, _, _ := newTestAPI(t, 1)
+
+	var quote QuoteResponse
+	code := doAPIRequest(t, router, http.MethodGet, "/api/v1/quote?pair=L-BTC/L-BTC&type=Buy&amount=0.001", nil, &quote)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "L-BTC", quote.Input.Currency)
+	assert.Equal(t, uint64(100100), quote.Input.Amount)
+	assert.Equal(t, uint64(100000), quote.Output.Amount)
+	assert.Equal(t, 0.1, quote.FeePercentage)
+	assert.Equal(t, uint64(100), quote.Fee.Amount)
+
+	tests := []struct {
+		name  string
+		query string
+		code  int
+	}{
+		{"invalid pair", "pair=L-BTC/EUR&type=Buy&amount=0.001", http.StatusBadRequest},
+		{"invalid type", "pair=L-BTC/L-BTC&type=Swap&amount=0.001", http.StatusBadRequest},
+		{"invalid amount", "pair=L-BTC/L-BTC&type=Buy&amount=abc", http.StatusBadRequest},
+		{"negative amount", "pair=L-BTC/L-BTC&type=Buy&amount=-1", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var response map[string]string
+			code := doAPIRequest(t, router, http.MethodGet, "/api/v1/quote?"+tt.query, nil, &response)
+			assert.Equal(t, tt.code, code)
+			assert.NotEmpty(t, response["error"])
+		})
+	}
+
+	// an amount the market can't price is rejected, as an invalid one
+	router = gin.New()
+	NewAPI(NewInMemoryOrderRepository(), &apiWallet{balance: 100000}, &apiRatesClient{price: 1, depth: 0.01}, &network.Testnet, time.Hour, false).Register(router)
+	var response map[string]string
+	code = doAPIRequest(t, router, http.MethodGet, "/api/v1/quote?pair=L-BTC/L-BTC&type=Buy&amount=1", nil, &response)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, response["error"], "exceeds the order book depth")
+
+	// no price yet
+	router, _, _ = newTestAPI(t, 0)
+	response = nil
+	code = doAPIRequest(t, router, http.MethodGet, "/api/v1/quote?pair=L-BTC/L-BTC&type=Buy&amount=0.001", nil, &response)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, response["error"], "is halted")
+}
+
+func TestAPI_Orders(t *testing.T) {
+	router, repo, wallet := newTestAPI(t, 1)
+
+	request := CreateOrderRequest{
+		Pair:         "L-BTC/L-BTC",
+		Type:         "Buy",
+		Amount:       0.001,
+		TraderScript: hex.EncodeToString(traderScriptExpected),
+	}
+	var created OrderResponse
+	code := doAPIRequest(t, router, http.MethodPost, "/api/v1/orders", request, &created)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "Pending", created.Status)
+	assert.Equal(t, network.Testnet.Name, created.Network)
+	assert.Equal(t, uint64(100100), created.Input.Amount)
+	assert.Equal(t, uint64(100000), created.Output.Amount)
+	if assert.NotNil(t, created.Fee) {
+		assert.Equal(t, "L-BTC", created.Fee.Currency)
+		assert.Equal(t, uint64(100), created.Fee.Amount)
+	}
+	assert.NotNil(t, created.ExpiresAt)
+
+	// the contract script is watched and matches the address
+	script, err := address.ToOutputScript(created.Address)
+	assert.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(script), created.ScriptPubKey)
+	assert.Equal(t, []string{created.ScriptPubKey}, wallet.watched)
+
+	leaves := []string{}
+	for _, leaf := range created.Leaves {
+		leaves = append(leaves, leaf.Name)
+		assert.Len(t, leaf.LeafHash, 64)
+	}
+	assert.Equal(t, []string{"refund", "expiry", "batch_fulfill"}, leaves)
+
+	// the order and its status history
+	assert.NoError(t, repo.UpdateOrderStatusWithTxHash(created.ID, "Funded", "aa"))
+	var fetched OrderResponse
+	code = doAPIRequest(t, router, http.MethodGet, "/api/v1/orders/"+created.ID, nil, &fetched)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Funded", fetched.Status)
+	assert.Equal(t, created.Leaves, fetched.Leaves)
+	if assert.Len(t, fetched.Statuses, 2) {
+		assert.Equal(t, "Pending", fetched.Statuses[0].Status)
+		assert.Equal(t, "aa", fetched.Statuses[1].TxID)
+	}
+
+	var notFound map[string]string
+	code = doAPIRequest(t, router, http.MethodGet, "/api/v1/orders/unknown", nil, &notFound)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "order not found", notFound["error"])
+
+	// the listing filters by status
+	var list struct {
+		Orders []OrderResponse `json:"orders"`
+	}
+	code = doAPIRequest(t, router, http.MethodGet, "/api/v1/orders?status=Funded", nil, &list)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, list.Orders, 1) {
+		assert.Equal(t, created.ID, list.Orders[0].ID)
+	}
+	code = doAPIRequest(t, router, http.MethodGet, "/api/v1/orders?status=Pending", nil, &list)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, list.Orders)
+
+	var invalid map[string]string
+	code = doAPIRequest(t, router, http.MethodGet, "/api/v1/orders?limit=0", nil, &invalid)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.NotEmpty(t, invalid["error"])
+
+	// invalid requests are rejected before creating any order
+	request.TraderScript = ""
+	code = doAPIRequest(t, router, http.MethodPost, "/api/v1/orders", request, &invalid)
+	assert.Equal(t, http.StatusBadRequest, code)
+	request.TraderScript = "zz"
+	code = doAPIRequest(t, router, http.MethodPost, "/api/v1/orders", request, &invalid)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Len(t, wallet.watched, 1)
+}
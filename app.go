@@ -3,8 +3,11 @@ package main
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"time"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
@@ -35,12 +38,54 @@ func ScriptHashFromAddress(addr string) (string, error) {
 }
 
 type Market struct {
-	BaseAsset         string
-	QuoteAsset        string
+	BaseAsset  string
+	QuoteAsset string
+	// the percentage added to the ask to buy and taken from the bid to sell
+	SpreadPercentage float64
+	Fee              FeePolicy
+	BuyLimit         uint64
+	SellLimit        uint64
+}
+
+// FeePolicy is the fee charged on the trades of a market, in its quote asset:
+// a percentage of the traded value, depending on the side and the size of the
+// trade, plus a fixed fee.
+type FeePolicy struct {
+	BuyPercentageFee  float64
+	SellPercentageFee float64
+	FixedFee          float64
+	// Tiers by increasing MinAmount, the percentages of the largest tier
+	// reached by the trade replace the default ones.
+	Tiers []FeeTier
+}
+
+// FeeTier applies to the trades of at least MinAmount of the base asset.
+type FeeTier struct {
+	MinAmount         float64
 	BuyPercentageFee  float64
 	SellPercentageFee float64
-	BuyLimit          uint64
-	SellLimit         uint64
+}
+
+// Percentage returns the percentage fee of a Buy or Sell of the given amount
+// of the base asset.
+func (p FeePolicy) Percentage(tradeType string, amount float64) float64 {
+	buy, sell := p.BuyPercentageFee, p.SellPercentageFee
+	for _, tier := range p.Tiers {
+		if amount < tier.MinAmount {
+			break
+		}
+		buy, sell = tier.BuyPercentageFee, tier.SellPercentageFee
+	}
+	if tradeType == "Buy" {
+		return buy
+	}
+	return sell
+}
+
+// Fee returns the fee in the quote asset of a Buy or Sell of the given amount
+// of the base asset at the price.
+func (p FeePolicy) Fee(tradeType string, amount, price float64) float64 {
+	return amount*price*p.Percentage(tradeType, amount)/100 + p.FixedFee
 }
 
 func GetMarketsWithLimits(ctx context.Context, walletSvc WalletService) (mkts []*Market, err error) {
@@ -87,20 +132,25 @@ func GetMarketWithLimits(ctx context.Context, walletSvc WalletService, market *M
 func GetMarkets() []*Market {
 	return []*Market{
 		{
-			BaseAsset:         "L-BTC",
-			QuoteAsset:        "L-BTC",
-			BuyPercentageFee:  0.1,
-			SellPercentageFee: 0.1,
-			BuyLimit:          0,
-			SellLimit:         0,
+			BaseAsset:  "L-BTC",
+			QuoteAsset: "L-BTC",
+			Fee: FeePolicy{
+				BuyPercentageFee:  0.1,
+				SellPercentageFee: 0.1,
+			},
+			BuyLimit:  0,
+			SellLimit: 0,
 		},
 		{
-			BaseAsset:         "L-BTC",
-			QuoteAsset:        "USDT",
-			BuyPercentageFee:  0.1,
-			SellPercentageFee: 0.75,
-			BuyLimit:          0,
-			SellLimit:         0,
+			BaseAsset:        "L-BTC",
+			QuoteAsset:       "USDT",
+			SpreadPercentage: 0.1,
+			Fee: FeePolicy{
+				BuyPercentageFee:  0.1,
+				SellPercentageFee: 0.75,
+			},
+			BuyLimit:  0,
+			SellLimit: 0,
 		},
 		// Add more markets here if needed
 	}
@@ -115,75 +165,345 @@ func getTradingPair(markets []*Market, pair string) *Market {
 	return nil
 }
 
-func getTransactionsForAddress(addr, networkName string) ([]Transaction, error) {
-	esplora, err := NewEsplora(networkName)
+// Quote is what the maker sends and receives to buy or sell an amount of the
+// base asset of a market, fee included.
+type Quote struct {
+	// Buy or Sell of the base asset
+	Type           string
+	Price          float64
+	FeePercentage  float64
+	Fee            float64
+	FeeCurrency    string
+	InputCurrency  string
+	InputValue     float64
+	OutputCurrency string
+	OutputValue    float64
+}
+
+// QuoteTrade prices a Buy or Sell of the given amount of the base asset of the
+// market at its TradePrice, charging the fee of the market in its quote asset.
+func QuoteTrade(rates RatesClient, mkt *Market, tradeType string, amount float64) (*Quote, error) {
+	if tradeType != "Buy" && tradeType != "Sell" {
+		return nil, fmt.Errorf("invalid trade type %s, expected Buy or Sell", tradeType)
+	}
+
+	// no order is created at a stale price
+	ticker, err := rates.MarketTicker(mkt.BaseAsset, mkt.QuoteAsset)
 	if err != nil {
-		return nil, fmt.Errorf("esplora initialization error: %w", err)
+		return nil, marketHaltedError(mkt, err)
 	}
-	transactions, err := esplora.FetchTransactionHistory(addr)
+	price, err := TradePrice(ticker, mkt, tradeType, amount)
 	if err != nil {
-		return nil, fmt.Errorf("esplora fetch txs error: %w", err)
+		return nil, err
 	}
 
-	return transactions, nil
+	quote := &Quote{
+		Type:          tradeType,
+		Price:         price,
+		FeePercentage: mkt.Fee.Percentage(tradeType, amount),
+		Fee:           mkt.Fee.Fee(tradeType, amount, price),
+		FeeCurrency:   mkt.QuoteAsset,
+	}
+	if tradeType == "Buy" {
+		quote.InputValue = amount*price + quote.Fee // Add the fee to the input value
+		quote.InputCurrency = mkt.QuoteAsset
+		quote.OutputValue = amount
+		quote.OutputCurrency = mkt.BaseAsset
+	} else {
+		quote.InputValue = amount
+		quote.InputCurrency = mkt.BaseAsset
+		quote.OutputValue = amount*price - quote.Fee // Subtract the fee from the output value
+		quote.OutputCurrency = mkt.QuoteAsset
+		if quote.OutputValue <= 0 {
+			return nil, fmt.Errorf("amount %v too small to cover the fee of %v %s", amount, quote.Fee, quote.FeeCurrency)
+		}
+	}
+	return quote, nil
 }
 
-func watchForTrades(order *Order, walletSvc WalletService, esplora *Esplora) error {
-	if duration := time.Since(order.Timestamp); duration > 10*time.Minute {
-		err := updateOrderStatus(order.ID, "Expired")
+// TradePrice returns the price of the base asset to Buy or Sell the amount of
+// it: the ask plus the spread of the market to Buy, the bid minus it to Sell.
+// Beyond the top of the book, the price slips as much as the average price to
+// take the amount from the order book, if known, does from the best one.
+func TradePrice(ticker Ticker, mkt *Market, tradeType string, amount float64) (float64, error) {
+	price, levels, spread := ticker.Ask, ticker.Asks, 1+mkt.SpreadPercentage/100
+	if tradeType == "Sell" {
+		price, levels, spread = ticker.Bid, ticker.Bids, 1-mkt.SpreadPercentage/100
+	}
+	if price <= 0 {
+		return 0, fmt.Errorf("no %s price for market pair: %s", tradeType, ticker.Pair)
+	}
+
+	if len(levels) > 0 {
+		depthPrice, err := depthWeightedPrice(levels, amount)
 		if err != nil {
-			return fmt.Errorf("error updating order status: %w", err)
+			return 0, fmt.Errorf("error pricing %v %s: %w", amount, mkt.BaseAsset, err)
 		}
+		price *= depthPrice / levels[0].Price
 	}
-	utxos, err := esplora.FetchUnspents(order.Address)
+	return price * spread, nil
+}
+
+// MarketRate returns the best price of the market to Buy or Sell its base
+// asset, the ask or the bid without spread, that the price of an order must
+// be within the slippage tolerance of.
+func MarketRate(rates RatesClient, mkt *Market, tradeType string) (float64, error) {
+	ticker, err := rates.MarketTicker(mkt.BaseAsset, mkt.QuoteAsset)
 	if err != nil {
-		return fmt.Errorf("error fetching unspents: %w", err)
+		return 0, marketHaltedError(mkt, err)
+	}
+	if tradeType == "Sell" {
+		return ticker.Bid, nil
+	}
+	return ticker.Ask, nil
+}
+
+// marketHaltedError returns the error of a market without a ticker. It is an
+// ErrStalePrice whatever the reason, as no fresh price is available either
+// way, e.g. none received yet or the sources disagreeing.
+func marketHaltedError(mkt *Market, err error) error {
+	if errors.Is(err, ErrStalePrice) {
+		return fmt.Errorf("market %s/%s is halted: %w", mkt.BaseAsset, mkt.QuoteAsset, err)
 	}
+	return fmt.Errorf("market %s/%s is halted: %w: %w", mkt.BaseAsset, mkt.QuoteAsset, ErrStalePrice, err)
+}
 
-	// TODO Check also the asset type
-	if coinsAreMoreThan(utxos, order.Input.Amount) {
-		updateOrderStatus(order.ID, "Funded")
+// setOrderFee records the fee of the quote on its order, in satoshis of the
+// asset charged.
+func setOrderFee(order *Order, quote *Quote) {
+	asset := currencyToAsset[quote.FeeCurrency]
+	order.Fee.Asset = asset.AssetHash
+	order.Fee.Amount = uint64(math.Round(quote.Fee * math.Pow(10, float64(asset.Precision))))
+}
 
-		trades, err := executeTrades(
-			order,
-			utxos,
+// saveAndWatchOrder stores a new order and asks the wallet to watch its
+// contract address for deposits.
+func saveAndWatchOrder(ctx context.Context, repo OrderRepository, walletSvc WalletService, order *Order) error {
+	err := repo.SaveOrder(order)
+	if err != nil {
+		return fmt.Errorf("error saving order: %w", err)
+	}
+
+	script, err := address.ToOutputScript(order.Address)
+	if err != nil {
+		return err
+	}
+	err = walletSvc.WatchScript(ctx, hex.EncodeToString(script))
+	if err != nil {
+		return fmt.Errorf("error watching order script: %w", err)
+	}
+	return nil
+}
+
+// watchForTradesInBatch reconciles the deposits of the given orders, along
+// with the ones notified by the wallet by order ID, and fulfills them,
+// sizing the fees of the transactions at the given rate in sats/vbyte. The
+// batchable trades are fulfilled in as few transactions as possible, the
+// others one by one, as are the trades of a failed batch. The errors are
+// returned by order ID.
+func watchForTradesInBatch(repo OrderRepository, orders []*Order, notified map[string][]*UTXO, walletSvc WalletService, chain ChainSource, feeRate float64) map[string]error {
+	errs := make(map[string]error)
+	batchable := []*Trade{}
+	fulfillmentsByOrder := make(map[string]int)
+	for _, order := range orders {
+		fulfillments, err := reconcileOrder(repo, order, chain, notified[order.ID])
+		if err != nil {
+			errs[order.ID] = err
+			continue
+		}
+		if len(fulfillments) == 0 {
+			continue
+		}
+
+		trades, rest, err := batchableTrades(order, fulfillments, walletSvc, feeRate)
+		if err != nil {
+			errs[order.ID] = err
+			continue
+		}
+		if len(rest) > 0 {
+			// e.g. top-ups of confidential orders or partial fill orders
+			trades, err := executeTrades(order, fulfillments, walletSvc, chain, feeRate)
+			if err != nil {
+				errs[order.ID] = fmt.Errorf("error executing trade: %v", err)
+				continue
+			}
+			err = recordTrades(repo, order, trades, len(fulfillments))
+			if err != nil {
+				errs[order.ID] = err
+			}
+			continue
+		}
+		batchable = append(batchable, trades...)
+		fulfillmentsByOrder[order.ID] = len(fulfillments)
+	}
+
+	executed := []*Trade{}
+	for start := 0; start < len(batchable); start += MAX_BATCH_SIZE {
+		end := start + MAX_BATCH_SIZE
+		if end > len(batchable) {
+			end = len(batchable)
+		}
+		trades := batchable[start:end]
+
+		batch, err := NewBatch(walletSvc, trades)
+		if err == nil {
+			batch.FeeRate = feeRate
+			err = batch.Execute()
+		}
+		if err == nil {
+			log.Printf("executed batch of %d trades: %s\n", len(trades), batch.TxID)
+			executed = append(executed, trades...)
+			continue
+		}
+
+		log.Printf("error executing batch, fulfilling its trades one by one: %v\n", err)
+		for _, trade := range trades {
+			err = trade.ExecuteTrade()
+			if err != nil {
+				errs[trade.Order.ID] = fmt.Errorf("error executing trade: %v", err)
+				continue
+			}
+			executed = append(executed, trade)
+		}
+	}
+
+	// record the trades of each order once all of them are executed, the
+	// deposits left of a failed order are fulfilled the next round
+	tradesByOrder := make(map[string][]*Trade)
+	for _, trade := range executed {
+		tradesByOrder[trade.Order.ID] = append(tradesByOrder[trade.Order.ID], trade)
+	}
+	for _, order := range orders {
+		trades, ok := tradesByOrder[order.ID]
+		if _, failed := errs[order.ID]; !ok || failed {
+			continue
+		}
+		err := recordTrades(repo, order, trades, fulfillmentsByOrder[order.ID])
+		if err != nil {
+			errs[order.ID] = err
+		}
+	}
+
+	return errs
+}
+
+// batchableTrades returns the trades of the fulfillments of the order that
+// can be batched, and the fulfillments that can't.
+func batchableTrades(order *Order, fulfillments [][]*UTXO, walletSvc WalletService, feeRate float64) ([]*Trade, [][]*UTXO, error) {
+	trades := []*Trade{}
+	rest := [][]*UTXO{}
+	for _, unspents := range fulfillments {
+		trade, err := FromFundedOrder(
 			walletSvc,
-			esplora,
+			order,
+			unspents[0],
+			unspents[1:]...,
 		)
 		if err != nil {
-			return fmt.Errorf("error executing trade: %v", err)
+			return nil, nil, err
 		}
+		trade.FeeRate = feeRate
 
-		for _, trade := range trades {
-			log.Printf("executed trade for order ID: %s\n", trade.Order.ID)
+		if !trade.IsBatchable() {
+			rest = append(rest, unspents)
+			continue
 		}
+		trades = append(trades, trade)
+	}
+
+	return trades, rest, nil
+}
 
-		updateOrderStatus(order.ID, "Fulfilled")
+// reconcileOrder reconciles the deposits of the order, updating its status,
+// and returns the unspents to fulfill, if any.
+func reconcileOrder(repo OrderRepository, order *Order, chain ChainSource, notified []*UTXO) ([][]*UTXO, error) {
+	// stop fulfilling at expiry, the sweeper refunds the deposits once the
+	// timelock of the expiry leaf matures
+	if order.IsExpired(time.Now()) {
+		err := repo.UpdateOrderStatus(order.ID, "Expired")
+		if err != nil {
+			return nil, fmt.Errorf("error updating order status: %w", err)
+		}
+		return nil, nil
 	}
-	return nil
+	utxos, err := chain.FetchUnspents(order.Address)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching unspents: %w", err)
+	}
+	// deposits notified before the chain source indexes them
+	utxos = withNotifiedUnspents(utxos, notified)
+
+	funding := ReconcileFunding(order, utxos)
+	if mismatched := funding.NumOfMismatched(); mismatched != order.Mismatched {
+		log.Printf("order ID: %s received %d deposits of wrong asset or that can't be verified\n", order.ID, mismatched)
+		err = repo.FlagOrderMismatchedDeposits(order.ID, mismatched)
+		if err != nil {
+			return nil, fmt.Errorf("error flagging order: %w", err)
+		}
+	}
+
+	status := funding.Status()
+	if status == "Pending" {
+		return nil, nil
+	}
+	err = repo.UpdateOrderStatusWithTxHash(order.ID, status, funding.FundingTxID())
+	if err != nil {
+		return nil, fmt.Errorf("error updating order status: %w", err)
+	}
+
+	// with no fulfillments the under-funded deposits wait for a top-up, if the
+	// contract combines them, or to be refunded
+	return funding.Fulfillments(), nil
 }
 
-func coinsAreMoreThan(utxos []*UTXO, amount uint64) bool {
-	// Calculate the total value of UTXOs
-	totalValue := uint64(0)
-	for _, utxo := range utxos {
-		totalValue += utxo.Value
+// recordTrades stores the outcome of the trades executed for the given number
+// of fulfillments of the order.
+func recordTrades(repo OrderRepository, order *Order, trades []*Trade, numOfFulfillments int) error {
+	if len(trades) == 0 {
+		// nothing filled, e.g. the market limit is reached
+		return nil
+	}
+
+	var txHash string
+	filled := uint64(0)
+	remaining := len(trades) < numOfFulfillments
+	for _, trade := range trades {
+		log.Printf("executed trade for order ID: %s\n", trade.Order.ID)
+		txHash = trade.TxID
+		filled += trade.Filled
+		remaining = remaining || trade.Remainder > 0
+	}
+
+	if order.IsPartial() {
+		err := repo.AddOrderFilledAmount(order.ID, filled)
+		if err != nil {
+			return fmt.Errorf("error updating order filled amount: %w", err)
+		}
+		if remaining {
+			return repo.UpdateOrderStatusWithTxHash(order.ID, "PartiallyFilled", txHash)
+		}
 	}
 
-	return totalValue >= amount
+	return repo.UpdateOrderStatusWithTxHash(order.ID, "Fulfilled", txHash)
 }
 
-func executeTrades(order *Order, unspents []*UTXO, walletSvc WalletService, esplora *Esplora) ([]*Trade, error) {
+func executeTrades(order *Order, fulfillments [][]*UTXO, walletSvc WalletService, chain ChainSource, feeRate float64) ([]*Trade, error) {
+	if order.IsPartial() {
+		return executePartialFills(order, fulfillments, walletSvc, feeRate)
+	}
+
 	trades := []*Trade{}
-	for _, unspent := range unspents {
+	for _, unspents := range fulfillments {
 		trade, err := FromFundedOrder(
 			walletSvc,
 			order,
-			unspent,
+			unspents[0],
+			unspents[1:]...,
 		)
 		if err != nil {
 			return nil, err
 		}
+		trade.FeeRate = feeRate
 
 		if trade.Status != Funded {
 			return nil, fmt.Errorf("trade is not funded: %v", err)
@@ -199,3 +519,194 @@ func executeTrades(order *Order, unspents []*UTXO, walletSvc WalletService, espl
 
 	return trades, nil
 }
+
+// executePartialFills fills the contract unspents of a partial fill order up
+// to the market limit, that is our balance of the order output asset less the
+// fees of the fills. The unspents left once the limit is reached wait for the
+// next round.
+func executePartialFills(order *Order, fulfillments [][]*UTXO, walletSvc WalletService, feeRate float64) ([]*Trade, error) {
+	balance, err := walletSvc.Balance(context.Background(), order.Output.Asset)
+	if err != nil {
+		return nil, fmt.Errorf("error getting balance for asset: %s, error: %w", order.Output.Asset, err)
+	}
+	limit := balance.AvailableBalance
+	reserve := partialFillReserve(order, feeRate)
+
+	trades := []*Trade{}
+	for _, unspents := range fulfillments {
+		if limit <= reserve {
+			break
+		}
+		trade, err := FromFundedOrder(walletSvc, order, unspents[0])
+		if err != nil {
+			return nil, err
+		}
+		trade.FeeRate = feeRate
+
+		fillAmount, _, err := order.PartialFillAmounts(trade.FundedAmount(), limit-reserve)
+		if err != nil {
+			log.Printf("order ID: %s can't be filled: %v\n", order.ID, err)
+			break
+		}
+		err = trade.ExecutePartialFill(fillAmount)
+		if err != nil {
+			return nil, err
+		}
+		limit -= fillAmount + reserve
+		trades = append(trades, trade)
+	}
+
+	return trades, nil
+}
+
+// partialFillReserve returns how much of our balance of the output asset of
+// the partial fill order to keep for the network fees of each fill, sized at
+// the given fee rate as the fee unspents are selected for.
+func partialFillReserve(order *Order, feeRate float64) uint64 {
+	if order.Output.Asset != currencyToAsset["L-BTC"].AssetHash {
+		return 0
+	}
+	// each fill spends a single contract unspent
+	return feeHeadroom(1, feeRate)
+}
+
+// isCancellable tells whether an order in the given status can be cancelled
+// via the refund leaf.
+func isCancellable(status string) bool {
+	switch status {
+	case "Pending", "Funded", "Overfunded", "PartiallyFilled":
+		return true
+	}
+	return false
+}
+
+// isKeyPathCancellable tells whether an order in the given status can be
+// cancelled by the maker via key-path, which also returns the under-funded
+// deposits and the ones of other assets.
+func isKeyPathCancellable(status string) bool {
+	return isCancellable(status) || status == "Underfunded"
+}
+
+func cancelOrder(repo OrderRepository, order *Order, walletSvc WalletService, chain ChainSource, feeRate float64) (string, error) {
+	utxos, err := chain.FetchUnspents(order.Address)
+	if err != nil {
+		return "", fmt.Errorf("error fetching unspents: %w", err)
+	}
+	// only exact and over-funded deposits satisfy the refund leaf, while the
+	// one of partial fill orders refunds any deposit
+	funding := ReconcileFunding(order, utxos)
+	refundable := funding.Refundable(order.RefundsWholeValue(order.RefundScript))
+	if len(refundable) == 0 {
+		return "", fmt.Errorf("order %s has no deposits refundable via the refund leaf", order.ID)
+	}
+
+	// each refund is recorded as soon as it is broadcast, the deposits
+	// refunded before a failure would be lost to the timeline otherwise
+	var txHash string
+	for _, unspent := range refundable {
+		trade, err := FromFundedOrder(
+			walletSvc,
+			order,
+			unspent,
+		)
+		if err != nil {
+			return "", err
+		}
+		trade.FeeRate = feeRate
+
+		err = trade.CancelTrade()
+		if err != nil {
+			return "", fmt.Errorf("error cancelling trade: %w", err)
+		}
+		log.Printf("cancelled trade for order ID: %s\n", trade.Order.ID)
+		txHash = trade.TxID
+
+		err = repo.UpdateOrderStatusWithTxHash(order.ID, "Cancelled", txHash)
+		if err != nil {
+			return "", fmt.Errorf("error updating order status: %w", err)
+		}
+	}
+
+	return txHash, nil
+}
+
+// cancelOrderWithSignedPset broadcasts the cancel transaction of all the
+// contract unspents, signed by the maker via key-path.
+func cancelOrderWithSignedPset(repo OrderRepository, order *Order, walletSvc WalletService, chain ChainSource, signedPsetBase64 string) (string, error) {
+	utxos, err := chain.FetchUnspents(order.Address)
+	if err != nil {
+		return "", fmt.Errorf("error fetching unspents: %w", err)
+	}
+	if len(utxos) == 0 {
+		return "", fmt.Errorf("no contract unspents to cancel")
+	}
+	trade, err := FromFundedOrder(walletSvc, order, utxos[0], utxos[1:]...)
+	if err != nil {
+		return "", err
+	}
+
+	// The PSET signed by the maker via key-path proves the ownership
+	err = trade.CancelTradeWithSignedPset(signedPsetBase64)
+	if err != nil {
+		return "", fmt.Errorf("error cancelling trade: %w", err)
+	}
+	log.Printf("cancelled trade via key-path for order ID: %s\n", order.ID)
+
+	err = repo.UpdateOrderStatusWithTxHash(order.ID, "Cancelled", trade.TxID)
+	if err != nil {
+		return "", fmt.Errorf("error updating order status: %w", err)
+	}
+
+	return trade.TxID, nil
+}
+
+// sweepExpiredOrder refunds the deposits of an expired order via the expiry
+// leaf, as soon as the median time past of the chain tip is after the expiry.
+func sweepExpiredOrder(repo OrderRepository, order *Order, walletSvc WalletService, chain ChainSource, feeRate float64) error {
+	medianTime, err := chain.FetchTipMedianTime()
+	if err != nil {
+		return fmt.Errorf("error fetching chain tip: %w", err)
+	}
+	if medianTime <= order.Expiry.Unix() {
+		return nil
+	}
+
+	utxos, err := chain.FetchUnspents(order.Address)
+	if err != nil {
+		return fmt.Errorf("error fetching unspents: %w", err)
+	}
+	// the expiry leaf refunds any deposit, under-funded ones included
+	funding := ReconcileFunding(order, utxos)
+	refundable := funding.Refundable(order.RefundsWholeValue(order.ExpiryScript))
+	if len(refundable) == 0 {
+		// nothing left to sweep, the order is done with
+		err = repo.UpdateOrderStatus(order.ID, "ExpiredEmpty")
+		if err != nil {
+			return fmt.Errorf("error updating order status: %w", err)
+		}
+		return nil
+	}
+
+	var txHash string
+	for _, unspent := range refundable {
+		trade, err := FromFundedOrder(walletSvc, order, unspent)
+		if err != nil {
+			return err
+		}
+		trade.FeeRate = feeRate
+
+		err = trade.SweepExpiredTrade()
+		if err != nil {
+			return fmt.Errorf("error sweeping trade: %w", err)
+		}
+		log.Printf("swept expired trade for order ID: %s\n", trade.Order.ID)
+		txHash = trade.TxID
+	}
+
+	err = repo.UpdateOrderStatusWithTxHash(order.ID, "Expired", txHash)
+	if err != nil {
+		return fmt.Errorf("error updating order status: %w", err)
+	}
+
+	return nil
+}
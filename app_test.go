@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/stretchr/testify/assert"
+	"github.com/vulpemventures/go-elements/elementsutil"
+	"github.com/vulpemventures/go-elements/network"
+	"github.com/vulpemventures/go-elements/payment"
+	"github.com/vulpemventures/go-elements/psetv2"
+	"github.com/vulpemventures/go-elements/transaction"
+)
+
+func TestFeePolicy(t *testing.T) {
+	policy := FeePolicy{
+		BuyPercentageFee:  0.5,
+		SellPercentageFee: 1,
+		FixedFee:          2,
+		Tiers: []FeeTier{
+			{MinAmount: 0.1, BuyPercentageFee: 0.4, SellPercentageFee: 0.8},
+			{MinAmount: 1, BuyPercentageFee: 0.2, SellPercentageFee: 0.5},
+		},
+	}
+
+	tests := []struct {
+		tradeType  string
+		amount     float64
+		percentage float64
+	}{
+		{"Buy", 0.01, 0.5},
+		{"Sell", 0.01, 1},
+		{"Buy", 0.1, 0.4},
+		{"Sell", 0.5, 0.8},
+		{"Buy", 1, 0.2},
+		{"Sell", 10, 0.5},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.percentage, policy.Percentage(tt.tradeType, tt.amount), tt.tradeType, tt.amount)
+	}
+
+	// the percentage of the traded value plus the fixed fee
+	assert.InDelta(t, 30000*0.01*0.005+2, policy.Fee("Buy", 0.01, 30000), 1e-9)
+	assert.InDelta(t, 30000*2*0.005+2, policy.Fee("Sell", 2, 30000), 1e-9)
+}
+
+func TestQuoteTrade(t *testing.T) {
+	rates := &apiRatesClient{price: 30000}
+	mkt := &Market{
+		BaseAsset:  "L-BTC",
+		QuoteAsset: "USDT",
+		Fee:        FeePolicy{BuyPercentageFee: 0.1, SellPercentageFee: 0.75, FixedFee: 1},
+	}
+
+	quote, err := QuoteTrade(rates, mkt, "Buy", 0.01)
+	assert.NoError(t, err)
+	assert.Equal(t, "USDT", quote.InputCurrency)
+	assert.InDelta(t, 300+0.3+1, quote.InputValue, 1e-9)
+	assert.Equal(t, 0.01, quote.OutputValue)
+	assert.InDelta(t, 1.3, quote.Fee, 1e-9)
+	assert.Equal(t, "USDT", quote.FeeCurrency)
+
+	quote, err = QuoteTrade(rates, mkt, "Sell", 0.01)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.01, quote.InputValue)
+	assert.InDelta(t, 300-2.25-1, quote.OutputValue, 1e-9)
+	assert.Equal(t, 0.75, quote.FeePercentage)
+
+	// the sell is priced in USDT per L-BTC as the buy
+	sell := newTestOrder(t, testOrder{quote: quote})
+	assert.Equal(t, currencyToAsset["L-BTC"].AssetHash, sell.Input.Asset)
+	assert.Equal(t, uint64(1000000), sell.Input.Amount)
+	assert.Equal(t, currencyToAsset["USDT"].AssetHash, sell.Output.Asset)
+	assert.Equal(t, uint64(29675000000), sell.Output.Amount)
+
+	// the market moved beyond the slippage tolerance since the quote
+	_, err = testOrder{quote: quote, rate: 31000}.new()
+	assert.ErrorContains(t, err, "is outside the expected range")
+	_, err = testOrder{quote: quote, rate: 30500}.new()
+	assert.NoError(t, err)
+
+	// amounts rounded down to zero satoshis can not be traded
+	dust := *quote
+	dust.InputValue = 0.000000001
+	_, err = testOrder{quote: &dust}.new()
+	assert.ErrorContains(t, err, "too small to trade")
+
+	// the fee is recorded on the order in satoshis of the quote asset
+	order := &Order{}
+	setOrderFee(order, quote)
+	assert.Equal(t, currencyToAsset["USDT"].AssetHash, order.Fee.Asset)
+	assert.Equal(t, uint64(325000000), order.Fee.Amount)
+	assert.InDelta(t, 3.25, order.FeeValue(), 1e-9)
+
+	// the fixed fee is more than what is sold
+	_, err = QuoteTrade(rates, mkt, "Sell", 0.00001)
+	assert.Error(t, err)
+
+	// a fixed fee beyond the slippage tolerance of a small trade, recorded
+	// on its order
+	quote, err = QuoteTrade(rates, mkt, "Buy", 0.0001)
+	assert.NoError(t, err)
+	assert.Greater(t, quote.Fee, quote.InputValue*0.03)
+	order = newTestOrder(t, testOrder{quote: quote})
+	assert.Equal(t, uint64(100300000), order.Fee.Amount)
+}
+
+func TestTradePrice(t *testing.T) {
+	mkt := &Market{BaseAsset: "L-BTC", QuoteAsset: "USDT", SpreadPercentage: 1}
+	ticker := Ticker{Pair: "L-BTC/USDT", Bid: 29000, Ask: 30000}
+
+	// buys off the ask and sells off the bid, spread included
+	price, err := TradePrice(ticker, mkt, "Buy", 0.01)
+	assert.NoError(t, err)
+	assert.InDelta(t, 30300, price, 1e-9)
+	price, err = TradePrice(ticker, mkt, "Sell", 0.01)
+	assert.NoError(t, err)
+	assert.InDelta(t, 28710, price, 1e-9)
+
+	// and slips down the order book once larger than its top
+	ticker.Asks = []PriceLevel{{30000, 1}, {30300, 1}}
+	ticker.Bids = []PriceLevel{{29000, 1}, {28710, 1}}
+	price, err = TradePrice(ticker, mkt, "Buy", 0.5)
+	assert.NoError(t, err)
+	assert.InDelta(t, 30300, price, 1e-9)
+	price, err = TradePrice(ticker, mkt, "Buy", 2)
+	assert.NoError(t, err)
+	assert.InDelta(t, 30150*1.01, price, 1e-9)
+	price, err = TradePrice(ticker, mkt, "Sell", 2)
+	assert.NoError(t, err)
+	assert.InDelta(t, 28855*0.99, price, 1e-9)
+	_, err = TradePrice(ticker, mkt, "Sell", 3)
+	assert.Error(t, err)
+
+	// a ticker without a bid can't be sold into
+	_, err = TradePrice(Ticker{Pair: "L-BTC/USDT", Ask: 30000}, mkt, "Sell", 0.01)
+	assert.Error(t, err)
+}
+
+// sweepChain serves the unspents of an expired contract at a chain tip past
+// its expiry.
+type sweepChain struct {
+	ChainSource
+	medianTime int64
+	unspents   []*UTXO
+}
+
+func (c *sweepChain) FetchTipMedianTime() (int64, error) {
+	return c.medianTime, nil
+}
+
+func (c *sweepChain) FetchUnspents(address string) ([]*UTXO, error) {
+	return c.unspents, nil
+}
+
+func TestSweepExpiredOrder(t *testing.T) {
+	repo := NewInMemoryOrderRepository()
+	order := newTestOrder(t, testOrder{expiry: time.Hour})
+	assert.NoError(t, repo.SaveOrder(order))
+	assert.NoError(t, repo.UpdateOrderStatus(order.ID, "Expired"))
+
+	// under-funded deposits are refunded too, here failing for lack of fees
+	under := dummyFundingUnspent(t, order)
+	under.Value = 40000
+	under.Prevout.Value, _ = elementsutil.ValueToBytes(under.Value)
+	chain := &sweepChain{medianTime: order.Expiry.Unix() + 1, unspents: []*UTXO{under}}
+	err := sweepExpiredOrder(repo, order, &emptyWallet{}, chain, 0)
+	assert.ErrorContains(t, err, "error sweeping trade")
+	_, status, err := repo.FetchOrderByID(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Expired", status)
+
+	// nothing left to refund
+	chain.unspents = nil
+	assert.NoError(t, sweepExpiredOrder(repo, order, &emptyWallet{}, chain, 0))
+	_, status, err = repo.FetchOrderByID(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "ExpiredEmpty", status)
+	orders, err := repo.FetchOrdersToSweep(network.Testnet.Name)
+	assert.NoError(t, err)
+	assert.Empty(t, orders)
+}
+
+// refundWallet pays the fees of the refunds with its P2WPKH unspents, signed
+// by its key, and fails the broadcasts after the given number.
+type refundWallet struct {
+	feeUnspentsWallet
+	key        *btcec.PrivateKey
+	broadcasts int
+}
+
+func newRefundWallet(t *testing.T, order *Order, broadcasts int) *refundWallet {
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	feeUnspent := *dummyFundingUnspent(t, order)
+	feeUnspent.Index = 2
+	feeUnspent.Value = 100000
+	feeUnspent.Prevout.Value, _ = elementsutil.ValueToBytes(feeUnspent.Value)
+	feeUnspent.Prevout.Script = payment.FromPublicKey(key.PubKey(), &network.Testnet, nil).WitnessScript
+	return &refundWallet{
+		feeUnspentsWallet: feeUnspentsWallet{unspents: []UTXO{feeUnspent}},
+		key:               key,
+		broadcasts:        broadcasts,
+	}
+}
+
+func (w *refundWallet) GetAddress(ctx context.Context, isChange bool) (string, []byte, error) {
+	return "", traderScriptExpected, nil
+}
+
+// SignPset signs the wallet inputs of the pset with a signature of a dummy
+// hash, the transaction is not validated by BroadcastTransaction.
+func (w *refundWallet) SignPset(ctx context.Context, pset string, extractRawTx bool) (string, error) {
+	ptx, err := psetv2.NewPsetFromBase64(pset)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256([]byte(pset))
+	signature := append(ecdsa.Sign(w.key, hash[:]).Serialize(), byte(txscript.SigHashAll))
+	for i := range ptx.Inputs {
+		if len(ptx.Inputs[i].TapLeafScript) == 0 {
+			ptx.Inputs[i].PartialSigs = []psetv2.PartialSig{{PubKey: w.key.PubKey().SerializeCompressed(), Signature: signature}}
+		}
+	}
+	return ptx.ToBase64()
+}
+
+func (w *refundWallet) BroadcastTransaction(ctx context.Context, txHex string) (string, error) {
+	if w.broadcasts == 0 {
+		return "", fmt.Errorf("mempool full")
+	}
+	w.broadcasts--
+	tx, err := transaction.NewTxFromHex(txHex)
+	if err != nil {
+		return "", err
+	}
+	return tx.TxHash().String(), nil
+}
+
+func TestCancelOrder(t *testing.T) {
+	repo := NewInMemoryOrderRepository()
+	order := newTestOrder(t, testOrder{})
+	assert.NoError(t, repo.SaveOrder(order))
+
+	first := dummyFundingUnspent(t, order)
+	second := dummyFundingUnspent(t, order)
+	second.Index = 1
+	wallet := newRefundWallet(t, order, 1)
+	chain := &sweepChain{unspents: []*UTXO{first, second}}
+
+	// the refund broadcast before the failure is recorded anyway
+	_, err := cancelOrder(repo, order, wallet, chain, 0)
+	assert.ErrorContains(t, err, "mempool full")
+	statuses, err := repo.FetchOrderStatuses(order.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 2) {
+		assert.Equal(t, "Cancelled", statuses[1].Status)
+		assert.NotEmpty(t, statuses[1].TxHash)
+	}
+
+	// and each one of them
+	repo = NewInMemoryOrderRepository()
+	assert.NoError(t, repo.SaveOrder(order))
+	wallet.broadcasts = 2
+	txHash, err := cancelOrder(repo, order, wallet, chain, 0)
+	assert.NoError(t, err)
+	statuses, err = repo.FetchOrderStatuses(order.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 3) {
+		assert.NotEqual(t, statuses[1].TxHash, statuses[2].TxHash)
+		assert.Equal(t, txHash, statuses[2].TxHash)
+	}
+}
+
+func TestExecutePartialFills(t *testing.T) {
+	order := newTestOrder(t, testOrder{partialFill: true})
+	usdtSellQuote := &Quote{Type: "Sell", Price: 30000, InputCurrency: "L-BTC", InputValue: 0.001, OutputCurrency: "USDT", OutputValue: 30}
+	usdtOrder := newTestOrder(t, testOrder{partialFill: true, quote: usdtSellQuote})
+
+	// the fees of each fill are kept at the fee rate, as the fee unspents are
+	// selected for
+	assert.Equal(t, uint64(FEE_AMOUNT), partialFillReserve(order, 0))
+	assert.Equal(t, feeHeadroom(1, 1), partialFillReserve(order, 1))
+	assert.Greater(t, partialFillReserve(order, 1), uint64(FEE_AMOUNT))
+	assert.Zero(t, partialFillReserve(usdtOrder, 1))
+
+	// a balance below the fees of a fill fills nothing
+	wallet := &apiWallet{balance: 5000}
+	trades, err := executePartialFills(order, [][]*UTXO{{dummyFundingUnspent(t, order)}}, wallet, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, trades)
+}
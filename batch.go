@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/vulpemventures/go-elements/psetv2"
+)
+
+// MAX_BATCH_SIZE bounds the number of orders fulfilled in one transaction
+const MAX_BATCH_SIZE = 20
+
+// Batch fulfills many funded orders in a single transaction via their batch
+// fulfill leaf: the contract input at index i pays the trader of the i-th
+// trade with the output at index 2i. Ocean's inputs, the change and the fee
+// are shared by all the trades.
+type Batch struct {
+	Trades []*Trade
+	TxID   string
+	// FeeRate is the fee rate in sats/vbyte the transaction is sized at, zero
+	// to pay the fixed FEE_AMOUNT. Fee is the one paid by the last prepared
+	// transaction.
+	FeeRate       float64
+	Fee           uint64
+	walletService WalletService
+}
+
+// NewBatch returns a batch of the given trades, which must all be batchable.
+func NewBatch(walletSvc WalletService, trades []*Trade) (*Batch, error) {
+	if len(trades) == 0 {
+		return nil, fmt.Errorf("empty batch")
+	}
+	if len(trades) > MAX_BATCH_SIZE {
+		return nil, fmt.Errorf("batch of %d trades exceeds the maximum of %d", len(trades), MAX_BATCH_SIZE)
+	}
+	for _, trade := range trades {
+		if !trade.IsBatchable() {
+			return nil, fmt.Errorf("trade of order %s can't be batched", trade.Order.ID)
+		}
+	}
+
+	return &Batch{
+		Trades:        trades,
+		walletService: walletSvc,
+	}, nil
+}
+
+// IsBatchable tells whether the trade can be fulfilled in a batch, that is
+// a funded order with a batch fulfill leaf and a single contract unspent.
+func (t *Trade) IsBatchable() bool {
+	return t.Status == Funded &&
+		len(t.Order.BatchFulfillScript) > 0 &&
+		!t.Order.IsPartial() &&
+		len(t.FundingTopUps) == 0 &&
+		t.FundedAmount() >= t.Order.Input.Amount
+}
+
+// PrepareBatchFulfillTransaction builds the transaction that fulfills all the
+// trades of the batch. The first outputs come in pairs, in the same order of
+// the contract inputs: the one paying the trader and the one of the provider
+// receiving the trade input. They're followed by the excess of over-funded
+// contracts, the change of each output asset and of the fees and the fee
+// itself.
+func (b *Batch) PrepareBatchFulfillTransaction(
+	unspentsForTrade []UTXO,
+	unspentsForFees []UTXO,
+	providerScriptOfTradeInputs []byte,
+	changeProviderScriptOfTradeOutputs []byte,
+	changeProviderScriptOfFees []byte,
+	changeProviderAmountsOfTradeOutputs map[string]uint64,
+	changeProviderAmountOfFees uint64,
+) (*psetv2.Pset, error) {
+	ptx, err := psetv2.New(nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pset: %w", err)
+	}
+	updater, err := psetv2.NewUpdater(ptx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create updater: %w", err)
+	}
+
+	// Offer funding inputs, one for each trade
+	inputIndex := 0
+	for _, trade := range b.Trades {
+		inputIndex, err = trade.addContractInputs(updater, trade.Order.BatchFulfillScript)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Trading inputs
+	for _, unspent := range unspentsForTrade {
+		updater.AddInputs([]psetv2.InputArgs{{
+			Txid:    unspent.Txid,
+			TxIndex: uint32(unspent.Index),
+		}})
+		updater.AddInWitnessUtxo(inputIndex, unspent.Prevout)
+		updater.AddInSighashType(inputIndex, txscript.SigHashAll)
+		inputIndex++
+	}
+
+	// Fee supplier inputs
+	for _, unspent := range unspentsForFees {
+		updater.AddInputs([]psetv2.InputArgs{{
+			Txid:    unspent.Txid,
+			TxIndex: uint32(unspent.Index),
+		}})
+		updater.AddInWitnessUtxo(inputIndex, unspent.Prevout)
+		updater.AddInSighashType(inputIndex, txscript.SigHashAll)
+		inputIndex++
+	}
+
+	//outputs
+	outputAssets := make([]string, 0)
+	for _, trade := range b.Trades {
+		updater.AddOutputs([]psetv2.OutputArgs{
+			{
+				Asset:  trade.Order.Output.Asset,
+				Amount: trade.Order.Output.Amount,
+				Script: trade.Order.TraderScript,
+			},
+			{
+				Asset:  trade.Order.Input.Asset,
+				Amount: trade.Order.Input.Amount,
+				Script: providerScriptOfTradeInputs,
+			},
+		})
+
+		if !containsAsset(outputAssets, trade.Order.Output.Asset) {
+			outputAssets = append(outputAssets, trade.Order.Output.Asset)
+		}
+	}
+
+	// Return the excess of over-funded contracts to the traders
+	for _, trade := range b.Trades {
+		if excess := trade.FundedAmount() - trade.Order.Input.Amount; excess > 0 {
+			updater.AddOutputs([]psetv2.OutputArgs{{
+				Asset:  trade.Order.Input.Asset,
+				Amount: excess,
+				Script: trade.Order.TraderScript,
+			}})
+		}
+	}
+
+	lbtc := currencyToAsset["L-BTC"].AssetHash
+	dust := dustAmount(b.FeeRate, false)
+	feeAmountWithoutCreatingDust := b.feeAmount()
+	for _, asset := range outputAssets {
+		change := changeProviderAmountsOfTradeOutputs[asset]
+		if change == 0 {
+			continue
+		}
+		if asset == lbtc && change < dust {
+			feeAmountWithoutCreatingDust += change
+			continue
+		}
+		updater.AddOutputs([]psetv2.OutputArgs{{
+			Asset:  asset,
+			Amount: change,
+			Script: changeProviderScriptOfTradeOutputs,
+		}})
+	}
+
+	if changeProviderAmountOfFees > 0 {
+		if changeProviderAmountOfFees < dust {
+			feeAmountWithoutCreatingDust += changeProviderAmountOfFees
+		} else {
+			updater.AddOutputs([]psetv2.OutputArgs{{
+				Asset:  lbtc,
+				Amount: changeProviderAmountOfFees,
+				Script: changeProviderScriptOfFees,
+			}})
+		}
+	}
+
+	updater.AddOutputs([]psetv2.OutputArgs{{
+		Asset:  lbtc,
+		Amount: feeAmountWithoutCreatingDust,
+	}})
+
+	return ptx, nil
+}
+
+// Execute fulfills all the trades of the batch in a single transaction.
+func (b *Batch) Execute() error {
+	for _, trade := range b.Trades {
+		if trade.Status != Funded {
+			return fmt.Errorf("trade of order %s is not funded or already executed", trade.Order.ID)
+		}
+	}
+
+	// Get an Address to receive the Trade Input amounts
+	_, providerScript, err := b.walletService.GetAddress(context.Background(), false)
+	if err != nil {
+		return fmt.Errorf("error in GetAddress: %w", err)
+	}
+	_, providerChangeScript, err := b.walletService.GetAddress(context.Background(), true)
+	if err != nil {
+		return fmt.Errorf("error in GetAddress: %w", err)
+	}
+	_, feeChangeScript, err := b.walletService.GetAddress(context.Background(), true)
+	if err != nil {
+		return fmt.Errorf("error in GetAddress: %w", err)
+	}
+
+	// fund the Trade Output amounts of the swaps, once for each asset
+	outputAssets, outputAmounts := make([]string, 0), make(map[string]uint64)
+	for _, trade := range b.Trades {
+		if _, ok := outputAmounts[trade.Order.Output.Asset]; !ok {
+			outputAssets = append(outputAssets, trade.Order.Output.Asset)
+		}
+		outputAmounts[trade.Order.Output.Asset] += trade.Order.Output.Amount
+	}
+	utxosForTrade := make([]UTXO, 0)
+	changeAmountsForTrade := make(map[string]uint64)
+	for _, asset := range outputAssets {
+		utxos, change, err := b.walletService.SelectUtxos(context.Background(), asset, outputAmounts[asset])
+		if err != nil {
+			return fmt.Errorf("error in SelectUtxos for batch : %d %s : %w", outputAmounts[asset], asset, err)
+		}
+		utxosForTrade = append(utxosForTrade, utxos...)
+		changeAmountsForTrade[asset] = change
+	}
+
+	// subsidize the tx fees
+	ptx, err := prepareWithFees(b.walletService, b.FeeRate, len(b.Trades), &b.Fee, func(utxosForFees []UTXO, changeAmountForFees uint64) (*psetv2.Pset, error) {
+		ptx, err := b.PrepareBatchFulfillTransaction(utxosForTrade, utxosForFees, providerScript, providerChangeScript, feeChangeScript, changeAmountsForTrade, changeAmountForFees)
+		if err != nil {
+			return nil, fmt.Errorf("error in PrepareBatchFulfillTransaction: %w", err)
+		}
+		return ptx, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	pbase64, err := ptx.ToBase64()
+	if err != nil {
+		return fmt.Errorf("error in ToBase64")
+	}
+
+	// Sign Ocean's inputs
+	base64, err := b.walletService.SignPset(context.Background(), pbase64, false)
+	if err != nil {
+		return fmt.Errorf("error in SignPset: %w", err)
+	}
+	ptx, err = psetv2.NewPsetFromBase64(base64)
+	if err != nil {
+		return fmt.Errorf("error in decoding base64: %w", err)
+	}
+
+	for i := len(b.Trades); i < len(ptx.Inputs); i++ {
+		err := psetv2.Finalize(ptx, i)
+		if err != nil {
+			return fmt.Errorf("error in finalize: %w", err)
+		}
+	}
+	for i, trade := range b.Trades {
+		witness, err := leafWitness(trade.FundingPayment, trade.Order.BatchFulfillScript)
+		if err != nil {
+			return err
+		}
+		ptx.Inputs[i].FinalScriptWitness = witness
+	}
+
+	txid, err := extractAndBroadcast(b.walletService, ptx)
+	if err != nil {
+		return err
+	}
+	if len(txid) > 0 {
+		b.TxID = txid
+		for _, trade := range b.Trades {
+			trade.TxID = txid
+			trade.Status = Executed
+			trade.Filled = trade.Order.Input.Amount
+		}
+	}
+
+	return nil
+}
+
+// feeAmount returns the network fee to pay, FEE_AMOUNT unless estimated.
+func (b *Batch) feeAmount() uint64 {
+	if b.Fee > 0 {
+		return b.Fee
+	}
+	return FEE_AMOUNT
+}
+
+func containsAsset(assets []string, asset string) bool {
+	for _, a := range assets {
+		if a == asset {
+			return true
+		}
+	}
+	return false
+}
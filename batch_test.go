@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatch_PrepareBatchFulfillTransaction(t *testing.T) {
+	otherTraderScript := append([]byte{0x51, 0x20}, make([]byte, 32)...)
+	first := newTestOrder(t, testOrder{})
+	second := newTestOrder(t, testOrder{
+		traderScript: otherTraderScript,
+		quote:        &Quote{Type: "Buy", Price: 1, InputCurrency: "L-BTC", InputValue: 0.002, OutputCurrency: "L-BTC", OutputValue: 0.00202},
+	})
+	assert.NotEmpty(t, first.BatchFulfillScript)
+
+	trades := []*Trade{}
+	for i, order := range []*Order{first, second} {
+		unspent := dummyFundingUnspent(t, order)
+		unspent.Index = i
+		trade, err := FromFundedOrder(nil, order, unspent)
+		if err != nil {
+			t.Fatal("FromFundedOrder", err)
+		}
+		assert.True(t, trade.IsBatchable())
+		trades = append(trades, trade)
+	}
+
+	batch, err := NewBatch(nil, trades)
+	if err != nil {
+		t.Fatal("NewBatch", err)
+	}
+
+	providerUnspent := *dummyFundingUnspent(t, first)
+	providerUnspent.Index = 2
+	lbtc := currencyToAsset["L-BTC"].AssetHash
+	ptx, err := batch.PrepareBatchFulfillTransaction([]UTXO{providerUnspent}, []UTXO{}, traderScriptExpected, traderScriptExpected, traderScriptExpected, map[string]uint64{lbtc: 0}, 0)
+	if err != nil {
+		t.Fatal("PrepareBatchFulfillTransaction", err)
+	}
+
+	assert.Len(t, ptx.Inputs, 3)
+	// the i-th contract input pays the trader of the i-th order with the
+	// output at twice its index, followed by the provider one
+	for i, trade := range trades {
+		assert.Equal(t, trade.Order.BatchFulfillScript, ptx.Inputs[i].TapLeafScript[0].Script)
+		assert.Equal(t, trade.Order.TraderScript, ptx.Outputs[2*i].Script)
+		assert.Equal(t, trade.Order.Output.Amount, ptx.Outputs[2*i].Value)
+		assert.Equal(t, trade.Order.Input.Amount, ptx.Outputs[2*i+1].Value)
+	}
+
+	// and one shared fee
+	assert.Len(t, ptx.Outputs, 5)
+	assert.Empty(t, ptx.Outputs[4].Script)
+	assert.Equal(t, uint64(FEE_AMOUNT), ptx.Outputs[4].Value)
+}
+
+func TestNewBatch(t *testing.T) {
+	order := newTestOrder(t, testOrder{partialFill: true})
+	trade, err := FromFundedOrder(nil, order, dummyFundingUnspent(t, order))
+	if err != nil {
+		t.Fatal("FromFundedOrder", err)
+	}
+
+	// partial fill orders are fulfilled one by one
+	_, err = NewBatch(nil, []*Trade{trade})
+	assert.Error(t, err)
+	_, err = NewBatch(nil, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/vulpemventures/go-elements/address"
+	"github.com/vulpemventures/go-elements/confidential"
+	"github.com/vulpemventures/go-elements/psetv2"
+)
+
+// blindTransaction blinds the outputs of a transaction spending the unspents
+// of a confidential order. The inputs are the contract unspents followed by
+// the wallet ones, in the same order of the pset. Each output whose index is
+// in blindingKeys is blinded to the given public key. If commitFirstOutput is
+// set, the first output is blinded with the blinders of the order, so that it
+// matches the commitments checked by the confidential fulfill script, and the
+// resulting commitments are verified against them.
+func blindTransaction(
+	ptx *psetv2.Pset,
+	order *Order,
+	inputs []*UTXO,
+	blindingKeys map[int][]byte,
+	commitFirstOutput bool,
+) error {
+	if len(inputs) != len(ptx.Inputs) {
+		return fmt.Errorf("expected %d inputs, got %d", len(ptx.Inputs), len(inputs))
+	}
+
+	outputIndexes := make([]uint32, 0, len(blindingKeys))
+	for index, blindingKey := range blindingKeys {
+		if index >= len(ptx.Outputs) {
+			return fmt.Errorf("output %d out of range", index)
+		}
+		ptx.Outputs[index].BlindingPubkey = blindingKey
+		ptx.Outputs[index].BlinderIndex = 0
+		outputIndexes = append(outputIndexes, uint32(index))
+	}
+	sort.Slice(outputIndexes, func(i, j int) bool {
+		return outputIndexes[i] < outputIndexes[j]
+	})
+
+	// The value blinder of the last blinded output is recomputed to balance
+	// the transaction, hence it can't be the one committed to by the script
+	if commitFirstOutput && (len(outputIndexes) < 2 || outputIndexes[0] != 0) {
+		return fmt.Errorf("the first output must be blinded along with at least another one")
+	}
+	if len(outputIndexes) == 0 {
+		for _, in := range inputs {
+			if in.Prevout.IsConfidential() {
+				return fmt.Errorf("confidential inputs require at least one blinded output")
+			}
+		}
+		return nil
+	}
+
+	// Explicit inputs don't contribute to the blinding factors, still their
+	// asset is needed to prove the assets of the outputs
+	ownedInputs := make([]psetv2.OwnedInput, 0, len(inputs))
+	ownedInputsByIndex := make(map[uint32]psetv2.OwnedInput)
+	for i, in := range inputs {
+		asset := unspentAsset(in)
+		if asset == "" {
+			asset = in.Asset
+		}
+		if asset == "" {
+			return fmt.Errorf("unknown asset for input %s:%d", in.Txid, in.Index)
+		}
+		ownedInput := psetv2.OwnedInput{
+			Index: uint32(i),
+			Value: in.Value,
+			Asset: asset,
+		}
+		if in.Prevout.IsConfidential() {
+			ownedInput.AssetBlinder = in.AssetBlinder
+			ownedInput.ValueBlinder = in.ValueBlinder
+		}
+		ownedInputs = append(ownedInputs, ownedInput)
+
+		if !in.Prevout.IsConfidential() {
+			ownedInput.AssetBlinder = confidential.Zero
+			ownedInput.ValueBlinder = confidential.Zero
+		}
+		ownedInputsByIndex[uint32(i)] = ownedInput
+	}
+
+	generator, err := confidential.NewZKPGeneratorFromOwnedInputs(ownedInputsByIndex, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create blinding generator: %w", err)
+	}
+
+	outputArgs := make([]psetv2.OutputBlindingArgs, 0, len(outputIndexes))
+	if commitFirstOutput {
+		// the generator draws the asset and then the value blinder first, the
+		// commitments are verified once blinded in case that changes
+		fixedBlinders := [][]byte{order.AssetBlinder, order.ValueBlinder}
+		firstOutputGenerator, err := confidential.NewZKPGeneratorFromOwnedInputs(
+			ownedInputsByIndex, &confidential.ZKPGeneratorOpts{
+				Rng: func() ([]byte, error) {
+					if len(fixedBlinders) > 0 {
+						blinder := fixedBlinders[0]
+						fixedBlinders = fixedBlinders[1:]
+						return blinder, nil
+					}
+					return randomBlinder()
+				},
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create blinding generator: %w", err)
+		}
+		args, err := firstOutputGenerator.BlindOutputs(ptx, outputIndexes[:1])
+		if err != nil {
+			return fmt.Errorf("failed to blind first output: %w", err)
+		}
+		outputArgs = append(outputArgs, args...)
+		outputIndexes = outputIndexes[1:]
+	}
+
+	args, err := generator.BlindOutputs(ptx, outputIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to blind outputs: %w", err)
+	}
+	outputArgs = append(outputArgs, args...)
+
+	blinder, err := psetv2.NewBlinder(ptx, ownedInputs, confidential.NewZKPValidator(), generator)
+	if err != nil {
+		return fmt.Errorf("failed to create blinder: %w", err)
+	}
+	if err := blinder.BlindLast(nil, outputArgs); err != nil {
+		return fmt.Errorf("failed to blind pset: %w", err)
+	}
+
+	if commitFirstOutput {
+		assetCommitment, valueCommitment, err := order.OutputCommitments()
+		if err != nil {
+			return err
+		}
+		out := ptx.Outputs[0]
+		if !bytes.Equal(out.AssetCommitment, assetCommitment) || !bytes.Equal(out.ValueCommitment, valueCommitment) {
+			return fmt.Errorf("the first output is not blinded with the blinders of the order")
+		}
+	}
+	return nil
+}
+
+// randomBlinder returns a random valid secp256k1 scalar.
+func randomBlinder() ([]byte, error) {
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	return key.Serialize(), nil
+}
+
+// outputBlindingKeys maps the index of each output of the given pset to the
+// blinding key of its script, if any.
+func outputBlindingKeys(ptx *psetv2.Pset, blindingKeysByScript map[string][]byte) map[int][]byte {
+	blindingKeys := make(map[int][]byte)
+	for i, out := range ptx.Outputs {
+		if len(out.Script) == 0 {
+			continue
+		}
+		if blindingKey, ok := blindingKeysByScript[hex.EncodeToString(out.Script)]; ok {
+			blindingKeys[i] = blindingKey
+		}
+	}
+	return blindingKeys
+}
+
+// blindingKeysOfAddresses returns the blinding keys of the given confidential
+// addresses, indexed by the same script keys.
+func blindingKeysOfAddresses(addressesByScript map[string]string) (map[string][]byte, error) {
+	blindingKeys := make(map[string][]byte)
+	for script, addr := range addressesByScript {
+		info, err := address.FromConfidential(addr)
+		if err != nil {
+			return nil, fmt.Errorf("confidential orders require a confidential Ocean account: %w", err)
+		}
+		blindingKeys[script] = info.BlindingKey
+	}
+	return blindingKeys, nil
+}
+
+func utxoPointers(lists ...[]UTXO) []*UTXO {
+	unspents := make([]*UTXO, 0)
+	for _, list := range lists {
+		for i := range list {
+			unspents = append(unspents, &list[i])
+		}
+	}
+	return unspents
+}
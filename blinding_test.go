@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/vulpemventures/go-elements/address"
+	"github.com/vulpemventures/go-elements/confidential"
+	"github.com/vulpemventures/go-elements/elementsutil"
+	"github.com/vulpemventures/go-elements/psetv2"
+	"github.com/vulpemventures/go-elements/transaction"
+)
+
+func TestBlindTransaction_ConfidentialFulfill(t *testing.T) {
+	makerBlindingKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oceanBlindingKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	makerBlindingKeyHex := hex.EncodeToString(makerBlindingKey.PubKey().SerializeCompressed())
+
+	order := newTestOrder(t, testOrder{makerBlindingKey: makerBlindingKeyHex})
+	assert.True(t, order.IsConfidential())
+	info, err := address.FromConfidential(order.Address)
+	if err != nil {
+		t.Fatal("the contract address must be confidential", err)
+	}
+	assert.Equal(t, order.BlindingPubKey(), info.BlindingKey)
+
+	// the fulfill script checks the commitments of the first output
+	assetCommitment, valueCommitment, err := order.OutputCommitments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, bytes.Contains(order.FulfillScript, assetCommitment[1:]))
+	assert.True(t, bytes.Contains(order.FulfillScript, valueCommitment[1:]))
+
+	// a blinded deposit is unblinded with the contract key
+	deposit := confidentialFundingUnspent(t, order, order.Input.Amount)
+	funding := ReconcileFunding(order, []*UTXO{deposit})
+	assert.Len(t, funding.Exact, 1)
+	assert.Empty(t, funding.Mismatched)
+
+	trade, err := FromFundedOrder(nil, order, deposit)
+	if err != nil {
+		t.Fatal("FromFundedOrder", err)
+	}
+
+	providerUnspent := *dummyFundingUnspent(t, order)
+	providerUnspent.Index = 2
+	providerUnspent.Value = order.Output.Amount
+	providerUnspent.Asset = order.Output.Asset
+	providerUnspent.Prevout.Value, _ = elementsutil.ValueToBytes(order.Output.Amount)
+	providerUnspents := []UTXO{providerUnspent}
+	feeUnspent := providerUnspent
+	feeUnspent.Index = 3
+	feeUnspent.Value = 1000
+	feeValue, _ := elementsutil.ValueToBytes(1000)
+	feeUnspent.Prevout = transaction.NewTxOutput(providerUnspent.Prevout.Asset, feeValue, providerUnspent.Prevout.Script)
+	feeUnspents := []UTXO{feeUnspent}
+
+	providerScript := []byte{0x00, 0x14, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x13, 0x14}
+	ptx, err := trade.PrepareFulfillTransaction(&providerUnspents, &feeUnspents, providerScript, providerScript, providerScript, 0, 500)
+	if err != nil {
+		t.Fatal("PrepareFulfillTransaction", err)
+	}
+
+	blindingKeys := outputBlindingKeys(ptx, map[string][]byte{
+		hex.EncodeToString(order.TraderScript): order.MakerBlindingKey,
+		hex.EncodeToString(providerScript):     oceanBlindingKey.PubKey().SerializeCompressed(),
+	})
+	inputs := append(trade.fundingUnspents(), utxoPointers(providerUnspents, feeUnspents)...)
+	err = blindTransaction(ptx, order, inputs, blindingKeys, true)
+	if err != nil {
+		t.Fatal("blindTransaction", err)
+	}
+
+	// trade, provider, fee change and fee outputs, the last one explicit
+	assert.Len(t, ptx.Outputs, 4)
+	assert.Equal(t, assetCommitment, ptx.Outputs[0].AssetCommitment)
+	assert.Equal(t, valueCommitment, ptx.Outputs[0].ValueCommitment)
+	assert.True(t, ptx.Outputs[1].IsFullyBlinded())
+	assert.True(t, ptx.Outputs[2].IsFullyBlinded())
+	assert.False(t, ptx.Outputs[3].NeedsBlinding())
+
+	// the first output is rejected if it doesn't match the fulfill script
+	other := *order
+	other.Output.Amount++
+	otherPtx, err := trade.PrepareFulfillTransaction(&providerUnspents, &feeUnspents, providerScript, providerScript, providerScript, 0, 500)
+	if err != nil {
+		t.Fatal("PrepareFulfillTransaction", err)
+	}
+	err = blindTransaction(otherPtx, &other, inputs, blindingKeys, true)
+	assert.ErrorContains(t, err, "not blinded with the blinders of the order")
+
+	// the maker unblinds the output it receives
+	utx, err := ptx.UnsignedTx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	revealed, err := confidential.UnblindOutputWithKey(utx.Outputs[0], makerBlindingKey.Serialize())
+	if err != nil {
+		t.Fatal("UnblindOutputWithKey", err)
+	}
+	assert.Equal(t, order.Output.Amount, revealed.Value)
+	assert.Equal(t, order.Output.Asset, elementsutil.TxIDFromBytes(revealed.Asset))
+}
+
+func TestReconcileFunding_BlindedToAnotherKey(t *testing.T) {
+	makerBlindingKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	makerBlindingKeyHex := hex.EncodeToString(makerBlindingKey.PubKey().SerializeCompressed())
+
+	order := newTestOrder(t, testOrder{makerBlindingKey: makerBlindingKeyHex})
+	deposit := confidentialFundingUnspent(t, order, order.Input.Amount)
+
+	// an order with another contract key can't unblind the deposit
+	other := *order
+	other.BlindingKey = makerBlindingKey.Serialize()
+	funding := ReconcileFunding(&other, []*UTXO{deposit})
+	assert.Len(t, funding.Mismatched, 1)
+	assert.Equal(t, "Pending", funding.Status())
+}
+
+// confidentialFundingUnspent returns a contract unspent of the given value
+// blinded to the contract blinding key.
+func confidentialFundingUnspent(t *testing.T, order *Order, value uint64) *UTXO {
+	script, err := address.ToOutputScript(order.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the deposit transaction spends an explicit unspent of the maker
+	makerUnspent := dummyFundingUnspent(t, order)
+	makerUnspent.Index = 5
+	makerUnspent.Asset = order.Input.Asset
+	makerUnspent.Value = value + FEE_AMOUNT
+	makerUnspent.Prevout.Value, _ = elementsutil.ValueToBytes(value + FEE_AMOUNT)
+
+	ptx, err := psetv2.New(nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updater, err := psetv2.NewUpdater(ptx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := updater.AddInputs([]psetv2.InputArgs{{Txid: makerUnspent.Txid, TxIndex: uint32(makerUnspent.Index)}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := updater.AddInWitnessUtxo(0, makerUnspent.Prevout); err != nil {
+		t.Fatal(err)
+	}
+	if err := updater.AddOutputs([]psetv2.OutputArgs{
+		{Asset: order.Input.Asset, Amount: value, Script: script},
+		{Asset: order.Input.Asset, Amount: FEE_AMOUNT},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	blindingKeys := map[int][]byte{0: order.BlindingPubKey()}
+	if err := blindTransaction(ptx, order, []*UTXO{makerUnspent}, blindingKeys, false); err != nil {
+		t.Fatal("blindTransaction", err)
+	}
+	utx, err := ptx.UnsignedTx()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &UTXO{
+		Txid:    utx.TxHash().String(),
+		Index:   0,
+		Prevout: utx.Outputs[0],
+	}
+}
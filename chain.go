@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vulpemventures/go-elements/elementsutil"
+	"github.com/vulpemventures/go-elements/transaction"
+)
+
+// ChainSource reads the Liquid chain, and its mempool, for the contracts of
+// the orders and broadcasts the transactions built by Banco.
+type ChainSource interface {
+	// FetchTransactionHistory returns the transactions funding or spending
+	// the address.
+	FetchTransactionHistory(address string) ([]Transaction, error)
+	// FetchUnspents returns the unspents of the address along with their
+	// prevouts.
+	FetchUnspents(address string) ([]*UTXO, error)
+	FetchPrevout(txHash string, txIndex int) (*transaction.TxOutput, error)
+	BroadcastTransaction(txHex string) (string, error)
+	FetchTipHeight() (int, error)
+	// FetchTipMedianTime returns the median time past of the chain tip, the
+	// time time-locked transactions are checked against.
+	FetchTipMedianTime() (int64, error)
+	// FetchFeeEstimates returns the fee rates in sats/vbyte indexed by the
+	// number of blocks to confirm within.
+	FetchFeeEstimates() (map[string]float64, error)
+}
+
+// feeEstimateTargets are the confirmation targets estimated by the sources
+// that take one at a time, as reported by Esplora.
+var feeEstimateTargets = []int{1, 2, 3, 6, 10, 20, 144, 504, 1008}
+
+// NewChainSource returns the chain source of the given kind: esplora (the
+// default), electrum or elements. The URL is the one of the Esplora API, of
+// the Electrum server or of the elementsd JSON-RPC interface. It can be left
+// empty for Esplora to use the one of blockstream.info for the network.
+func NewChainSource(kind, url, networkName string) (ChainSource, error) {
+	switch kind {
+	case "", "esplora":
+		return NewEsplora(networkName, url)
+	case "electrum":
+		return NewElectrum(url)
+	case "elements":
+		return NewElementsRPC(url)
+	default:
+		return nil, fmt.Errorf("invalid chain source %s", kind)
+	}
+}
+
+// unspentFromPrevout returns the unspent of the given output with the asset
+// and value, if explicit, or the commitments otherwise, the same way Esplora
+// reports them.
+func unspentFromPrevout(txid string, index int, prevout *transaction.TxOutput, confirmed bool) (*UTXO, error) {
+	unspent := &UTXO{Txid: txid, Index: index, Prevout: prevout}
+	unspent.Status.Confirmed = confirmed
+	if prevout.IsConfidential() {
+		unspent.AssetCommitment = hex.EncodeToString(prevout.Asset)
+		unspent.ValueCommitment = hex.EncodeToString(prevout.Value)
+		return unspent, nil
+	}
+
+	value, err := elementsutil.ValueFromBytes(prevout.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value of output %s:%d: %w", txid, index, err)
+	}
+	unspent.Value = value
+	unspent.Asset = unspentAsset(unspent)
+	return unspent, nil
+}
+
+// fetchUnspentsOf returns the unspents of the given outpoints, formatted as
+// txid:index, along with their prevouts.
+func fetchUnspentsOf(chain ChainSource, outpoints []string) ([]*UTXO, error) {
+	unspents := make([]*UTXO, 0, len(outpoints))
+	for _, outpoint := range outpoints {
+		txid, indexStr, ok := strings.Cut(outpoint, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid outpoint %s, must be txid:index", outpoint)
+		}
+		index, err := strconv.Atoi(indexStr)
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("invalid index of outpoint %s", outpoint)
+		}
+		prevout, err := chain.FetchPrevout(txid, index)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching prevout of %s: %w", outpoint, err)
+		}
+		unspent, err := unspentFromPrevout(txid, index, prevout, false)
+		if err != nil {
+			return nil, err
+		}
+		unspents = append(unspents, unspent)
+	}
+	return unspents, nil
+}
+
+// medianTime returns the median of the timestamps of the last blocks.
+func medianTime(timestamps []int64) int64 {
+	if len(timestamps) == 0 {
+		return 0
+	}
+	sorted := append([]int64{}, timestamps...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+// prevoutOf returns the output of the raw transaction.
+func prevoutOf(txHex string, index int) (*transaction.TxOutput, error) {
+	tx, err := transaction.NewTxFromHex(txHex)
+	if err != nil {
+		return nil, fmt.Errorf("error creating transaction from hex: %w", err)
+	}
+	if index < 0 || index >= len(tx.Outputs) {
+		return nil, fmt.Errorf("output %d not found in transaction %s", index, tx.TxHash().String())
+	}
+	return tx.Outputs[index], nil
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vulpemventures/go-elements/transaction"
+)
+
+// chainFixture is an order funded by a single transaction, the one served by
+// the fake chain backends.
+type chainFixture struct {
+	order *Order
+	txid  string
+	txHex string
+}
+
+func newChainFixture(t *testing.T) *chainFixture {
+	order := newTestOrder(t, testOrder{})
+	txHex := fundingTxHex(t, order)
+	tx, _ := transaction.NewTxFromHex(txHex)
+	return &chainFixture{order: order, txid: tx.TxHash().String(), txHex: txHex}
+}
+
+// blockHeader returns an Elements block header with the given timestamp.
+func blockHeader(timestamp uint32) string {
+	header := make([]byte, 80)
+	binary.LittleEndian.PutUint32(header[68:72], timestamp)
+	return hex.EncodeToString(header)
+}
+
+// serveElectrum answers the Electrum requests with the results returned by
+// the handler, sending a header notification before each response.
+func serveElectrum(t *testing.T, handler func(method string, params []json.RawMessage) interface{}) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadBytes('\n')
+					if err != nil {
+						return
+					}
+					var request struct {
+						ID     int               `json:"id"`
+						Method string            `json:"method"`
+						Params []json.RawMessage `json:"params"`
+					}
+					json.Unmarshal(line, &request)
+
+					notification, _ := json.Marshal(map[string]interface{}{
+						"method": "blockchain.headers.subscribe",
+						"params": []interface{}{map[string]interface{}{"height": 1}},
+					})
+					response := map[string]interface{}{"id": request.ID}
+					result := handler(request.Method, request.Params)
+					if err, ok := result.(error); ok {
+						response["error"] = map[string]interface{}{"code": 1, "message": err.Error()}
+					} else {
+						response["result"] = result
+					}
+					data, _ := json.Marshal(response)
+					conn.Write(append(append(notification, '\n'), append(data, '\n')...))
+				}
+			}()
+		}
+	}()
+	return "tcp://" + listener.Addr().String()
+}
+
+func TestElectrum(t *testing.T) {
+	fixture := newChainFixture(t)
+	scriptHash, err := electrumScriptHash(fixture.order.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := serveElectrum(t, func(method string, params []json.RawMessage) interface{} {
+		switch method {
+		case "blockchain.scripthash.get_history":
+			if string(params[0]) != `"`+scriptHash+`"` {
+				return fmt.Errorf("unknown script hash")
+			}
+			return []interface{}{
+				map[string]interface{}{"tx_hash": "aa", "height": 100},
+				map[string]interface{}{"tx_hash": fixture.txid, "height": 0},
+			}
+		case "blockchain.scripthash.listunspent":
+			return []interface{}{map[string]interface{}{"tx_hash": fixture.txid, "tx_pos": 0, "height": 0}}
+		case "blockchain.transaction.get":
+			return fixture.txHex
+		case "blockchain.transaction.broadcast":
+			return fmt.Errorf("bad-txns-inputs-missingorspent")
+		case "blockchain.headers.subscribe":
+			return map[string]interface{}{"height": 110, "hex": blockHeader(0)}
+		case "blockchain.block.header":
+			var height uint32
+			json.Unmarshal(params[0], &height)
+			return blockHeader(1000 + height)
+		case "blockchain.estimatefee":
+			var target int
+			json.Unmarshal(params[0], &target)
+			if target > 2 {
+				return -1
+			}
+			return 0.000001
+		}
+		return fmt.Errorf("unknown method %s", method)
+	})
+
+	electrum, err := NewElectrum(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := electrum.FetchTransactionHistory(fixture.order.Address)
+	assert.NoError(t, err)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, fixture.txid, history[0].TxID)
+		assert.False(t, history[0].Status.Confirmed)
+		assert.True(t, history[1].Status.Confirmed)
+		assert.Equal(t, 100, history[1].Status.BlockHeight)
+		assert.Equal(t, 1100, history[1].Status.BlockTime)
+	}
+
+	unspents, err := electrum.FetchUnspents(fixture.order.Address)
+	assert.NoError(t, err)
+	if assert.Len(t, unspents, 1) {
+		assert.Equal(t, fixture.order.Input.Amount, unspents[0].Value)
+		assert.NoError(t, verifyUnspent(fixture.order, unspents[0]))
+	}
+
+	_, err = electrum.FetchPrevout(fixture.txid, 1)
+	assert.Error(t, err)
+
+	_, err = electrum.BroadcastTransaction(fixture.txHex)
+	assert.EqualError(t, err, "electrum blockchain.transaction.broadcast: bad-txns-inputs-missingorspent")
+
+	height, err := electrum.FetchTipHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, 110, height)
+
+	// median of the blocks 100 to 110
+	medianTime, err := electrum.FetchTipMedianTime()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1105), medianTime)
+
+	estimates, err := electrum.FetchFeeEstimates()
+	assert.NoError(t, err)
+	assert.Len(t, estimates, 2)
+	assert.InDelta(t, 0.1, estimates["1"], 1e-9)
+	assert.InDelta(t, 0.1, estimates["2"], 1e-9)
+
+	// the connection is opened again once dropped
+	electrum.conn.Close()
+	height, err = electrum.FetchTipHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, 110, height)
+}
+
+func TestElementsRPC(t *testing.T) {
+	fixture := newChainFixture(t)
+	calls := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+		if r.Header.Get("Authorization") != "Basic "+auth {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "/wallet/banco", r.URL.Path)
+
+		var request struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		calls = append(calls, request.Method)
+
+		var result interface{}
+		switch request.Method {
+		case "getdescriptorinfo":
+			result = map[string]interface{}{"descriptor": strings.Trim(string(request.Params[0]), `"`) + "#checksum"}
+		case "importdescriptors":
+			result = []interface{}{map[string]interface{}{"success": true}}
+		case "listtransactions":
+			result = []interface{}{
+				map[string]interface{}{"txid": "aa", "confirmations": 2, "blockheight": 100, "blocktime": 1100},
+				map[string]interface{}{"txid": fixture.txid, "confirmations": 0},
+				map[string]interface{}{"txid": fixture.txid, "confirmations": 0},
+			}
+		case "listunspent":
+			result = []interface{}{map[string]interface{}{"txid": fixture.txid, "vout": 0, "confirmations": 1}}
+		case "getrawtransaction":
+			result = fixture.txHex
+		case "getblockcount":
+			result = 110
+		case "getblockchaininfo":
+			result = map[string]interface{}{"mediantime": 1105}
+		case "estimatesmartfee":
+			var target int
+			json.Unmarshal(request.Params[0], &target)
+			if target > 1 {
+				result = map[string]interface{}{"errors": []string{"Insufficient data or no feerate found"}}
+			} else {
+				result = map[string]interface{}{"feerate": 0.000001}
+			}
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"code": -26, "message": "txn-mempool-conflict"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
+	}))
+	defer server.Close()
+
+	rpc, err := NewElementsRPC(strings.Replace(server.URL, "http://", "http://user:pass@", 1) + "/wallet/banco")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := rpc.FetchTransactionHistory(fixture.order.Address)
+	assert.NoError(t, err)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, fixture.txid, history[0].TxID)
+		assert.True(t, history[1].Status.Confirmed)
+		assert.Equal(t, 1100, history[1].Status.BlockTime)
+	}
+
+	unspents, err := rpc.FetchUnspents(fixture.order.Address)
+	assert.NoError(t, err)
+	if assert.Len(t, unspents, 1) {
+		assert.True(t, unspents[0].Status.Confirmed)
+		assert.NoError(t, verifyUnspent(fixture.order, unspents[0]))
+	}
+	// the address is imported once
+	assert.Equal(t, []string{"getdescriptorinfo", "importdescriptors", "listtransactions", "listunspent", "getrawtransaction"}, calls)
+
+	_, err = rpc.BroadcastTransaction(fixture.txHex)
+	assert.EqualError(t, err, "elements sendrawtransaction: txn-mempool-conflict (code -26)")
+
+	height, err := rpc.FetchTipHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, 110, height)
+
+	medianTime, err := rpc.FetchTipMedianTime()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1105), medianTime)
+
+	estimates, err := rpc.FetchFeeEstimates()
+	assert.NoError(t, err)
+	assert.Len(t, estimates, 1)
+	assert.InDelta(t, 0.1, estimates["1"], 1e-9)
+
+	unauthorized, _ := NewElementsRPC(server.URL)
+	_, err = unauthorized.FetchTipHeight()
+	assert.EqualError(t, err, "elements getblockcount: 401 Unauthorized")
+}
+
+func TestNewChainSource(t *testing.T) {
+	source, err := NewChainSource("", "", "testnet")
+	assert.NoError(t, err)
+	assert.IsType(t, &Esplora{}, source)
+
+	tests := []struct {
+		kind string
+		url  string
+	}{
+		{"bitcoind", ""},
+		{"electrum", "electrum.example.com:50001"},
+		{"electrum", "http://electrum.example.com:50001"},
+		{"elements", "localhost:7041"},
+	}
+	for _, tt := range tests {
+		_, err := NewChainSource(tt.kind, tt.url, "testnet")
+		assert.Error(t, err, tt.kind+" "+tt.url)
+	}
+}
+
+func TestFetchUnspentsOf(t *testing.T) {
+	fixture := newChainFixture(t)
+	esplora := newTestEsplora(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tx/"+fixture.txid+"/hex" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(fixture.txHex))
+	})
+
+	unspents, err := fetchUnspentsOf(esplora, []string{fixture.txid + ":0"})
+	assert.NoError(t, err)
+	if assert.Len(t, unspents, 1) {
+		assert.Equal(t, fixture.order.Input.Amount, unspents[0].Value)
+		assert.Equal(t, fixture.order.Input.Asset, unspents[0].Asset)
+	}
+
+	for _, outpoint := range []string{fixture.txid, fixture.txid + ":x", fixture.txid + ":-1", fixture.txid + ":1"} {
+		_, err := fetchUnspentsOf(esplora, []string{outpoint})
+		assert.Error(t, err, outpoint)
+	}
+}
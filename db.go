@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
 const (
-	sqliteAdapter  = "sqlite"
-	sqliteFilename = "db/banco.db"
+	sqliteAdapter   = "sqlite"
+	postgresAdapter = "postgres"
+	sqliteFilename  = "db/banco.db"
 )
 
 type OrderAndStatusRow struct {
@@ -19,143 +22,380 @@ type OrderAndStatusRow struct {
 	FulfillScript []byte `json:"fulfill_script"`
 	RefundScript  []byte `json:"refund_script"`
 	TraderScript  []byte `json:"trader_script"`
-	InputAsset    string `json:"input_asset"`
-	InputAmount   uint64 `json:"input_amount"`
-	OutputAsset   string `json:"output_asset"`
-	OutputAmount  uint64 `json:"output_amount"`
-	Address       string `json:"address"`
-	Status        string `json:"status"`
+	MakerPubKey   []byte `json:"maker_pubkey"`
+	// expiring orders only, unix timestamp
+	Expiry       int64  `json:"expiry"`
+	ExpiryScript []byte `json:"expiry_script"`
+	// explicit orders only
+	BatchFulfillScript []byte `json:"batch_fulfill_script"`
+	// partial fill orders only
+	PartialFillScript []byte `json:"partial_fill_script"`
+	FilledAmount      uint64 `json:"filled_amount"`
+	// confidential orders only
+	MakerBlindingKey []byte `json:"maker_blinding_key"`
+	BlindingKey      []byte `json:"blinding_key"`
+	AssetBlinder     []byte `json:"asset_blinder"`
+	ValueBlinder     []byte `json:"value_blinder"`
+	Mismatched       int    `json:"mismatched_deposits"`
+	InputAsset       string `json:"input_asset"`
+	InputAmount      uint64 `json:"input_amount"`
+	OutputAsset      string `json:"output_asset"`
+	OutputAmount     uint64 `json:"output_amount"`
+	Address          string `json:"address"`
+	Network          string `json:"network"`
+	FeeAsset         string `json:"fee_asset"`
+	FeeAmount        uint64 `json:"fee_amount"`
+	Status           string `json:"status"`
 }
 
-func initDB() (*sql.DB, error) {
-	// Create the db directory if it doesn't exist
-	dir := filepath.Dir(sqliteFilename)
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		err = os.MkdirAll(dir, 0755)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create directory: %v", err)
+// createOrderStatusesTable is the order_statuses table of the legacy
+// databases, see upgradeLegacySQLite
+const createOrderStatusesTable = `CREATE TABLE IF NOT EXISTS %s (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id TEXT,
+    status TEXT CHECK(status IN ('Pending', 'Funded', 'Underfunded', 'Overfunded', 'WrongAsset', 'PartiallyFilled', 'Fulfilled', 'Cancelled', 'Expired')),
+    timestamp TEXT,
+    tx_hash TEXT,
+    FOREIGN KEY(order_id) REFERENCES orders(id)
+	)`
+
+// orderColumns are the columns of an OrderAndStatusRow but the status
+const orderColumns = `o.id, o.timestamp, o.fulfill_script, o.refund_script, o.trader_script, o.input_asset, o.input_amount, o.output_asset, o.output_amount, o.address, o.maker_pubkey, COALESCE(o.expiry, 0), o.expiry_script, o.batch_fulfill_script, o.partial_fill_script, o.filled_amount, o.maker_blinding_key, o.blinding_key, o.asset_blinder, o.value_blinder, o.mismatched_deposits, COALESCE(o.network, ''), COALESCE(o.fee_asset, ''), COALESCE(o.fee_amount, 0)`
+
+// sqlRepository is the OrderRepository backed by a long-lived pool of
+// connections to either a SQLite or a PostgreSQL database.
+type sqlRepository struct {
+	db      *sql.DB
+	adapter string
+}
+
+// NewSQLiteOrderRepository opens the SQLite database at the given path,
+// creating it and applying the pending migrations if needed.
+func NewSQLiteOrderRepository(filename string) (OrderRepository, error) {
+	return newSQLRepository(sqliteAdapter, filename)
+}
+
+func newSQLRepository(adapter, dsn string) (OrderRepository, error) {
+	db, err := openDatabase(adapter, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := NewMigrator(db, adapter)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	_, err = migrator.Up()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate database: %w", err)
+	}
+
+	return &sqlRepository{db: db, adapter: adapter}, nil
+}
+
+// openDatabase returns the pool of connections to the database of the given
+// adapter, either the path of the SQLite database or the PostgreSQL
+// connection string.
+func openDatabase(adapter, dsn string) (*sql.DB, error) {
+	switch adapter {
+	case sqliteAdapter:
+		if dsn == "" {
+			dsn = sqliteFilename
+		}
+		// Create the db directory if it doesn't exist
+		dir := filepath.Dir(dsn)
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			err = os.MkdirAll(dir, 0755)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create directory: %v", err)
+			}
+		}
+	case postgresAdapter:
+		if dsn == "" {
+			return nil, fmt.Errorf("missing connection string for %s", adapter)
 		}
+	default:
+		return nil, fmt.Errorf("unsupported database type: %s", adapter)
 	}
 
-	db, err := sql.Open(sqliteAdapter, sqliteFilename)
+	db, err := sql.Open(adapter, dsn)
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
+	if adapter == sqliteAdapter {
+		// SQLite has a single writer, concurrent ones would fail as busy
+		db.SetMaxOpenConns(1)
+	}
 
 	err = db.Ping()
 	if err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %v", err)
 	}
+	return db, nil
+}
 
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS orders (
-		id TEXT PRIMARY KEY,
-		timestamp TEXT,
-		address TEXT,
-		fulfill_script BLOB,
-		refund_script BLOB,
-		trader_script BLOB,
-		input_asset TEXT,
-		input_amount INTEGER UNSIGNED,
-		output_asset TEXT,
-		output_amount INTEGER UNSIGNED
-	)`)
+// upgradeLegacySQLite brings a database created before the versioned
+// migrations to the initial schema, adding the columns and the statuses
+// introduced since then.
+func upgradeLegacySQLite(db *sql.DB) error {
+	// databases created before the maker key-path support lack the column
+	err := addColumnIfNotExists(db, "orders", "maker_pubkey", "BLOB")
+	if err != nil {
+		return fmt.Errorf("alter table orders: %w", err)
+	}
+	err = addColumnIfNotExists(db, "orders", "mismatched_deposits", "INTEGER DEFAULT 0")
+	if err != nil {
+		return fmt.Errorf("alter table orders: %w", err)
+	}
+	err = addColumnIfNotExists(db, "orders", "filled_amount", "INTEGER UNSIGNED DEFAULT 0")
+	if err != nil {
+		return fmt.Errorf("alter table orders: %w", err)
+	}
+	err = addColumnIfNotExists(db, "orders", "expiry", "INTEGER")
 	if err != nil {
-		return nil, fmt.Errorf("create table orders: %w", err)
+		return fmt.Errorf("alter table orders: %w", err)
+	}
+	// orders created before the expiry leaf keep expiring off-chain after
+	// the 10 minutes they used to
+	_, err = db.Exec(`UPDATE orders SET expiry = CAST(strftime('%s', timestamp) AS INTEGER) + 600 WHERE expiry IS NULL`)
+	if err != nil {
+		return fmt.Errorf("update table orders: %w", err)
+	}
+	for _, column := range []string{"expiry_script", "batch_fulfill_script", "partial_fill_script", "maker_blinding_key", "blinding_key", "asset_blinder", "value_blinder"} {
+		err = addColumnIfNotExists(db, "orders", column, "BLOB")
+		if err != nil {
+			return fmt.Errorf("alter table orders: %w", err)
+		}
 	}
 
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS order_statuses (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    order_id TEXT,
-    status TEXT CHECK(status IN ('Pending', 'Funded', 'Fulfilled', 'Cancelled', 'Expired')),
-    timestamp TEXT,
-    tx_hash TEXT,
-    FOREIGN KEY(order_id) REFERENCES orders(id)
-	)`)
+	_, err = db.Exec(fmt.Sprintf(createOrderStatusesTable, "order_statuses"))
 	if err != nil {
-		return nil, fmt.Errorf("create table order_statuses: %w", err)
+		return fmt.Errorf("create table order_statuses: %w", err)
 	}
 
-	return db, nil
+	// databases created before the funding reconciliation and the partial
+	// fills reject the new statuses
+	err = upgradeOrderStatusesTable(db)
+	if err != nil {
+		return fmt.Errorf("upgrade table order_statuses: %w", err)
+	}
+
+	return nil
 }
 
-func saveOrder(order *Order) error {
-	db, err := sql.Open(sqliteAdapter, sqliteFilename)
+func addColumnIfNotExists(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
 	if err != nil {
 		return err
 	}
-	defer db.Close()
+	defer rows.Close()
 
-	// Start a transaction
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+func upgradeOrderStatusesTable(db *sql.DB) error {
+	var schema string
+	err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'order_statuses'`).Scan(&schema)
+	if err != nil {
+		return err
+	}
+	if strings.Contains(schema, "'PartiallyFilled'") {
+		return nil
+	}
+
+	// SQLite can't alter a CHECK constraint, the table must be rebuilt
 	tx, err := db.Begin()
 	if err != nil {
 		return err
 	}
+	statements := []string{
+		fmt.Sprintf(createOrderStatusesTable, "order_statuses_new"),
+		`INSERT INTO order_statuses_new (id, order_id, status, timestamp, tx_hash)
+		SELECT id, order_id, status, timestamp, tx_hash FROM order_statuses`,
+		`DROP TABLE order_statuses`,
+		`ALTER TABLE order_statuses_new RENAME TO order_statuses`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
 
-	// Convert time.Time to UTC string
-	timestampStr := order.Timestamp.UTC().Format("2006-01-02 15:04:05")
+	return tx.Commit()
+}
 
-	// Prepare the first INSERT statement
-	insertOrderStmt, err := tx.Prepare(`
-		INSERT INTO orders (id, timestamp, fulfill_script, refund_script, trader_script, input_asset, input_amount, output_asset, output_amount, address)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`)
+func (r *sqlRepository) rebind(query string) string {
+	return rebindQuery(r.adapter, query)
+}
+
+// rebindQuery turns the ? placeholders of the query into the $N ones expected
+// by PostgreSQL.
+func rebindQuery(adapter, query string) string {
+	if adapter != postgresAdapter {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func (r *sqlRepository) Close() error {
+	return r.db.Close()
+}
+
+func (r *sqlRepository) SaveOrder(order *Order) error {
+	// Start a transaction
+	tx, err := r.db.Begin()
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
-	defer insertOrderStmt.Close()
+
+	// Convert time.Time to UTC string
+	timestampStr := order.Timestamp.UTC().Format("2006-01-02 15:04:05")
+	networkName := ""
+	if order.Network != nil {
+		networkName = order.Network.Name
+	}
+	// zero for orders that never expire, NULL is left to legacy ones
+	expiry := int64(0)
+	if !order.Expiry.IsZero() {
+		expiry = order.Expiry.Unix()
+	}
 
 	// Execute the first INSERT statement
-	_, err = insertOrderStmt.Exec(order.ID, timestampStr, order.FulfillScript, order.RefundScript, order.TraderScript, order.Input.Asset, order.Input.Amount, order.Output.Asset, order.Output.Amount, order.Address)
+	_, err = tx.Exec(r.rebind(`
+		INSERT INTO orders (id, timestamp, fulfill_script, refund_script, trader_script, input_asset, input_amount, output_asset, output_amount, address, maker_pubkey, expiry, expiry_script, batch_fulfill_script, partial_fill_script, maker_blinding_key, blinding_key, asset_blinder, value_blinder, network, fee_asset, fee_amount)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`), order.ID, timestampStr, order.FulfillScript, order.RefundScript, order.TraderScript, order.Input.Asset, order.Input.Amount, order.Output.Asset, order.Output.Amount, order.Address, order.MakerPubKey, expiry, order.ExpiryScript, order.BatchFulfillScript, order.PartialFillScript, order.MakerBlindingKey, order.BlindingKey, order.AssetBlinder, order.ValueBlinder, networkName, order.Fee.Asset, order.Fee.Amount)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// Prepare the second INSERT statement
-	insertStatusStmt, err := tx.Prepare(`
+	// Execute the second INSERT statement
+	_, err = tx.Exec(r.rebind(`
 		INSERT INTO order_statuses (order_id, status, timestamp)
 		VALUES (?, 'Pending', ?)
-	`)
+	`), order.ID, timestampStr)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
-	defer insertStatusStmt.Close()
 
-	// Execute the second INSERT statement
-	_, err = insertStatusStmt.Exec(order.ID, timestampStr)
+	// Commit the transaction
+	err = tx.Commit()
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// Commit the transaction
-	err = tx.Commit()
+	return nil
+}
+
+func (r *sqlRepository) UpdateOrderStatus(id string, status string) error {
+	return r.appendOrderStatus(id, status, "")
+}
+
+func (r *sqlRepository) UpdateOrderStatusWithTxHash(id string, status string, txHash string) error {
+	return r.appendOrderStatus(id, status, txHash)
+}
+
+func (r *sqlRepository) appendOrderStatus(id string, status string, txHash string) error {
+	// Convert time.Now() to UTC string
+	timestampStr := time.Now().UTC().Format("2006-01-02 15:04:05")
+
+	// an empty txHash matches the current status whatever its txid
+	_, err := r.db.Exec(r.rebind(`
+			INSERT INTO order_statuses (order_id, status, timestamp, tx_hash)
+			SELECT ?, ?, ?, NULLIF(?, '')
+			WHERE NOT EXISTS (
+				SELECT 1 FROM order_statuses
+				WHERE id = (SELECT MAX(id) FROM order_statuses WHERE order_id = ?)
+				AND status = ? AND (? = '' OR COALESCE(tx_hash, '') = ?)
+			)
+		`), id, status, timestampStr, txHash, id, status, txHash, txHash)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 
 	return nil
 }
 
-func updateOrderStatus(id string, status string) error {
-	db, err := sql.Open(sqliteAdapter, sqliteFilename)
+func (r *sqlRepository) FetchOrderStatuses(id string) ([]*StatusChange, error) {
+	rows, err := r.db.Query(r.rebind(`
+		SELECT status, timestamp, COALESCE(tx_hash, '')
+		FROM order_statuses
+		WHERE order_id = ?
+		ORDER BY id`), id)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer db.Close()
+	defer rows.Close()
 
-	// Convert time.Now() to string
-	timestampStr := time.Now().Format("2006-01-02 15:04:05")
+	var statuses []*StatusChange
+	for rows.Next() {
+		var status, timestampStr, txHash string
+		err := rows.Scan(&status, &timestampStr, &txHash)
+		if err != nil {
+			return nil, err
+		}
+		timestamp, err := time.Parse("2006-01-02 15:04:05", timestampStr)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, &StatusChange{
+			Status:    status,
+			Timestamp: timestamp,
+			TxHash:    txHash,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	_, err = db.Exec(`
-			UPDATE order_statuses
-			SET status = ?, timestamp = ?
-			WHERE order_id = ?
-		`, status, timestampStr, id)
+	return statuses, nil
+}
+
+func (r *sqlRepository) AddOrderFilledAmount(id string, amount uint64) error {
+	_, err := r.db.Exec(r.rebind(`
+			UPDATE orders
+			SET filled_amount = filled_amount + ?
+			WHERE id = ?
+		`), amount, id)
 	if err != nil {
 		return err
 	}
@@ -163,19 +403,34 @@ func updateOrderStatus(id string, status string) error {
 	return nil
 }
 
-func fetchOrdersToFulfill() ([]*Order, error) {
-	db, err := sql.Open(sqliteAdapter, sqliteFilename)
+func (r *sqlRepository) FlagOrderMismatchedDeposits(id string, mismatched int) error {
+	_, err := r.db.Exec(r.rebind(`
+			UPDATE orders
+			SET mismatched_deposits = ?
+			WHERE id = ?
+		`), mismatched, id)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer db.Close()
 
-	rows, err := db.Query(`
-    SELECT o.id, o.timestamp, o.fulfill_script, o.refund_script, o.trader_script, o.input_asset, o.input_amount, o.output_asset, o.output_amount, o.address
+	return nil
+}
+
+// FetchOrdersToFulfill returns the orders that may still be funded.
+func (r *sqlRepository) FetchOrdersToFulfill(networkName string) ([]*Order, error) {
+	return r.fetchOrders(networkName, `os.status IN ('Pending', 'Funded', 'Underfunded', 'Overfunded', 'PartiallyFilled')`)
+}
+
+func (r *sqlRepository) FetchOrdersToSweep(networkName string) ([]*Order, error) {
+	return r.fetchOrders(networkName, `os.status = 'Expired' AND o.expiry_script IS NOT NULL AND (os.tx_hash IS NULL OR os.tx_hash = '')`)
+}
+
+func (r *sqlRepository) fetchOrders(networkName string, where string) ([]*Order, error) {
+	rows, err := r.db.Query(r.rebind(`
+    SELECT `+orderColumns+`
     FROM orders o
-    JOIN order_statuses os ON o.id = os.order_id
-		WHERE os.status = 'Pending' OR os.status = 'Funded'
-`)
+    JOIN order_statuses os ON os.id = (SELECT MAX(id) FROM order_statuses WHERE order_id = o.id)
+		WHERE o.network = ? AND `+where), networkName)
 	if err != nil {
 		return nil, err
 	}
@@ -183,45 +438,17 @@ func fetchOrdersToFulfill() ([]*Order, error) {
 
 	var orders []*Order
 	for rows.Next() {
-		var order OrderAndStatusRow
-		err := rows.Scan(&order.ID, &order.Timestamp, &order.FulfillScript, &order.RefundScript, &order.TraderScript, &order.InputAsset, &order.InputAmount, &order.OutputAsset, &order.OutputAmount, &order.Address)
+		var row OrderAndStatusRow
+		err := rows.Scan(&row.ID, &row.Timestamp, &row.FulfillScript, &row.RefundScript, &row.TraderScript, &row.InputAsset, &row.InputAmount, &row.OutputAsset, &row.OutputAmount, &row.Address, &row.MakerPubKey, &row.Expiry, &row.ExpiryScript, &row.BatchFulfillScript, &row.PartialFillScript, &row.FilledAmount, &row.MakerBlindingKey, &row.BlindingKey, &row.AssetBlinder, &row.ValueBlinder, &row.Mismatched, &row.Network, &row.FeeAsset, &row.FeeAmount)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				// Handle no rows in result set, for example by returning an empty slice
-				return []*Order{}, nil
-			} else {
-				// Handle other errors
-				return nil, err
-			}
+			return nil, err
 		}
 
-		timestamp, err := time.Parse("2006-01-02 15:04:05", order.Timestamp)
+		order, err := row.toOrder()
 		if err != nil {
 			return nil, err
 		}
-
-		orders = append(orders, &Order{
-			ID:            order.ID,
-			Timestamp:     timestamp,
-			FulfillScript: order.FulfillScript,
-			RefundScript:  order.RefundScript,
-			TraderScript:  order.TraderScript,
-			Input: struct {
-				Asset  string
-				Amount uint64
-			}{
-				Asset:  order.InputAsset,
-				Amount: order.InputAmount,
-			},
-			Output: struct {
-				Asset  string
-				Amount uint64
-			}{
-				Asset:  order.OutputAsset,
-				Amount: order.OutputAmount,
-			},
-			Address: order.Address,
-		})
+		orders = append(orders, order)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -231,18 +458,59 @@ func fetchOrdersToFulfill() ([]*Order, error) {
 	return orders, nil
 }
 
-func fetchOrderIDByAddress(address string) (string, error) {
-	db, err := sql.Open(sqliteAdapter, sqliteFilename)
+func (r *sqlRepository) ListOrders(networkName string, status string, limit int, offset int) ([]*OrderWithStatus, error) {
+	query := `SELECT ` + orderColumns + `, s.status
+		FROM orders o
+		JOIN order_statuses s ON s.id = (SELECT MAX(id) FROM order_statuses WHERE order_id = o.id)
+		WHERE o.network = ?`
+	args := []interface{}{networkName}
+	if status != "" {
+		query += ` AND s.status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY o.timestamp DESC, o.id`
+	if limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, limit, offset)
+	} else if offset > 0 {
+		return nil, fmt.Errorf("offset requires a limit")
+	}
+
+	rows, err := r.db.Query(r.rebind(query), args...)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []*OrderWithStatus{}
+	for rows.Next() {
+		var row OrderAndStatusRow
+		err := rows.Scan(&row.ID, &row.Timestamp, &row.FulfillScript, &row.RefundScript, &row.TraderScript, &row.InputAsset, &row.InputAmount, &row.OutputAsset, &row.OutputAmount, &row.Address, &row.MakerPubKey, &row.Expiry, &row.ExpiryScript, &row.BatchFulfillScript, &row.PartialFillScript, &row.FilledAmount, &row.MakerBlindingKey, &row.BlindingKey, &row.AssetBlinder, &row.ValueBlinder, &row.Mismatched, &row.Network, &row.FeeAsset, &row.FeeAmount, &row.Status)
+		if err != nil {
+			return nil, err
+		}
+
+		order, err := row.toOrder()
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, &OrderWithStatus{Order: order, Status: row.Status})
 	}
-	defer db.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func (r *sqlRepository) FetchOrderIDByAddress(address string) (string, error) {
 	var ID string
 	query := `SELECT o.id
-		FROM orders o 
+		FROM orders o
 		WHERE o.address = ?`
-	row := db.QueryRow(query, address)
-	err = row.Scan(&ID)
+	row := r.db.QueryRow(r.rebind(query), address)
+	err := row.Scan(&ID)
 	if err != nil {
 		return "", err
 	}
@@ -250,49 +518,79 @@ func fetchOrderIDByAddress(address string) (string, error) {
 	return ID, nil
 }
 
-func fetchOrderByID(id string) (*Order, string, error) {
-	db, err := sql.Open(sqliteAdapter, sqliteFilename)
+func (r *sqlRepository) FetchOrderByID(id string) (*Order, string, error) {
+	var row OrderAndStatusRow
+	query := `SELECT ` + orderColumns + `, s.status
+				FROM orders o
+				JOIN order_statuses s ON s.id = (SELECT MAX(id) FROM order_statuses WHERE order_id = o.id)
+				WHERE o.id = ?`
+	err := r.db.QueryRow(r.rebind(query), id).Scan(&row.ID, &row.Timestamp, &row.FulfillScript, &row.RefundScript, &row.TraderScript, &row.InputAsset, &row.InputAmount, &row.OutputAsset, &row.OutputAmount, &row.Address, &row.MakerPubKey, &row.Expiry, &row.ExpiryScript, &row.BatchFulfillScript, &row.PartialFillScript, &row.FilledAmount, &row.MakerBlindingKey, &row.BlindingKey, &row.AssetBlinder, &row.ValueBlinder, &row.Mismatched, &row.Network, &row.FeeAsset, &row.FeeAmount, &row.Status)
 	if err != nil {
 		return nil, "", err
 	}
-	defer db.Close()
-	var order OrderAndStatusRow
-	query := `SELECT o.id, o.timestamp, o.fulfill_script, o.refund_script, o.trader_script, 
-				o.input_asset, o.input_amount, o.output_asset, o.output_amount, o.address, s.status 
-				FROM orders o 
-				JOIN order_statuses s ON o.id = s.order_id 
-				WHERE o.id = ?`
-	row := db.QueryRow(query, id)
-	err = row.Scan(&order.ID, &order.Timestamp, &order.FulfillScript, &order.RefundScript, &order.TraderScript, &order.InputAsset, &order.InputAmount, &order.OutputAsset, &order.OutputAmount, &order.Address, &order.Status)
+
+	order, err := row.toOrder()
 	if err != nil {
 		return nil, "", err
 	}
 
-	timestamp, err := time.Parse("2006-01-02 15:04:05", order.Timestamp)
+	return order, row.Status, nil
+}
+
+func (row *OrderAndStatusRow) toOrder() (*Order, error) {
+	timestamp, err := time.Parse("2006-01-02 15:04:05", row.Timestamp)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
 	return &Order{
-		ID:            order.ID,
-		Timestamp:     timestamp,
-		FulfillScript: order.FulfillScript,
-		RefundScript:  order.RefundScript,
-		TraderScript:  order.TraderScript,
+		ID:                 row.ID,
+		Timestamp:          timestamp,
+		FulfillScript:      row.FulfillScript,
+		RefundScript:       row.RefundScript,
+		TraderScript:       row.TraderScript,
+		MakerPubKey:        row.MakerPubKey,
+		ExpiryScript:       row.ExpiryScript,
+		Expiry:             expiryTime(row.Expiry),
+		BatchFulfillScript: row.BatchFulfillScript,
+		PartialFillScript:  row.PartialFillScript,
+		FilledAmount:       row.FilledAmount,
+		MakerBlindingKey:   row.MakerBlindingKey,
+		BlindingKey:        row.BlindingKey,
+		AssetBlinder:       row.AssetBlinder,
+		ValueBlinder:       row.ValueBlinder,
+		Mismatched:         row.Mismatched,
 		Input: struct {
 			Asset  string
 			Amount uint64
 		}{
-			Asset:  order.InputAsset,
-			Amount: order.InputAmount,
+			Asset:  row.InputAsset,
+			Amount: row.InputAmount,
 		},
 		Output: struct {
 			Asset  string
 			Amount uint64
 		}{
-			Asset:  order.OutputAsset,
-			Amount: order.OutputAmount,
+			Asset:  row.OutputAsset,
+			Amount: row.OutputAmount,
+		},
+		Fee: struct {
+			Asset  string
+			Amount uint64
+		}{
+			Asset:  row.FeeAsset,
+			Amount: row.FeeAmount,
 		},
-		Address: order.Address,
-	}, order.Status, nil
+		Address: row.Address,
+		Network: SupportedNetworks[row.Network],
+	}, nil
+}
+
+// expiryTime converts the stored unix expiry, zero for orders that never
+// expire.
+func expiryTime(expiry int64) time.Time {
+	if expiry == 0 {
+		return time.Time{}
+	}
+	return time.Unix(expiry, 0)
 }
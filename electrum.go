@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vulpemventures/go-elements/address"
+	"github.com/vulpemventures/go-elements/transaction"
+)
+
+// electrumTimeout bounds every request to the Electrum server.
+const electrumTimeout = 30 * time.Second
+
+// Electrum is the chain source backed by an Electrum server indexing Liquid,
+// like the one Ocean connects to, reached at tcp://host:port or
+// ssl://host:port.
+type Electrum struct {
+	addr   string
+	useTLS bool
+
+	// one request at a time on a single connection, opened on demand
+	lock   sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID int
+}
+
+type electrumRequest struct {
+	ID     int           `json:"id"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+type electrumResponse struct {
+	ID     *int            `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// electrumError is an error returned by the server, as opposed to a failure
+// of the connection.
+type electrumError struct {
+	Method  string
+	Message string
+}
+
+func (e *electrumError) Error() string {
+	return fmt.Sprintf("electrum %s: %s", e.Method, e.Message)
+}
+
+func NewElectrum(electrumURL string) (*Electrum, error) {
+	u, err := url.Parse(electrumURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid electrum URL: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid electrum URL %s: missing host", electrumURL)
+	}
+	switch u.Scheme {
+	case "tcp":
+		return &Electrum{addr: u.Host}, nil
+	case "ssl", "tls":
+		return &Electrum{addr: u.Host, useTLS: true}, nil
+	default:
+		return nil, fmt.Errorf("invalid electrum URL %s: scheme must be tcp or ssl", electrumURL)
+	}
+}
+
+func (e *Electrum) FetchTransactionHistory(addr string) ([]Transaction, error) {
+	scriptHash, err := electrumScriptHash(addr)
+	if err != nil {
+		return nil, err
+	}
+	var history []struct {
+		TxHash string `json:"tx_hash"`
+		Height int    `json:"height"`
+	}
+	err = e.call("blockchain.scripthash.get_history", []interface{}{scriptHash}, &history)
+	if err != nil {
+		return nil, err
+	}
+
+	// newest first, mempool ones on top, as Esplora does
+	times := make(map[int]int64)
+	transactions := make([]Transaction, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		tx := Transaction{TxID: history[i].TxHash}
+		// heights of unconfirmed transactions are 0 or -1
+		if height := history[i].Height; height > 0 {
+			if _, ok := times[height]; !ok {
+				times[height], err = e.fetchBlockTime(height)
+				if err != nil {
+					return nil, err
+				}
+			}
+			tx.Status.Confirmed = true
+			tx.Status.BlockHeight = height
+			tx.Status.BlockTime = int(times[height])
+		}
+		transactions = append(transactions, tx)
+	}
+	return transactions, nil
+}
+
+func (e *Electrum) FetchUnspents(addr string) ([]*UTXO, error) {
+	scriptHash, err := electrumScriptHash(addr)
+	if err != nil {
+		return nil, err
+	}
+	var unspents []struct {
+		TxHash string `json:"tx_hash"`
+		TxPos  int    `json:"tx_pos"`
+		Height int    `json:"height"`
+	}
+	err = e.call("blockchain.scripthash.listunspent", []interface{}{scriptHash}, &unspents)
+	if err != nil {
+		return nil, err
+	}
+
+	utxos := make([]*UTXO, 0, len(unspents))
+	for _, u := range unspents {
+		prevout, err := e.FetchPrevout(u.TxHash, u.TxPos)
+		if err != nil {
+			return nil, err
+		}
+		utxo, err := unspentFromPrevout(u.TxHash, u.TxPos, prevout, u.Height > 0)
+		if err != nil {
+			return nil, err
+		}
+		utxos = append(utxos, utxo)
+	}
+	return utxos, nil
+}
+
+func (e *Electrum) FetchPrevout(txHash string, txIndex int) (*transaction.TxOutput, error) {
+	var txHex string
+	err := e.call("blockchain.transaction.get", []interface{}{txHash}, &txHex)
+	if err != nil {
+		return nil, err
+	}
+	return prevoutOf(txHex, txIndex)
+}
+
+func (e *Electrum) BroadcastTransaction(txHex string) (string, error) {
+	var txid string
+	err := e.call("blockchain.transaction.broadcast", []interface{}{txHex}, &txid)
+	if err != nil {
+		return "", err
+	}
+	return txid, nil
+}
+
+func (e *Electrum) FetchTipHeight() (int, error) {
+	var tip struct {
+		Height int `json:"height"`
+	}
+	err := e.call("blockchain.headers.subscribe", []interface{}{}, &tip)
+	if err != nil {
+		return 0, err
+	}
+	return tip.Height, nil
+}
+
+// FetchTipMedianTime returns the median time of the last 11 blocks.
+func (e *Electrum) FetchTipMedianTime() (int64, error) {
+	height, err := e.FetchTipHeight()
+	if err != nil {
+		return 0, err
+	}
+	timestamps := []int64{}
+	for h := height; h >= 0 && h > height-11; h-- {
+		timestamp, err := e.fetchBlockTime(h)
+		if err != nil {
+			return 0, err
+		}
+		timestamps = append(timestamps, timestamp)
+	}
+	return medianTime(timestamps), nil
+}
+
+// FetchFeeEstimates asks the server an estimate for each target, in BTC/kB.
+// The targets the server can't estimate are left out.
+func (e *Electrum) FetchFeeEstimates() (map[string]float64, error) {
+	estimates := make(map[string]float64)
+	for _, target := range feeEstimateTargets {
+		var btcPerKB float64
+		err := e.call("blockchain.estimatefee", []interface{}{target}, &btcPerKB)
+		if err != nil {
+			return nil, err
+		}
+		if btcPerKB > 0 {
+			estimates[strconv.Itoa(target)] = btcPerKB * 1e5
+		}
+	}
+	return estimates, nil
+}
+
+// fetchBlockTime returns the timestamp in the header of the block at the
+// given height. Elements headers start as Bitcoin ones do: version, previous
+// block hash, merkle root and time.
+func (e *Electrum) fetchBlockTime(height int) (int64, error) {
+	var headerHex string
+	err := e.call("blockchain.block.header", []interface{}{height}, &headerHex)
+	if err != nil {
+		return 0, err
+	}
+	header, err := hex.DecodeString(headerHex)
+	if err != nil || len(header) < 72 {
+		return 0, fmt.Errorf("invalid header of block %d", height)
+	}
+	return int64(binary.LittleEndian.Uint32(header[68:72])), nil
+}
+
+// call sends the request and decodes the result, opening the connection again
+// once if it dropped.
+func (e *Electrum) call(method string, params []interface{}, result interface{}) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if e.conn == nil {
+			err = e.connect()
+			if err != nil {
+				return err
+			}
+		}
+		err = e.roundTrip(method, params, result)
+		var serverErr *electrumError
+		if err == nil || errors.As(err, &serverErr) {
+			return err
+		}
+		e.conn.Close()
+		e.conn = nil
+	}
+	return fmt.Errorf("electrum %s: %w", method, err)
+}
+
+func (e *Electrum) connect() error {
+	dialer := &net.Dialer{Timeout: electrumTimeout}
+	var conn net.Conn
+	var err error
+	if e.useTLS {
+		host, _, _ := net.SplitHostPort(e.addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", e.addr, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", e.addr)
+	}
+	if err != nil {
+		return fmt.Errorf("error connecting to electrum server %s: %w", e.addr, err)
+	}
+	e.conn = conn
+	e.reader = bufio.NewReader(conn)
+	return nil
+}
+
+func (e *Electrum) roundTrip(method string, params []interface{}, result interface{}) error {
+	e.nextID++
+	id := e.nextID
+	request, err := json.Marshal(electrumRequest{ID: id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	e.conn.SetDeadline(time.Now().Add(electrumTimeout))
+	_, err = e.conn.Write(append(request, '\n'))
+	if err != nil {
+		return err
+	}
+
+	for {
+		line, err := e.reader.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+		var response electrumResponse
+		err = json.Unmarshal(line, &response)
+		if err != nil {
+			return fmt.Errorf("invalid response: %w", err)
+		}
+		// skip the notifications of the subscriptions, e.g. new headers
+		if response.ID == nil || *response.ID != id {
+			continue
+		}
+
+		if len(response.Error) > 0 && string(response.Error) != "null" {
+			var message struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(response.Error, &message) != nil || message.Message == "" {
+				message.Message = string(response.Error)
+			}
+			return &electrumError{Method: method, Message: message.Message}
+		}
+		err = json.Unmarshal(response.Result, result)
+		if err != nil {
+			return &electrumError{Method: method, Message: fmt.Sprintf("invalid result: %v", err)}
+		}
+		return nil
+	}
+}
+
+// electrumScriptHash returns the hash of the output script of the address
+// used as key by the server: its sha256 with the bytes reversed.
+func electrumScriptHash(addr string) (string, error) {
+	script, err := address.ToOutputScript(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid address: %w", err)
+	}
+	hash := sha256.Sum256(script)
+	for i, j := 0, len(hash)-1; i < j; i, j = i+1, j-1 {
+		hash[i], hash[j] = hash[j], hash[i]
+	}
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -1,14 +1,33 @@
 package main
 
 import (
+	"container/list"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/vulpemventures/go-elements/transaction"
 )
 
+const (
+	esploraTimeout = 15 * time.Second
+	// retries of the requests failed for the network, rate limiting or server
+	// errors, waiting twice as much each time
+	esploraMaxRetries = 3
+	esploraBackoff    = 500 * time.Millisecond
+	// raw transactions kept in memory by FetchPrevout
+	esploraTxCacheSize = 1000
+)
+
+// esploraClient is the HTTP client shared by all the Esplora instances.
+var esploraClient = &http.Client{Timeout: esploraTimeout}
+
 type Transaction struct {
 	TxID   string `json:"txid"`
 	Status struct {
@@ -22,6 +41,26 @@ type Transaction struct {
 type Esplora struct {
 	BaseAPIURL  string
 	NetworkName string
+	// the wait before the first retry, esploraBackoff if zero
+	Backoff time.Duration
+
+	txCache *txCache
+}
+
+// EsploraError is returned for the responses with a non-2xx status.
+type EsploraError struct {
+	StatusCode int
+	URL        string
+	Body       string
+}
+
+func (e *EsploraError) Error() string {
+	return fmt.Sprintf("esplora %s: %d %s", e.URL, e.StatusCode, e.Body)
+}
+
+// Temporary tells whether the request may succeed if retried.
+func (e *EsploraError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
 }
 
 var EsploraAPIURLs = map[string]string{
@@ -36,33 +75,30 @@ var EsploraURLs = map[string]string{
 	"regtest": "http://localhost:5001",
 }
 
-func NewEsplora(networkName string) (*Esplora, error) {
-	// Get the base API URL for the network
-	baseAPIURL, ok := EsploraAPIURLs[networkName]
+// NewEsplora returns the Esplora client of the network, at the given base API
+// URL or at the default one of the network if empty.
+func NewEsplora(networkName, baseAPIURL string) (*Esplora, error) {
+	defaultURL, ok := EsploraAPIURLs[networkName]
 	if !ok {
 		return nil, fmt.Errorf("invalid network %s", networkName)
 	}
+	if baseAPIURL == "" {
+		baseAPIURL = defaultURL
+	}
 
 	return &Esplora{
-		BaseAPIURL:  baseAPIURL,
+		BaseAPIURL:  strings.TrimSuffix(baseAPIURL, "/"),
 		NetworkName: networkName,
+		txCache:     newTxCache(esploraTxCacheSize),
 	}, nil
 }
 
 func (e *Esplora) FetchTransactionHistory(address string) ([]Transaction, error) {
-	apiURL := fmt.Sprintf("%s/address/%s/txs", e.BaseAPIURL, address)
-
-	resp, err := http.Get(apiURL)
+	body, err := e.get(fmt.Sprintf("/address/%s/txs", address))
 	if err != nil {
 		return nil, fmt.Errorf("error fetching transaction history: %w", err)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	resp.Body.Close()
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
-	}
-
 	var transactions []Transaction
 	err = json.Unmarshal(body, &transactions)
 	if err != nil {
@@ -72,57 +108,216 @@ func (e *Esplora) FetchTransactionHistory(address string) ([]Transaction, error)
 	return transactions, nil
 }
 
+// FetchPrevout returns the output of the transaction, fetched once and then
+// kept in memory.
 func (e *Esplora) FetchPrevout(txHash string, txIndex int) (*transaction.TxOutput, error) {
-	apiURL := fmt.Sprintf("%s/tx/%s/hex", e.BaseAPIURL, txHash)
+	txHex, ok := e.txCache.get(txHash)
+	if !ok {
+		body, err := e.get(fmt.Sprintf("/tx/%s/hex", txHash))
+		if err != nil {
+			return nil, fmt.Errorf("error fetching raw transaction: %w", err)
+		}
+		txHex = string(body)
+	}
 
-	resp, err := http.Get(apiURL)
+	prevout, err := prevoutOf(txHex, txIndex)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching raw transaction: %v", err)
+		return nil, err
 	}
+	e.txCache.add(txHash, txHex)
+	return prevout, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
-	resp.Body.Close()
+func (e *Esplora) FetchUnspents(address string) ([]*UTXO, error) {
+	body, err := e.get(fmt.Sprintf("/address/%s/utxo", address))
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %v", err)
+		return nil, fmt.Errorf("error fetching UTXOs: %w", err)
 	}
 
-	tx, err := transaction.NewTxFromHex(string(body))
+	var utxos []*UTXO
+	err = json.Unmarshal(body, &utxos)
 	if err != nil {
-		return nil, fmt.Errorf("error creating transaction from hex: %v", err)
+		return nil, fmt.Errorf("error unmarshaling JSON: %w", err)
+	}
 
+	for _, unspent := range utxos {
+		prevout, err := e.FetchPrevout(unspent.Txid, unspent.Index)
+		if err != nil {
+			return nil, err
+		}
+		unspent.Prevout = prevout
 	}
 
-	txOutput := tx.Outputs[txIndex]
-	return txOutput, nil
+	return utxos, nil
 }
 
-func (e *Esplora) FetchUnspents(address string) ([]*UTXO, error) {
-	apiURL := fmt.Sprintf("%s/address/%s/utxo", e.BaseAPIURL, address)
+// BroadcastTransaction broadcasts the raw transaction and returns its txid.
+func (e *Esplora) BroadcastTransaction(txHex string) (string, error) {
+	body, err := e.do(http.MethodPost, "/tx", txHex)
+	if err != nil {
+		return "", fmt.Errorf("error broadcasting transaction: %w", err)
+	}
+	return string(body), nil
+}
 
-	resp, err := http.Get(apiURL)
+func (e *Esplora) FetchTipHeight() (int, error) {
+	body, err := e.get("/blocks/tip/height")
 	if err != nil {
-		return nil, fmt.Errorf("error fetching UTXOs: %v", err)
+		return 0, fmt.Errorf("error fetching tip height: %w", err)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	resp.Body.Close()
+	height, err := strconv.Atoi(strings.TrimSpace(string(body)))
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %v", err)
+		return 0, fmt.Errorf("invalid tip height %s: %w", body, err)
 	}
+	return height, nil
+}
 
-	var utxos []*UTXO
-	err = json.Unmarshal(body, &utxos)
+// FetchTipMedianTime returns the median time past of the chain tip, the time
+// time-locked transactions are checked against.
+func (e *Esplora) FetchTipMedianTime() (int64, error) {
+	hash, err := e.get("/blocks/tip/hash")
 	if err != nil {
-		return nil, fmt.Errorf("error unmarshaling JSON: %v", err)
+		return 0, fmt.Errorf("error fetching tip hash: %w", err)
 	}
 
-	for _, unspent := range utxos {
-		prevout, err := e.FetchPrevout(unspent.Txid, unspent.Index)
-		if err != nil {
-			return nil, err
+	body, err := e.get(fmt.Sprintf("/block/%s", strings.TrimSpace(string(hash))))
+	if err != nil {
+		return 0, fmt.Errorf("error fetching tip block: %w", err)
+	}
+
+	var block struct {
+		MedianTime int64 `json:"mediantime"`
+	}
+	err = json.Unmarshal(body, &block)
+	if err != nil {
+		return 0, fmt.Errorf("error unmarshaling JSON: %w", err)
+	}
+
+	return block.MedianTime, nil
+}
+
+// FetchFeeEstimates returns the fee rates in sats/vbyte indexed by the number
+// of blocks to confirm within.
+func (e *Esplora) FetchFeeEstimates() (map[string]float64, error) {
+	body, err := e.get("/fee-estimates")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching fee estimates: %w", err)
+	}
+
+	var estimates map[string]float64
+	err = json.Unmarshal(body, &estimates)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling JSON: %w", err)
+	}
+
+	return estimates, nil
+}
+
+func (e *Esplora) get(path string) ([]byte, error) {
+	return e.do(http.MethodGet, path, "")
+}
+
+// do sends the request to the API and returns the body of the 2xx response,
+// retrying with exponential backoff the temporary failures.
+func (e *Esplora) do(method, path, body string) ([]byte, error) {
+	backoff := e.Backoff
+	if backoff <= 0 {
+		backoff = esploraBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		respBody, err := e.doOnce(method, path, body)
+		var esploraErr *EsploraError
+		if err == nil || attempt == esploraMaxRetries || (errors.As(err, &esploraErr) && !esploraErr.Temporary()) {
+			return respBody, err
 		}
-		unspent.Prevout = prevout
+		time.Sleep(backoff)
+		backoff *= 2
 	}
+}
 
-	return utxos, nil
+func (e *Esplora) doOnce(method, path, body string) ([]byte, error) {
+	req, err := http.NewRequest(method, e.BaseAPIURL+path, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "text/plain")
+	}
+
+	resp, err := esploraClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &EsploraError{
+			StatusCode: resp.StatusCode,
+			URL:        req.URL.String(),
+			Body:       strings.TrimSpace(string(respBody)),
+		}
+	}
+	return respBody, nil
+}
+
+// txCache keeps the most recently used raw transactions by txid. A nil cache
+// keeps nothing.
+type txCache struct {
+	lock    sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type txCacheEntry struct {
+	txid  string
+	txHex string
+}
+
+func newTxCache(size int) *txCache {
+	return &txCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *txCache) get(txid string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, ok := c.entries[txid]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*txCacheEntry).txHex, true
+}
+
+func (c *txCache) add(txid, txHex string) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if element, ok := c.entries[txid]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[txid] = c.order.PushFront(&txCacheEntry{txid: txid, txHex: txHex})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*txCacheEntry).txid)
+	}
 }
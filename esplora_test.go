@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestEsplora(t *testing.T, handler http.HandlerFunc) *Esplora {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	esplora, err := NewEsplora("testnet", server.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	esplora.Backoff = time.Millisecond
+	return esplora
+}
+
+func TestNewEsplora(t *testing.T) {
+	esplora, err := NewEsplora("testnet", "")
+	assert.NoError(t, err)
+	assert.Equal(t, EsploraAPIURLs["testnet"], esplora.BaseAPIURL)
+
+	esplora, err = NewEsplora("regtest", "http://esplora:3000/")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://esplora:3000", esplora.BaseAPIURL)
+
+	_, err = NewEsplora("signet", "")
+	assert.Error(t, err)
+}
+
+func TestEsplora_Retries(t *testing.T) {
+	var requests int32
+	esplora := newTestEsplora(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blocks/tip/height":
+			// rate limited, then a server error and finally the height
+			switch atomic.AddInt32(&requests, 1) {
+			case 1:
+				w.WriteHeader(http.StatusTooManyRequests)
+			case 2:
+				w.WriteHeader(http.StatusBadGateway)
+			default:
+				w.Write([]byte("1234\n"))
+			}
+		case "/tx":
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != "00" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte("sendrawtransaction RPC error: {\"code\":-22,\"message\":\"TX decode failed\"}"))
+				return
+			}
+			w.Write([]byte("txid"))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	height, err := esplora.FetchTipHeight()
+	assert.NoError(t, err)
+	assert.Equal(t, 1234, height)
+	assert.Equal(t, int32(3), requests)
+
+	// client errors are returned right away
+	txid, err := esplora.BroadcastTransaction("00")
+	assert.NoError(t, err)
+	assert.Equal(t, "txid", txid)
+	_, err = esplora.BroadcastTransaction("zz")
+	var esploraErr *EsploraError
+	if assert.True(t, errors.As(err, &esploraErr)) {
+		assert.Equal(t, http.StatusBadRequest, esploraErr.StatusCode)
+		assert.False(t, esploraErr.Temporary())
+		assert.Contains(t, esploraErr.Body, "TX decode failed")
+	}
+
+	// the server errors are returned once out of retries, not unmarshaled
+	_, err = esplora.FetchFeeEstimates()
+	if assert.True(t, errors.As(err, &esploraErr)) {
+		assert.Equal(t, http.StatusServiceUnavailable, esploraErr.StatusCode)
+		assert.True(t, esploraErr.Temporary())
+	}
+}
+
+func TestEsplora_FetchPrevout(t *testing.T) {
+	fixture := newChainFixture(t)
+	var requests int32
+	esplora := newTestEsplora(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/tx/"+fixture.txid+"/hex" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(fixture.txHex))
+	})
+
+	// the raw transaction is fetched once
+	for i := 0; i < 3; i++ {
+		prevout, err := esplora.FetchPrevout(fixture.txid, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, dummyFundingUnspent(t, fixture.order).Prevout.Script, prevout.Script)
+	}
+	assert.Equal(t, int32(1), requests)
+
+	_, err := esplora.FetchPrevout(fixture.txid, 1)
+	assert.Error(t, err)
+	_, err = esplora.FetchPrevout("unknown", 0)
+	assert.Error(t, err)
+	assert.Equal(t, int32(2), requests)
+}
+
+func TestTxCache(t *testing.T) {
+	cache := newTxCache(2)
+	cache.add("a", "aa")
+	cache.add("b", "bb")
+	// a is the most recently used, b is evicted
+	_, ok := cache.get("a")
+	assert.True(t, ok)
+	cache.add("c", "cc")
+
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+	txHex, ok := cache.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "aa", txHex)
+	txHex, ok = cache.get("c")
+	assert.True(t, ok)
+	assert.Equal(t, "cc", txHex)
+
+	var nilCache *txCache
+	nilCache.add("a", "aa")
+	_, ok = nilCache.get("a")
+	assert.False(t, ok)
+}
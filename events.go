@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vulpemventures/go-elements/address"
+	"github.com/vulpemventures/go-elements/transaction"
+)
+
+// orderEventsInterval is how often the order events streams look for status
+// changes.
+const orderEventsInterval = 2 * time.Second
+
+// OrderEvent is either a change of the status of an order or a transaction
+// funding or spending its contract.
+type OrderEvent struct {
+	StatusChange *StatusChange
+	Transaction  *TransactionNotification
+}
+
+// WatchOrderEvents streams the events of the order, starting from its current
+// status, until the context is done or a final status is sent. Statuses are
+// read from the timeline every interval, transactions are the notifications
+// of the hub involving the order contract.
+func WatchOrderEvents(ctx context.Context, repo OrderRepository, hub *NotificationHub, order *Order, interval time.Duration) (<-chan *OrderEvent, error) {
+	script, err := address.ToOutputScript(order.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid order address: %w", err)
+	}
+	sub := hub.Subscribe(func(tx *transaction.Transaction) bool {
+		return involvesOrder(order, script, tx)
+	})
+	notifications := sub.C
+
+	events := make(chan *OrderEvent)
+	send := func(event *OrderEvent) bool {
+		select {
+		case events <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(events)
+		defer sub.Unsubscribe()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		sent := -1
+		for {
+			statuses, err := repo.FetchOrderStatuses(order.ID)
+			if err != nil {
+				log.Printf("error fetching statuses of order %s: %v", order.ID, err)
+			}
+			// the history before the current status is left to the caller
+			if sent < 0 && len(statuses) > 0 {
+				sent = len(statuses) - 1
+			}
+			for ; sent >= 0 && sent < len(statuses); sent++ {
+				change := statuses[sent]
+				if !send(&OrderEvent{StatusChange: change}) {
+					return
+				}
+				if isFinalStatus(change) {
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case notification, ok := <-notifications:
+				if !ok {
+					// keep following the statuses without the notifications
+					notifications = nil
+					continue
+				}
+				if !send(&OrderEvent{Transaction: notification}) {
+					return
+				}
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// involvesOrder tells whether the transaction funds the contract of the order
+// or spends it via one of its leaves, revealed in the witness of the input.
+// Key-path cancels are told by the status change that follows them.
+func involvesOrder(order *Order, script []byte, tx *transaction.Transaction) bool {
+	if PaysToScripts(script)(tx) {
+		return true
+	}
+
+	leaves := [][]byte{order.FulfillScript, order.RefundScript, order.PartialFillScript, order.ExpiryScript, order.BatchFulfillScript}
+	for _, input := range tx.Inputs {
+		// script-path witness: [...stack, leaf script, control block]
+		if len(input.Witness) < 2 {
+			continue
+		}
+		revealed := input.Witness[len(input.Witness)-2]
+		for _, leaf := range leaves {
+			if len(leaf) > 0 && bytes.Equal(revealed, leaf) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isFinalStatus tells whether no other status follows the given one: the
+// order has been fulfilled, cancelled or its expired deposits refunded, if
+// any.
+func isFinalStatus(change *StatusChange) bool {
+	switch change.Status {
+	case "Fulfilled", "Cancelled", "ExpiredEmpty":
+		return true
+	case "Expired":
+		return change.TxHash != ""
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vulpemventures/go-elements/network"
+	"github.com/vulpemventures/go-elements/transaction"
+)
+
+// notificationsWallet streams the transaction notifications sent to its
+// channel.
+type notificationsWallet struct {
+	WalletService
+	notifications chan *TransactionNotification
+}
+
+func (w *notificationsWallet) TransactionNotifications(ctx context.Context) (<-chan *TransactionNotification, error) {
+	return w.notifications, nil
+}
+
+func fundingTxHex(t *testing.T, order *Order) string {
+	funding := dummyFundingUnspent(t, order)
+	tx := transaction.NewTx(2)
+	tx.AddOutput(funding.Prevout)
+	txHex, err := tx.ToHex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return txHex
+}
+
+func TestInvolvesOrder(t *testing.T) {
+	order := newTestOrder(t, testOrder{})
+	funding := dummyFundingUnspent(t, order)
+	script := funding.Prevout.Script
+
+	// the refund spends the contract revealing the refund leaf
+	refund := transaction.NewTx(2)
+	input := transaction.NewTxInput(make([]byte, 32), 0)
+	input.Witness = [][]byte{order.RefundScript, {0xc4}}
+	refund.AddInput(input)
+	refund.AddOutput(transaction.NewTxOutput(funding.Prevout.Asset, funding.Prevout.Value, traderScriptExpected))
+
+	// unrelated transaction
+	other := transaction.NewTx(2)
+	other.AddOutput(transaction.NewTxOutput(funding.Prevout.Asset, funding.Prevout.Value, traderScriptExpected))
+
+	fundingTx, _ := transaction.NewTxFromHex(fundingTxHex(t, order))
+	assert.True(t, involvesOrder(order, script, fundingTx))
+	assert.True(t, involvesOrder(order, script, refund))
+	assert.False(t, involvesOrder(order, script, other))
+}
+
+func TestWatchOrderEvents(t *testing.T) {
+	repo := NewInMemoryOrderRepository()
+	order := newTestOrder(t, testOrder{})
+	assert.NoError(t, repo.SaveOrder(order))
+	assert.NoError(t, repo.UpdateOrderStatus(order.ID, "Cancelled"))
+	assert.NoError(t, repo.UpdateOrderStatus(order.ID, "Pending"))
+
+	wallet := &notificationsWallet{notifications: make(chan *TransactionNotification)}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	hub := NewNotificationHub(wallet)
+	go hub.Run(ctx)
+	events, err := WatchOrderEvents(ctx, repo, hub, order, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal("WatchOrderEvents", err)
+	}
+
+	// the current status only, not the history
+	event := <-events
+	assert.Equal(t, "Pending", event.StatusChange.Status)
+
+	// only the transactions of the order are sent
+	wallet.notifications <- &TransactionNotification{TxId: "other"}
+	wallet.notifications <- &TransactionNotification{TxId: "funding", TxHex: fundingTxHex(t, order)}
+	event = <-events
+	if assert.NotNil(t, event.Transaction) {
+		assert.Equal(t, "funding", event.Transaction.TxId)
+	}
+
+	assert.NoError(t, repo.UpdateOrderStatusWithTxHash(order.ID, "Funded", "funding"))
+	assert.NoError(t, repo.UpdateOrderStatusWithTxHash(order.ID, "Fulfilled", "fulfill"))
+	event = <-events
+	assert.Equal(t, "Funded", event.StatusChange.Status)
+	event = <-events
+	assert.Equal(t, "Fulfilled", event.StatusChange.Status)
+
+	// the stream ends with the final status
+	_, ok := <-events
+	assert.False(t, ok)
+}
+
+func TestOfferStatusFragment(t *testing.T) {
+	order := newTestOrder(t, testOrder{expiry: time.Hour})
+	statuses := []*StatusChange{
+		{Status: "Pending", Timestamp: order.Timestamp},
+		{Status: "Underfunded", Timestamp: order.Timestamp, TxHash: "b7e6664c79fc4229504fc0521c661d431e0be2cb25be230bbaf6d8112fc89efe"},
+	}
+
+	html, err := renderFragment("web", "status.html", offerStatusData(order, "Underfunded", statuses, network.Testnet.Name))
+	assert.NoError(t, err)
+	assert.NotContains(t, html, "\n")
+	assert.Contains(t, html, "Status: <strong>Underfunded</strong>")
+	assert.Contains(t, html, "The deposit is lower than")
+	assert.Contains(t, html, "It is refunded to you once the contract timelock matures.")
+	assert.NotContains(t, html, "key-path")
+	assert.Contains(t, html, "b7e666...c89efe")
+
+	// the maker can recover it before the expiry
+	order.MakerPubKey = order.TraderScript[2:]
+	html, err = renderFragment("web", "status.html", offerStatusData(order, "Underfunded", statuses, network.Testnet.Name))
+	assert.NoError(t, err)
+	assert.Contains(t, html, "Recover it by signing the key-path cancel transaction")
+}
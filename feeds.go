@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// how often the REST price feeds are polled
+	feedPollInterval = 5 * time.Second
+	feedTimeout      = 10 * time.Second
+
+	bitfinexTickerURL = "https://api-pub.bitfinex.com/v2/ticker/tBTCUST"
+	binanceDepthURL   = "https://api.binance.com/api/v3/depth?symbol=BTCUSDT&limit=100"
+)
+
+// feedClient is the HTTP client shared by the price feeds.
+var feedClient = &http.Client{Timeout: feedTimeout}
+
+// PollingRatesClient is a price feed that polls the prices of the markets
+// every interval, e.g. from the REST API of an exchange.
+type PollingRatesClient struct {
+	Name     string
+	interval time.Duration
+	fetch    func() ([]Ticker, error)
+	Prices   *PriceStore
+}
+
+// NewPollingRatesClient returns the feed polling the tickers with fetch,
+// stale once older than maxPriceAge.
+func NewPollingRatesClient(name string, interval, maxPriceAge time.Duration, fetch func() ([]Ticker, error)) *PollingRatesClient {
+	return &PollingRatesClient{
+		Name:     name,
+		interval: interval,
+		fetch:    fetch,
+		Prices:   NewPriceStore(name, maxPriceAge),
+	}
+}
+
+// NewBitfinexClient polls the BTC/USDT ticker of Bitfinex at the given URL.
+func NewBitfinexClient(tickerURL string, maxPriceAge time.Duration) *PollingRatesClient {
+	return NewPollingRatesClient("bitfinex", feedPollInterval, maxPriceAge, func() ([]Ticker, error) {
+		// [BID, BID_SIZE, ASK, ASK_SIZE, DAILY_CHANGE, DAILY_CHANGE_RELATIVE,
+		// LAST_PRICE, ...]
+		var ticker []float64
+		err := getJSON(tickerURL, &ticker)
+		if err != nil {
+			return nil, err
+		}
+		if len(ticker) < 7 {
+			return nil, fmt.Errorf("invalid bitfinex ticker %v", ticker)
+		}
+		return []Ticker{{Pair: "L-BTC/USDT", Bid: ticker[0], Ask: ticker[2], Last: ticker[6]}}, nil
+	})
+}
+
+// NewBinanceClient polls the BTC/USDT order book of Binance at the given URL,
+// which has no last price.
+func NewBinanceClient(depthURL string, maxPriceAge time.Duration) *PollingRatesClient {
+	return NewPollingRatesClient("binance", feedPollInterval, maxPriceAge, func() ([]Ticker, error) {
+		// [[PRICE, QUANTITY], ...], best first
+		var book struct {
+			Bids [][2]string `json:"bids"`
+			Asks [][2]string `json:"asks"`
+		}
+		err := getJSON(depthURL, &book)
+		if err != nil {
+			return nil, err
+		}
+		bids, err := binanceLevels(book.Bids)
+		if err != nil {
+			return nil, fmt.Errorf("invalid binance bids: %w", err)
+		}
+		asks, err := binanceLevels(book.Asks)
+		if err != nil {
+			return nil, fmt.Errorf("invalid binance asks: %w", err)
+		}
+		if len(bids) == 0 || len(asks) == 0 {
+			return nil, fmt.Errorf("empty binance order book")
+		}
+		return []Ticker{{Pair: "L-BTC/USDT", Bid: bids[0].Price, Ask: asks[0].Price, Bids: bids, Asks: asks}}, nil
+	})
+}
+
+func binanceLevels(entries [][2]string) ([]PriceLevel, error) {
+	levels := make([]PriceLevel, 0, len(entries))
+	for _, entry := range entries {
+		price, err := strconv.ParseFloat(entry[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price %q: %w", entry[0], err)
+		}
+		volume, err := strconv.ParseFloat(entry[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity %q: %w", entry[1], err)
+		}
+		levels = append(levels, PriceLevel{Price: price, Volume: volume})
+	}
+	return levels, nil
+}
+
+// NewFeedClient polls the prices by market pair, e.g. {"L-BTC/USDT": 30000},
+// from a JSON file or http(s) URL, a stand-in for the exchanges. The price is
+// the bid, ask and last one alike.
+func NewFeedClient(source string, maxPriceAge time.Duration) *PollingRatesClient {
+	return NewPollingRatesClient("feed", feedPollInterval, maxPriceAge, func() ([]Ticker, error) {
+		prices := make(map[string]float64)
+		if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+			if err := getJSON(source, &prices); err != nil {
+				return nil, err
+			}
+		} else {
+			data, err := os.ReadFile(strings.TrimPrefix(source, "file://"))
+			if err != nil {
+				return nil, fmt.Errorf("error reading price feed: %w", err)
+			}
+			err = json.Unmarshal(data, &prices)
+			if err != nil {
+				return nil, fmt.Errorf("invalid price feed %s: %w", source, err)
+			}
+		}
+
+		tickers := make([]Ticker, 0, len(prices))
+		for pair, price := range prices {
+			tickers = append(tickers, Ticker{Pair: pair, Bid: price, Ask: price, Last: price})
+		}
+		return tickers, nil
+	})
+}
+
+// Subscribe fetches the prices right away and then every interval. Failures
+// are logged and leave the last prices in place until they are stale.
+func (c *PollingRatesClient) Subscribe() error {
+	c.poll()
+	go func() {
+		for range time.Tick(c.interval) {
+			c.poll()
+		}
+	}()
+	return nil
+}
+
+func (c *PollingRatesClient) poll() {
+	tickers, err := c.fetch()
+	if err != nil {
+		log.Printf("error fetching %s prices: %v", c.Name, err)
+		return
+	}
+
+	for _, ticker := range tickers {
+		if ticker.Ask > 0 {
+			c.Prices.Update(ticker)
+		}
+	}
+}
+
+func (c *PollingRatesClient) MarketPrice(base, quote string) (float64, error) {
+	return c.Prices.Price(base, quote)
+}
+
+func (c *PollingRatesClient) MarketTicker(base, quote string) (Ticker, error) {
+	return c.Prices.MarketTicker(base, quote)
+}
+
+// StaticRatesClient quotes every market at a fixed price.
+type StaticRatesClient struct {
+	Prices map[string]float64
+}
+
+// NewStaticRatesClient parses the prices by market pair from a comma
+// separated list, e.g. L-BTC/USDT=30000,L-BTC/EUR=28000.
+func NewStaticRatesClient(prices string) (*StaticRatesClient, error) {
+	client := &StaticRatesClient{Prices: make(map[string]float64)}
+	for _, entry := range strings.Split(prices, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pair, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid static price %q, must be PAIR=PRICE", entry)
+		}
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil || price <= 0 {
+			return nil, fmt.Errorf("invalid static price of %s: %s", pair, value)
+		}
+		client.Prices[pair] = price
+	}
+	return client, nil
+}
+
+func (c *StaticRatesClient) Subscribe() error {
+	return nil
+}
+
+func (c *StaticRatesClient) MarketPrice(base, quote string) (float64, error) {
+	if base == quote {
+		return 1, nil
+	}
+	price, ok := c.Prices[base+"/"+quote]
+	if !ok {
+		return 0, fmt.Errorf("no static price for market pair: %s/%s", base, quote)
+	}
+	return price, nil
+}
+
+// MarketTicker returns the static price as both the bid and the ask.
+func (c *StaticRatesClient) MarketTicker(base, quote string) (Ticker, error) {
+	price, err := c.MarketPrice(base, quote)
+	if err != nil {
+		return Ticker{}, err
+	}
+	return Ticker{Pair: base + "/" + quote, Bid: price, Ask: price, Last: price, Timestamp: time.Now()}, nil
+}
+
+// NewRatesClient returns the aggregate of the price feeds with the given
+// names: kraken, bitfinex, binance, static, with the comma separated static
+// prices, and feed, polling the given file or URL. The prices of the feeds
+// are stale once older than maxPriceAge, except the static ones.
+func NewRatesClient(feeds []string, staticPrices, feedURL string, maxDeviation float64, maxPriceAge time.Duration) (*AggregateRatesClient, error) {
+	sources := []RatesClient{}
+	for _, name := range feeds {
+		switch strings.TrimSpace(name) {
+		case "kraken":
+			sources = append(sources, NewKrakenClient(maxPriceAge))
+		case "bitfinex":
+			sources = append(sources, NewBitfinexClient(bitfinexTickerURL, maxPriceAge))
+		case "binance":
+			sources = append(sources, NewBinanceClient(binanceDepthURL, maxPriceAge))
+		case "static":
+			static, err := NewStaticRatesClient(staticPrices)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, static)
+		case "feed":
+			if feedURL == "" {
+				return nil, fmt.Errorf("missing price feed URL")
+			}
+			sources = append(sources, NewFeedClient(feedURL, maxPriceAge))
+		case "":
+		default:
+			return nil, fmt.Errorf("invalid price feed %s", name)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no price feed configured")
+	}
+	return NewAggregateRatesClient(maxDeviation, sources...), nil
+}
+
+func getJSON(url string, v interface{}) error {
+	resp, err := feedClient.Get(url)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling JSON: %w", err)
+	}
+	return nil
+}
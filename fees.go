@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/vulpemventures/go-elements/address"
+	"github.com/vulpemventures/go-elements/psetv2"
+)
+
+const (
+	// MIN_FEE_RATE is the minimum relay fee rate of Liquid, in sats/vbyte
+	MIN_FEE_RATE = 0.1
+	// maxFeeIterations bounds the rounds of fee unspents selection
+	maxFeeIterations = 5
+
+	// sizes of the witness items of a P2WPKH and of a taproot key-path input
+	ecdsaSignatureSize   = 72
+	compressedPubKeySize = 33
+	schnorrSignatureSize = 64
+
+	// virtual size of a change output and of the input spending it later on,
+	// with and without the commitments and proofs of a blinded output
+	explicitChangeVsize     = 66 + 68
+	confidentialChangeVsize = 122 + (4174+131)/4 + 68
+)
+
+// FeeEstimator returns the fee rate to pay for the transactions built by
+// Banco, either a fixed one or the one estimated by the chain source for the
+// given confirmation target.
+type FeeEstimator struct {
+	chain              ChainSource
+	fixedRate          float64
+	confirmationTarget int
+}
+
+func NewFeeEstimator(chain ChainSource, fixedRate float64, confirmationTarget int) *FeeEstimator {
+	return &FeeEstimator{
+		chain:              chain,
+		fixedRate:          fixedRate,
+		confirmationTarget: confirmationTarget,
+	}
+}
+
+// FeeRate returns the fee rate in sats/vbyte, never below the minimum relay
+// one. If the chain source can't be reached the minimum is returned.
+func (f *FeeEstimator) FeeRate() float64 {
+	if f.fixedRate > 0 {
+		return math.Max(f.fixedRate, MIN_FEE_RATE)
+	}
+
+	estimates, err := f.chain.FetchFeeEstimates()
+	if err != nil {
+		log.Printf("error fetching fee estimates, using the minimum fee rate: %v\n", err)
+		return MIN_FEE_RATE
+	}
+	// pick the estimate of the closest target not below the requested one
+	rate, target := 0.0, math.MaxInt
+	for key, estimate := range estimates {
+		blocks, err := strconv.Atoi(key)
+		if err != nil || blocks < f.confirmationTarget {
+			continue
+		}
+		if blocks < target {
+			rate, target = estimate, blocks
+		}
+	}
+
+	return math.Max(rate, MIN_FEE_RATE)
+}
+
+// feeHeadroom returns the fee to select unspents for, enough to pay a
+// transaction spending the given contract inputs with a couple of blinded
+// outputs each, besides the change and fee ones.
+func feeHeadroom(numOfContractInputs int, feeRate float64) uint64 {
+	vsize := (4 + 2*numOfContractInputs) * confidentialChangeVsize
+	return max(FEE_AMOUNT, feeForVsize(vsize, feeRate))
+}
+
+// feeForVsize returns the fee of a transaction of the given virtual size.
+func feeForVsize(vsize int, feeRate float64) uint64 {
+	return uint64(math.Ceil(float64(vsize) * feeRate))
+}
+
+// estimateVsize returns the virtual size of the given pset once finalized.
+// The first numOfContractInputs inputs spend the contract via their tapscript
+// leaf, the others are Ocean's ones spent via P2WPKH or taproot key-path.
+// The outputs must be already blinded, if needed, to account for their proofs.
+func estimateVsize(ptx *psetv2.Pset, numOfContractInputs int) (int, error) {
+	tx, err := ptx.UnsignedTx()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get unsigned tx: %w", err)
+	}
+
+	for i, in := range ptx.Inputs {
+		switch {
+		case i < numOfContractInputs && len(in.TapLeafScript) > 0:
+			leaf := in.TapLeafScript[0]
+			controlBlock, err := leaf.ControlBlock.ToBytes()
+			if err != nil {
+				return 0, fmt.Errorf("failed to serialize control block: %w", err)
+			}
+			tx.Inputs[i].Witness = [][]byte{leaf.Script, controlBlock}
+		case in.WitnessUtxo != nil && len(in.WitnessUtxo.Script) > 0 && address.GetScriptType(in.WitnessUtxo.Script) == address.P2TRScript:
+			tx.Inputs[i].Witness = [][]byte{make([]byte, schnorrSignatureSize)}
+		default:
+			tx.Inputs[i].Witness = [][]byte{
+				make([]byte, ecdsaSignatureSize),
+				make([]byte, compressedPubKeySize),
+			}
+		}
+	}
+
+	return tx.VirtualSize(), nil
+}
+
+// dustAmount returns the amount below which a change output costs more than
+// it's worth, that is the fees to add and later spend it.
+func dustAmount(feeRate float64, confidential bool) uint64 {
+	if feeRate <= 0 {
+		return FEE_AMOUNT
+	}
+	if confidential {
+		return feeForVsize(confidentialChangeVsize, feeRate)
+	}
+	return feeForVsize(explicitChangeVsize, feeRate)
+}
+
+// prepareWithFees selects Ocean's L-BTC unspents to pay the network fees of
+// the transaction built by prepare, which must pay the fee pointed by fee.
+// The fee is adjusted to the estimated size of the transaction at the given
+// fee rate. The unspents are selected once, with some headroom, since Ocean
+// keeps them locked until they expire. With no fee rate the fixed FEE_AMOUNT
+// is paid.
+func prepareWithFees(
+	walletSvc WalletService,
+	feeRate float64,
+	numOfContractInputs int,
+	fee *uint64,
+	prepare func(utxosForFees []UTXO, changeAmountForFees uint64) (*psetv2.Pset, error),
+) (*psetv2.Pset, error) {
+	*fee = FEE_AMOUNT
+	target := *fee
+	if feeRate > 0 {
+		target = feeHeadroom(numOfContractInputs, feeRate)
+	}
+	utxosForFees, change, err := walletSvc.SelectUtxos(context.Background(), currencyToAsset["L-BTC"].AssetHash, target)
+	if err != nil {
+		return nil, fmt.Errorf("error in SelectUtxos for fees: %w", err)
+	}
+	selectedAmount := target + change
+
+	lowered := false
+	for i := 0; i < maxFeeIterations; i++ {
+		if selectedAmount < *fee {
+			return nil, fmt.Errorf("fee of %d sats exceeds the %d sats selected for fees", *fee, selectedAmount)
+		}
+
+		ptx, err := prepare(utxosForFees, selectedAmount-*fee)
+		if err != nil {
+			return nil, err
+		}
+		if feeRate <= 0 {
+			return ptx, nil
+		}
+
+		vsize, err := estimateVsize(ptx, numOfContractInputs)
+		if err != nil {
+			return nil, err
+		}
+		// lower the fee only once, a bigger change could make it grow again
+		estimatedFee := feeForVsize(vsize, feeRate)
+		switch {
+		case estimatedFee > *fee:
+			*fee = estimatedFee
+		case estimatedFee < *fee && !lowered:
+			*fee = estimatedFee
+			lowered = true
+		default:
+			return ptx, nil
+		}
+	}
+
+	return nil, fmt.Errorf("fees not settled after %d attempts", maxFeeIterations)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vulpemventures/go-elements/elementsutil"
+	"github.com/vulpemventures/go-elements/psetv2"
+)
+
+// feeUnspentsWallet selects fee unspents of the given value, enough to cover
+// any requested amount up to their sum, and counts the selections.
+type feeUnspentsWallet struct {
+	WalletService
+	unspents   []UTXO
+	selections int
+}
+
+func (w *feeUnspentsWallet) SelectUtxos(ctx context.Context, asset string, amount uint64) ([]UTXO, uint64, error) {
+	w.selections++
+	selected := make([]UTXO, 0)
+	total := uint64(0)
+	for _, unspent := range w.unspents {
+		selected = append(selected, unspent)
+		total += unspent.Value
+		if total >= amount {
+			break
+		}
+	}
+	return selected, total - amount, nil
+}
+
+func TestTrade_PrepareWithFees(t *testing.T) {
+	order := newTestOrder(t, testOrder{})
+
+	feeUnspent := *dummyFundingUnspent(t, order)
+	feeUnspent.Index = 1
+	feeUnspent.Value = 100000
+	feeUnspent.Prevout.Value, _ = elementsutil.ValueToBytes(feeUnspent.Value)
+	feeUnspent.Prevout.Script = traderScriptExpected
+	wallet := &feeUnspentsWallet{unspents: []UTXO{feeUnspent}}
+
+	tests := []struct {
+		name    string
+		feeRate float64
+	}{
+		{"fixed fee", 0},
+		{"minimum fee rate", MIN_FEE_RATE},
+		{"high fee rate", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trade, err := FromFundedOrder(wallet, order, dummyFundingUnspent(t, order))
+			if err != nil {
+				t.Fatal("FromFundedOrder", err)
+			}
+			trade.FeeRate = tt.feeRate
+			wallet.selections = 0
+
+			ptx, err := trade.prepareWithFees(func(utxosForFees []UTXO, changeAmountForFees uint64) (*psetv2.Pset, error) {
+				return trade.PrepareCancelTransaction(&utxosForFees, traderScriptExpected, changeAmountForFees)
+			})
+			if err != nil {
+				t.Fatal("prepareWithFees", err)
+			}
+			// the fee unspents locked by Ocean are selected only once
+			assert.Equal(t, 1, wallet.selections)
+
+			// refund, fee change and fee
+			assert.Len(t, ptx.Outputs, 3)
+			fee := ptx.Outputs[2].Value
+			assert.Equal(t, trade.Fee, fee)
+			assert.Equal(t, feeUnspent.Value-fee, ptx.Outputs[1].Value)
+			if tt.feeRate == 0 {
+				assert.Equal(t, uint64(FEE_AMOUNT), fee)
+				return
+			}
+
+			vsize, err := estimateVsize(ptx, 1)
+			if err != nil {
+				t.Fatal("estimateVsize", err)
+			}
+			assert.Equal(t, feeForVsize(vsize, tt.feeRate), fee)
+		})
+	}
+}
+
+func TestDustAmount(t *testing.T) {
+	assert.Equal(t, uint64(FEE_AMOUNT), dustAmount(0, false))
+	assert.Less(t, dustAmount(MIN_FEE_RATE, false), dustAmount(MIN_FEE_RATE, true))
+	assert.Less(t, dustAmount(MIN_FEE_RATE, false), dustAmount(1, false))
+}
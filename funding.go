@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/vulpemventures/go-elements/address"
+	"github.com/vulpemventures/go-elements/confidential"
+	"github.com/vulpemventures/go-elements/elementsutil"
+)
+
+// FundingReconciliation sorts the unspents sent to a trade contract by how
+// they fund the order:
+//
+//   - Exact deposits are fulfilled as they are.
+//   - Over deposits are fulfilled returning the excess to the trader.
+//   - Under deposits are filled as they are by partial fill orders. The
+//     fixed-index fulfill leaf of confidential orders pays many of them with
+//     a single output, hence they wait for a top-up and are fulfilled together
+//     once they add up to the order amount. The batch fulfill leaf of explicit
+//     orders pays each contract input on its own and they are never traded.
+//   - WrongAsset deposits can not satisfy neither the fulfill nor the refund
+//     leaves and are never fulfilled.
+//   - Mismatched deposits are blinded to a key other than the contract one or
+//     their asset, value or script can not be verified against the contract.
+//     As for the wrong asset ones, they are never fulfilled.
+//
+// Anyone can send to the contract address, hence the deposits that can't be
+// traded are only counted on the order and never change its status, or they
+// would keep the valid ones from being fulfilled.
+//
+// Exact and over-funded deposits can be refunded through the cancel leaf.
+// Under-funded ones are refunded through the expiry leaf once the timelock
+// matures. Before that, and for the deposits that can't be traded, the maker
+// signature via key-path is the only way back, if the order has a maker key.
+type FundingReconciliation struct {
+	Order      *Order
+	Exact      []*UTXO
+	Under      []*UTXO
+	Over       []*UTXO
+	WrongAsset []*UTXO
+	Mismatched []*UTXO
+}
+
+func ReconcileFunding(order *Order, unspents []*UTXO) *FundingReconciliation {
+	r := &FundingReconciliation{Order: order}
+	for _, unspent := range unspents {
+		if order.IsConfidential() {
+			unblindUnspent(order, unspent)
+		}
+		switch {
+		case verifyUnspent(order, unspent) != nil:
+			r.Mismatched = append(r.Mismatched, unspent)
+		case unspentAsset(unspent) != order.Input.Asset:
+			r.WrongAsset = append(r.WrongAsset, unspent)
+		case unspent.Value == order.Input.Amount:
+			r.Exact = append(r.Exact, unspent)
+		case unspent.Value > order.Input.Amount:
+			r.Over = append(r.Over, unspent)
+		default:
+			r.Under = append(r.Under, unspent)
+		}
+	}
+	return r
+}
+
+// Fulfillments returns the groups of unspents to spend, each one in its own
+// fulfill transaction. Partial fill orders fill every unspent on its own,
+// whatever its value.
+func (r *FundingReconciliation) Fulfillments() [][]*UTXO {
+	groups := make([][]*UTXO, 0)
+	if r.Order.IsPartial() {
+		for _, unspents := range [][]*UTXO{r.Exact, r.Over, r.Under} {
+			for _, unspent := range unspents {
+				groups = append(groups, []*UTXO{unspent})
+			}
+		}
+		return groups
+	}
+	for _, unspent := range r.Exact {
+		groups = append(groups, []*UTXO{unspent})
+	}
+	for _, unspent := range r.Over {
+		groups = append(groups, []*UTXO{unspent})
+	}
+	if r.combinesUnder() {
+		groups = append(groups, r.Under)
+	}
+	return groups
+}
+
+// combinesUnder tells whether the under-funded deposits add up to the order
+// amount and can be fulfilled together.
+func (r *FundingReconciliation) combinesUnder() bool {
+	return r.Order.CombinesDeposits() && r.UnderAmount() >= r.Order.Input.Amount
+}
+
+// Refundable returns the deposits a refund leaf can return: the exact and
+// over-funded ones and, if the leaf returns the whole value of any deposit, the
+// under-funded ones.
+func (r *FundingReconciliation) Refundable(wholeValue bool) []*UTXO {
+	refundable := make([]*UTXO, 0, len(r.Exact)+len(r.Over)+len(r.Under))
+	refundable = append(refundable, r.Exact...)
+	refundable = append(refundable, r.Over...)
+	if wholeValue {
+		refundable = append(refundable, r.Under...)
+	}
+	return refundable
+}
+
+// UnderAmount returns the total value of the under-funded deposits.
+func (r *FundingReconciliation) UnderAmount() uint64 {
+	total := uint64(0)
+	for _, unspent := range r.Under {
+		total += unspent.Value
+	}
+	return total
+}
+
+// Status returns the order status resulting from the reconciliation, before
+// any fulfillment happens. Deposits that can't be traded leave it Pending.
+func (r *FundingReconciliation) Status() string {
+	fundings := len(r.Exact) + len(r.Over) + len(r.Under)
+	switch {
+	case r.Order.IsPartial() && r.Order.FilledAmount > 0 && fundings > 0:
+		return "PartiallyFilled"
+	case len(r.Over) > 0 || (r.combinesUnder() && r.UnderAmount() > r.Order.Input.Amount):
+		return "Overfunded"
+	case len(r.Exact) > 0 || (r.combinesUnder() && r.UnderAmount() == r.Order.Input.Amount):
+		return "Funded"
+	case len(r.Under) > 0:
+		return "Underfunded"
+	default:
+		return "Pending"
+	}
+}
+
+// FundingTxID returns the txid of the deposit the status refers to, the first
+// one that can be traded if any. Empty if there are no deposits.
+func (r *FundingReconciliation) FundingTxID() string {
+	for _, unspents := range [][]*UTXO{r.Exact, r.Over, r.Under, r.WrongAsset, r.Mismatched} {
+		if len(unspents) > 0 {
+			return unspents[0].Txid
+		}
+	}
+	return ""
+}
+
+// NumOfMismatched returns the number of deposits that can't be traded.
+func (r *FundingReconciliation) NumOfMismatched() int {
+	return len(r.WrongAsset) + len(r.Mismatched)
+}
+
+// verifyUnspent checks that the given unspent is an output of the contract
+// and that the asset and value reported by the chain source match the ones of
+// the prevout. Blinded outputs must have been unblinded with the contract
+// blinding key beforehand.
+func verifyUnspent(order *Order, unspent *UTXO) error {
+	if unspent.Prevout == nil {
+		return fmt.Errorf("missing prevout")
+	}
+	if unspent.Prevout.IsConfidential() {
+		if len(unspent.AssetBlinder) == 0 || len(unspent.ValueBlinder) == 0 {
+			return fmt.Errorf("blinded output can not be unblinded with the contract key")
+		}
+	} else if len(unspent.AssetCommitment) > 0 || len(unspent.ValueCommitment) > 0 {
+		return fmt.Errorf("commitments reported for an explicit output")
+	}
+
+	script, err := address.ToOutputScript(order.Address)
+	if err != nil {
+		return fmt.Errorf("invalid contract address: %w", err)
+	}
+	if !bytes.Equal(unspent.Prevout.Script, script) {
+		return fmt.Errorf("prevout script does not match the contract")
+	}
+
+	if len(unspent.Asset) > 0 && unspent.Asset != unspentAsset(unspent) {
+		return fmt.Errorf("asset %s does not match the prevout one", unspent.Asset)
+	}
+	if unspent.Prevout.IsConfidential() {
+		// asset and value come from the unblinded prevout
+		return nil
+	}
+	value, err := elementsutil.ValueFromBytes(unspent.Prevout.Value)
+	if err != nil {
+		return fmt.Errorf("invalid prevout value: %w", err)
+	}
+	if value != unspent.Value {
+		return fmt.Errorf("value %d does not match the prevout one %d", unspent.Value, value)
+	}
+
+	return nil
+}
+
+// unspentAsset returns the asset hash of the given unspent, or an empty string
+// if it is unknown or blinded to a key we don't know.
+func unspentAsset(unspent *UTXO) string {
+	if unspent.Prevout != nil && unspent.Prevout.IsConfidential() && len(unspent.AssetBlinder) > 0 {
+		return unspent.Asset
+	}
+	if unspent.Prevout == nil || len(unspent.Prevout.Asset) != 33 || unspent.Prevout.Asset[0] != 0x01 {
+		return ""
+	}
+	return elementsutil.TxIDFromBytes(unspent.Prevout.Asset[1:])
+}
+
+// unblindUnspent reveals asset, value and blinders of a confidential contract
+// unspent with the contract blinding key. Unspents that can't be unblinded
+// are left untouched and end up among the mismatched ones.
+func unblindUnspent(order *Order, unspent *UTXO) {
+	if unspent.Prevout == nil || !unspent.Prevout.IsConfidential() {
+		return
+	}
+	revealed, err := confidential.UnblindOutputWithKey(unspent.Prevout, order.BlindingKey)
+	if err != nil {
+		return
+	}
+	unspent.Value = revealed.Value
+	unspent.Asset = elementsutil.TxIDFromBytes(revealed.Asset)
+	unspent.AssetBlinder = revealed.AssetBlindingFactor
+	unspent.ValueBlinder = revealed.ValueBlindingFactor
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/vulpemventures/go-elements/elementsutil"
+	"github.com/vulpemventures/go-elements/network"
+)
+
+func TestReconcileFunding(t *testing.T) {
+	order := newTestOrder(t, testOrder{})
+
+	unspentOf := func(index int, value uint64) *UTXO {
+		unspent := dummyFundingUnspent(t, order)
+		unspent.Index = index
+		unspent.Value = value
+		unspent.Prevout.Value, _ = elementsutil.ValueToBytes(value)
+		return unspent
+	}
+	wrongAsset := unspentOf(9, order.Input.Amount)
+	wrongAsset.Prevout.Asset, _ = elementsutil.AssetHashToBytes(currencyToAsset["USDT"].AssetHash)
+
+	// value reported by the chain source differs from the prevout one
+	wrongValue := unspentOf(10, order.Input.Amount)
+	wrongValue.Value = order.Input.Amount + 1000
+	// asset reported by the chain source differs from the prevout one
+	inconsistentAsset := unspentOf(11, order.Input.Amount)
+	inconsistentAsset.Asset = currencyToAsset["USDT"].AssetHash
+	blinded := unspentOf(12, order.Input.Amount)
+	blinded.AssetCommitment = "0a" + hex.EncodeToString(make([]byte, 32))
+	notContract := unspentOf(13, order.Input.Amount)
+	notContract.Prevout.Script = traderScriptExpected
+
+	tests := []struct {
+		name             string
+		unspents         []*UTXO
+		wantStatus       string
+		wantFulfillments int
+	}{
+		{"no deposits", nil, "Pending", 0},
+		{"exact", []*UTXO{unspentOf(0, order.Input.Amount)}, "Funded", 1},
+		{"over", []*UTXO{unspentOf(0, order.Input.Amount+1000)}, "Overfunded", 1},
+		{"under", []*UTXO{unspentOf(0, order.Input.Amount-1000)}, "Underfunded", 0},
+		// the batch fulfill leaf pays each contract input on its own
+		{"under with top-up", []*UTXO{unspentOf(0, order.Input.Amount-1000), unspentOf(1, 1000)}, "Underfunded", 0},
+		{"under with bigger top-up", []*UTXO{unspentOf(0, order.Input.Amount-1000), unspentOf(1, 2000)}, "Underfunded", 0},
+		{"wrong asset", []*UTXO{wrongAsset}, "Pending", 0},
+		{"exact and wrong asset", []*UTXO{unspentOf(0, order.Input.Amount), wrongAsset}, "Funded", 1},
+		{"wrong asset and exact", []*UTXO{wrongAsset, unspentOf(0, order.Input.Amount)}, "Funded", 1},
+		{"value mismatch", []*UTXO{wrongValue}, "Pending", 0},
+		{"asset mismatch", []*UTXO{inconsistentAsset}, "Pending", 0},
+		{"blinded", []*UTXO{blinded}, "Pending", 0},
+		{"not of the contract", []*UTXO{notContract}, "Pending", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			funding := ReconcileFunding(order, tt.unspents)
+			assert.Equal(t, tt.wantStatus, funding.Status())
+			assert.Len(t, funding.Fulfillments(), tt.wantFulfillments)
+		})
+	}
+	// on a pair whose price is not 1
+	usdtOrder := newTestOrder(t, testOrder{quote: usdtBuyQuote})
+	assert.Equal(t, uint64(30*1e8), usdtOrder.Input.Amount)
+	assert.Equal(t, uint64(100000), usdtOrder.Output.Amount)
+	funding := ReconcileFunding(usdtOrder, []*UTXO{dummyFundingUnspent(t, usdtOrder)})
+	assert.Equal(t, "Funded", funding.Status())
+	assert.Len(t, funding.Fulfillments(), 1)
+}
+
+func TestReconcileFunding_SplitDeposit(t *testing.T) {
+	order := newTestOrder(t, testOrder{expiry: time.Hour})
+	first := dummyFundingUnspent(t, order)
+	first.Value = order.Input.Amount / 2
+	first.Prevout.Value, _ = elementsutil.ValueToBytes(first.Value)
+	second := dummyFundingUnspent(t, order)
+	second.Index = 1
+	second.Value = order.Input.Amount - first.Value
+	second.Prevout.Value, _ = elementsutil.ValueToBytes(second.Value)
+
+	// the batch fulfill leaf pays each contract input on its own, hence the
+	// halves of an explicit order are never traded, only refunded at expiry
+	assert.False(t, order.CombinesDeposits())
+	funding := ReconcileFunding(order, []*UTXO{first, second})
+	assert.Equal(t, "Underfunded", funding.Status())
+	assert.Empty(t, funding.Fulfillments())
+	assert.Empty(t, funding.Refundable(order.RefundsWholeValue(order.RefundScript)))
+	assert.Len(t, funding.Refundable(order.RefundsWholeValue(order.ExpiryScript)), 2)
+
+	html, err := renderFragment("web", "status.html", offerStatusData(order, funding.Status(), nil, network.Testnet.Name))
+	assert.NoError(t, err)
+	assert.NotContains(t, html, "Send the missing amount")
+
+	// while the fixed-index fulfill leaf of confidential orders pays them with
+	// a single output
+	makerBlindingKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	confidentialOrder := newTestOrder(t, testOrder{makerBlindingKey: hex.EncodeToString(makerBlindingKey.PubKey().SerializeCompressed())})
+	assert.True(t, confidentialOrder.CombinesDeposits())
+	half := confidentialOrder.Input.Amount / 2
+	funding = ReconcileFunding(confidentialOrder, []*UTXO{
+		confidentialFundingUnspent(t, confidentialOrder, half),
+		confidentialFundingUnspent(t, confidentialOrder, confidentialOrder.Input.Amount-half),
+	})
+	assert.Equal(t, "Funded", funding.Status())
+	if assert.Len(t, funding.Fulfillments(), 1) {
+		assert.Len(t, funding.Fulfillments()[0], 2)
+	}
+}
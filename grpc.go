@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	bancov1 "github.com/tiero/banco/api-spec/protobuf/gen/go/banco/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// tradeServer implements the gRPC TradeService on top of the same
+// dependencies and helpers of the JSON API.
+type tradeServer struct {
+	api *API
+	hub *NotificationHub
+	// cancels the orders in between its fulfillment rounds
+	watcher *Watcher
+	// how often WatchOrder looks for status changes
+	watchInterval time.Duration
+}
+
+func NewTradeServer(api *API, hub *NotificationHub, watcher *Watcher) bancov1.TradeServiceServer {
+	return &tradeServer{
+		api:           api,
+		hub:           hub,
+		watcher:       watcher,
+		watchInterval: orderEventsInterval,
+	}
+}
+
+// serveGRPC serves the TradeService on the given port until the listener
+// fails.
+func serveGRPC(port string, server bancov1.TradeServiceServer) error {
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return fmt.Errorf("listen on port %s: %w", port, err)
+	}
+
+	grpcServer := grpc.NewServer()
+	bancov1.RegisterTradeServiceServer(grpcServer, server)
+	return grpcServer.Serve(listener)
+}
+
+func (s *tradeServer) ListMarkets(ctx context.Context, req *bancov1.ListMarketsRequest) (*bancov1.ListMarketsResponse, error) {
+	markets, err := s.api.markets(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	response := &bancov1.ListMarketsResponse{}
+	for _, mkt := range markets {
+		market := &bancov1.Market{
+			Pair:         mkt.Pair,
+			BaseAsset:    mkt.BaseAsset,
+			QuoteAsset:   mkt.QuoteAsset,
+			BaseAssetId:  mkt.BaseAssetID,
+			QuoteAssetId: mkt.QuoteAssetID,
+			BuyLimit:     mkt.BuyLimit,
+			SellLimit:    mkt.SellLimit,
+			Price:        mkt.Price,
+			// deprecated, kept for the clients that don't know the
+			// per-direction fees yet
+			FeePercentage:     mkt.BuyFeePercentage,
+			Bid:               mkt.Bid,
+			Ask:               mkt.Ask,
+			SpreadPercentage:  mkt.SpreadPercentage,
+			BuyFeePercentage:  mkt.BuyFeePercentage,
+			SellFeePercentage: mkt.SellFeePercentage,
+			FixedFee:          mkt.FixedFee,
+			Halted:            mkt.Halted,
+			HaltReason:        mkt.HaltReason,
+		}
+		for _, tier := range mkt.FeeTiers {
+			market.FeeTiers = append(market.FeeTiers, &bancov1.FeeTier{
+				MinAmount:         tier.MinAmount,
+				BuyFeePercentage:  tier.BuyFeePercentage,
+				SellFeePercentage: tier.SellFeePercentage,
+			})
+		}
+		response.Markets = append(response.Markets, market)
+	}
+	return response, nil
+}
+
+func (s *tradeServer) GetQuote(ctx context.Context, req *bancov1.GetQuoteRequest) (*bancov1.GetQuoteResponse, error) {
+	quote, err := s.quoteTrade(req.GetPair(), req.GetType(), req.GetAmount())
+	if err != nil {
+		return nil, err
+	}
+
+	return &bancov1.GetQuoteResponse{
+		Price:         quote.Price,
+		FeePercentage: quote.FeePercentage,
+		Input:         toProtoAssetValue(currencyValue(quote.InputCurrency, quote.InputValue)),
+		Output:        toProtoAssetValue(currencyValue(quote.OutputCurrency, quote.OutputValue)),
+		Fee:           toProtoAssetValue(currencyValue(quote.FeeCurrency, quote.Fee)),
+	}, nil
+}
+
+func (s *tradeServer) CreateOrder(ctx context.Context, req *bancov1.CreateOrderRequest) (*bancov1.CreateOrderResponse, error) {
+	if req.GetTraderScript() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing trader_script")
+	}
+	// confidential orders need Ocean's blinded addresses to blind our outputs
+	if req.GetMakerBlindingKey() != "" && !s.api.confidential {
+		return nil, status.Error(codes.InvalidArgument, "confidential trades are not enabled")
+	}
+
+	quote, err := s.quoteTrade(req.GetPair(), req.GetType(), req.GetAmount())
+	if err != nil {
+		return nil, err
+	}
+
+	order, httpStatus, err := s.api.newOrder(req.GetPair(), req.GetTraderScript(), req.GetMakerPubkey(), req.GetMakerBlindingKey(), req.GetPartialFill(), quote)
+	if err != nil {
+		if httpStatus == http.StatusBadRequest {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	err = saveAndWatchOrder(ctx, s.api.repo, s.api.walletSvc, order)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	protoOrder, err := toProtoOrder(order, "Pending")
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &bancov1.CreateOrderResponse{Order: protoOrder}, nil
+}
+
+func (s *tradeServer) GetOrder(ctx context.Context, req *bancov1.GetOrderRequest) (*bancov1.GetOrderResponse, error) {
+	order, current, err := s.fetchOrder(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	protoOrder, err := toProtoOrder(order, current)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	statuses, err := s.api.repo.FetchOrderStatuses(order.ID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	response := &bancov1.GetOrderResponse{Order: protoOrder}
+	for _, change := range statuses {
+		response.Statuses = append(response.Statuses, toProtoStatusChange(change))
+	}
+	return response, nil
+}
+
+func (s *tradeServer) CancelOrder(ctx context.Context, req *bancov1.CancelOrderRequest) (*bancov1.CancelOrderResponse, error) {
+	order, current, err := s.fetchOrder(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if !isCancellable(current) {
+		return nil, status.Errorf(codes.FailedPrecondition, "order is %s and cannot be cancelled", current)
+	}
+	if err := order.VerifyOwnership(req.GetSignature()); err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	txid, err := s.watcher.CancelOrder(order)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &bancov1.CancelOrderResponse{Txid: txid}, nil
+}
+
+// WatchOrder sends the current status of the order and then its events,
+// until a final status.
+func (s *tradeServer) WatchOrder(req *bancov1.WatchOrderRequest, stream bancov1.TradeService_WatchOrderServer) error {
+	order, _, err := s.fetchOrder(req.GetId())
+	if err != nil {
+		return err
+	}
+
+	events, err := WatchOrderEvents(stream.Context(), s.api.repo, s.hub, order, s.watchInterval)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	for event := range events {
+		response := &bancov1.WatchOrderResponse{OrderId: order.ID}
+		if event.StatusChange != nil {
+			response.StatusChange = toProtoStatusChange(event.StatusChange)
+		}
+		if event.Transaction != nil {
+			response.Transaction = &bancov1.Transaction{
+				Txid:      event.Transaction.TxId,
+				Confirmed: event.Transaction.Confirmed,
+				Timestamp: event.Transaction.Timestamp,
+			}
+		}
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+	return stream.Context().Err()
+}
+
+// fetchOrder returns the order of the running network along with its current
+// status.
+func (s *tradeServer) fetchOrder(id string) (*Order, string, error) {
+	order, current, err := s.api.repo.FetchOrderByID(id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !order.IsOnNetwork(s.api.net)) {
+		return nil, "", status.Error(codes.NotFound, "order not found")
+	}
+	if err != nil {
+		return nil, "", status.Error(codes.Internal, err.Error())
+	}
+	return order, current, nil
+}
+
+func (s *tradeServer) quoteTrade(pair string, tradeType bancov1.TradeType, amount float64) (*Quote, error) {
+	var typ string
+	switch tradeType {
+	case bancov1.TradeType_TRADE_TYPE_BUY:
+		typ = "Buy"
+	case bancov1.TradeType_TRADE_TYPE_SELL:
+		typ = "Sell"
+	}
+
+	quote, httpStatus, err := s.api.quoteTrade(pair, typ, amount)
+	if err != nil {
+		if httpStatus == http.StatusBadRequest {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return quote, nil
+}
+
+func toProtoOrder(order *Order, current string) (*bancov1.Order, error) {
+	response, err := newOrderResponse(order, current)
+	if err != nil {
+		return nil, err
+	}
+
+	protoOrder := &bancov1.Order{
+		Id:                 response.ID,
+		Network:            response.Network,
+		Address:            response.Address,
+		Status:             response.Status,
+		CreatedAt:          response.CreatedAt.Unix(),
+		Input:              toProtoAssetValue(response.Input),
+		Output:             toProtoAssetValue(response.Output),
+		FilledAmount:       response.FilledAmount,
+		PartialFill:        response.PartialFill,
+		Confidential:       response.Confidential,
+		MismatchedDeposits: int32(response.MismatchedDeposits),
+		ScriptPubkey:       response.ScriptPubKey,
+		TraderScript:       response.TraderScript,
+		MakerPubkey:        response.MakerPubKey,
+	}
+	if response.ExpiresAt != nil {
+		protoOrder.ExpiresAt = response.ExpiresAt.Unix()
+	}
+	for _, leaf := range response.Leaves {
+		protoOrder.Leaves = append(protoOrder.Leaves, &bancov1.Leaf{
+			Name:     leaf.Name,
+			Script:   leaf.Script,
+			LeafHash: leaf.LeafHash,
+		})
+	}
+	return protoOrder, nil
+}
+
+func toProtoAssetValue(value AssetValue) *bancov1.AssetValue {
+	return &bancov1.AssetValue{
+		Currency: value.Currency,
+		AssetId:  value.AssetID,
+		Amount:   value.Amount,
+		Value:    value.Value,
+	}
+}
+
+func toProtoStatusChange(change *StatusChange) *bancov1.StatusChange {
+	return &bancov1.StatusChange{
+		Status:    change.Status,
+		Timestamp: change.Timestamp.Unix(),
+		Txid:      change.TxHash,
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	bancov1 "github.com/tiero/banco/api-spec/protobuf/gen/go/banco/v1"
+	"github.com/vulpemventures/go-elements/network"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestTradeClient(t *testing.T, price float64) (bancov1.TradeServiceClient, OrderRepository) {
+	repo := NewInMemoryOrderRepository()
+	wallet := &apiWallet{balance: 100000}
+	api := NewAPI(repo, wallet, &apiRatesClient{price: price}, &network.Testnet, time.Hour, false)
+	server := NewTradeServer(api, NewNotificationHub(wallet), nil).(*tradeServer)
+	server.watchInterval = 10 * time.Millisecond
+
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	bancov1.RegisterTradeServiceServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return bancov1.NewTradeServiceClient(conn), repo
+}
+
+func TestTradeServer(t *testing.T) {
+	client, repo := newTestTradeClient(t, 1)
+	ctx := context.Background()
+
+	markets, err := client.ListMarkets(ctx, &bancov1.ListMarketsRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, markets.GetMarkets(), len(GetMarkets()))
+	assert.Equal(t, uint64(100000), markets.GetMarkets()[0].GetBuyLimit())
+	assert.Equal(t, 1.0, markets.GetMarkets()[0].GetBid())
+	assert.Equal(t, 1.0, markets.GetMarkets()[0].GetAsk())
+	assert.False(t, markets.GetMarkets()[0].GetHalted())
+	assert.Equal(t, 0.1, markets.GetMarkets()[1].GetBuyFeePercentage())
+	assert.Equal(t, 0.75, markets.GetMarkets()[1].GetSellFeePercentage())
+
+	quote, err := client.GetQuote(ctx, &bancov1.GetQuoteRequest{Pair: "L-BTC/L-BTC", Type: bancov1.TradeType_TRADE_TYPE_BUY, Amount: 0.001})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100100), quote.GetInput().GetAmount())
+	assert.Equal(t, uint64(100), quote.GetFee().GetAmount())
+	assert.Equal(t, "L-BTC", quote.GetFee().GetCurrency())
+
+	_, err = client.GetQuote(ctx, &bancov1.GetQuoteRequest{Pair: "L-BTC/L-BTC", Amount: 0.001})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	created, err := client.CreateOrder(ctx, &bancov1.CreateOrderRequest{
+		Pair:         "L-BTC/L-BTC",
+		Type:         bancov1.TradeType_TRADE_TYPE_BUY,
+		Amount:       0.001,
+		TraderScript: hex.EncodeToString(traderScriptExpected),
+	})
+	assert.NoError(t, err)
+	order := created.GetOrder()
+	assert.Equal(t, "Pending", order.GetStatus())
+	assert.Len(t, order.GetLeaves(), 3)
+	assert.NotZero(t, order.GetExpiresAt())
+
+	fetched, err := client.GetOrder(ctx, &bancov1.GetOrderRequest{Id: order.GetId()})
+	assert.NoError(t, err)
+	assert.Equal(t, order.GetAddress(), fetched.GetOrder().GetAddress())
+	assert.Len(t, fetched.GetStatuses(), 1)
+
+	// only the maker can cancel the order
+	_, err = client.CancelOrder(ctx, &bancov1.CancelOrderRequest{Id: order.GetId(), Signature: hex.EncodeToString(make([]byte, 64))})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.GetOrder(ctx, &bancov1.GetOrderRequest{Id: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// the current status first, then the new ones until the final one
+	stream, err := client.WatchOrder(ctx, &bancov1.WatchOrderRequest{Id: order.GetId()})
+	if err != nil {
+		t.Fatal("WatchOrder", err)
+	}
+	event, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, "Pending", event.GetStatusChange().GetStatus())
+
+	assert.NoError(t, repo.UpdateOrderStatusWithTxHash(order.GetId(), "Funded", "aa"))
+	assert.NoError(t, repo.UpdateOrderStatusWithTxHash(order.GetId(), "Fulfilled", "bb"))
+	event, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, "Funded", event.GetStatusChange().GetStatus())
+	assert.Equal(t, "aa", event.GetStatusChange().GetTxid())
+	event, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, "Fulfilled", event.GetStatusChange().GetStatus())
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+
+	// fulfilled orders can't be cancelled
+	_, err = client.CancelOrder(ctx, &bancov1.CancelOrderRequest{Id: order.GetId()})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestTradeServer_HaltedMarkets(t *testing.T) {
+	client, _ := newTestTradeClient(t, 0)
+	ctx := context.Background()
+
+	markets, err := client.ListMarkets(ctx, &bancov1.ListMarketsRequest{})
+	assert.NoError(t, err)
+	for _, market := range markets.GetMarkets() {
+		assert.True(t, market.GetHalted())
+		assert.NotEmpty(t, market.GetHaltReason())
+		assert.Zero(t, market.GetPrice())
+		assert.Zero(t, market.GetBid())
+	}
+
+	_, err = client.GetQuote(ctx, &bancov1.GetQuoteRequest{Pair: "L-BTC/L-BTC", Type: bancov1.TradeType_TRADE_TYPE_BUY, Amount: 0.001})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
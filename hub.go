@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/vulpemventures/go-elements/transaction"
+)
+
+const (
+	// how many notifications a subscriber can lag behind before they are
+	// dropped for it
+	notificationsBufferSize = 32
+	// waits before opening again the notifications stream with the wallet
+	notificationsMinBackoff = time.Second
+	notificationsMaxBackoff = time.Minute
+)
+
+// NotificationFilter selects the transactions a subscriber is notified of.
+type NotificationFilter func(tx *transaction.Transaction) bool
+
+// NotificationHub keeps a single transaction notifications stream open with
+// the wallet and fans the notifications out to the in-process subscribers.
+type NotificationHub struct {
+	walletSvc  WalletService
+	bufferSize int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	lock          sync.Mutex
+	subscriptions map[*Subscription]struct{}
+	closed        bool
+}
+
+// Subscription receives the notifications matching its filter on C, until it
+// is unsubscribed or the hub stops.
+type Subscription struct {
+	C <-chan *TransactionNotification
+
+	hub           *NotificationHub
+	notifications chan *TransactionNotification
+	filter        NotificationFilter
+	dropped       int
+	closeOnce     sync.Once
+}
+
+func NewNotificationHub(walletSvc WalletService) *NotificationHub {
+	return &NotificationHub{
+		walletSvc:     walletSvc,
+		bufferSize:    notificationsBufferSize,
+		minBackoff:    notificationsMinBackoff,
+		maxBackoff:    notificationsMaxBackoff,
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+// Run forwards the wallet notifications to the subscribers until the context
+// is done, opening the stream again with exponential backoff whenever it
+// fails. All the subscriptions are closed on return.
+func (h *NotificationHub) Run(ctx context.Context) {
+	defer h.close()
+
+	backoff := h.minBackoff
+	for {
+		notifications, err := h.walletSvc.TransactionNotifications(ctx)
+		if err != nil {
+			log.Printf("error opening transaction notifications stream: %v", err)
+		} else if h.forward(ctx, notifications) {
+			// the stream worked, start over with the shortest wait
+			backoff = h.minBackoff
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		log.Printf("reopening transaction notifications stream after %s", backoff)
+
+		backoff *= 2
+		if backoff > h.maxBackoff {
+			backoff = h.maxBackoff
+		}
+	}
+}
+
+// Subscribe returns a subscription to the notifications of the transactions
+// matching the filter, or to all of them if nil.
+func (h *NotificationHub) Subscribe(filter NotificationFilter) *Subscription {
+	notifications := make(chan *TransactionNotification, h.bufferSize)
+	sub := &Subscription{
+		C:             notifications,
+		hub:           h,
+		notifications: notifications,
+		filter:        filter,
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.closed {
+		sub.closeOnce.Do(func() { close(sub.notifications) })
+		return sub
+	}
+	h.subscriptions[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe stops the notifications and closes C. It is safe to call more
+// than once.
+func (s *Subscription) Unsubscribe() {
+	s.hub.lock.Lock()
+	defer s.hub.lock.Unlock()
+	delete(s.hub.subscriptions, s)
+	s.closeOnce.Do(func() { close(s.notifications) })
+}
+
+// forward publishes the notifications of the stream until it is closed or the
+// context is done, and tells whether any was received.
+func (h *NotificationHub) forward(ctx context.Context, notifications <-chan *TransactionNotification) bool {
+	received := false
+	for {
+		select {
+		case <-ctx.Done():
+			return received
+		case notification, ok := <-notifications:
+			if !ok {
+				return received
+			}
+			received = true
+			h.publish(notification)
+		}
+	}
+}
+
+// publish sends the notification to the matching subscribers without waiting
+// for them: the ones whose buffer is full miss it.
+func (h *NotificationHub) publish(notification *TransactionNotification) {
+	var tx *transaction.Transaction
+	if notification.TxHex != "" {
+		// a transaction that can't be parsed only goes to unfiltered subscribers
+		tx, _ = transaction.NewTxFromHex(notification.TxHex)
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for sub := range h.subscriptions {
+		if sub.filter != nil && (tx == nil || !sub.filter(tx)) {
+			continue
+		}
+		select {
+		case sub.notifications <- notification:
+		default:
+			sub.dropped++
+			log.Printf("subscriber lagging behind, dropped notification of tx %s (%d so far)", notification.TxId, sub.dropped)
+		}
+	}
+}
+
+func (h *NotificationHub) close() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.closed = true
+	for sub := range h.subscriptions {
+		delete(h.subscriptions, sub)
+		sub.closeOnce.Do(func() { close(sub.notifications) })
+	}
+}
+
+// PaysToScripts selects the transactions with an output paying to any of the
+// scripts.
+func PaysToScripts(scripts ...[]byte) NotificationFilter {
+	return func(tx *transaction.Transaction) bool {
+		for _, output := range tx.Outputs {
+			for _, script := range scripts {
+				if bytes.Equal(output.Script, script) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
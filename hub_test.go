@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vulpemventures/go-elements/transaction"
+)
+
+// streamsWallet opens the queued notification streams in order, failing when
+// the next one is nil.
+type streamsWallet struct {
+	WalletService
+	streams chan chan *TransactionNotification
+}
+
+func (w *streamsWallet) TransactionNotifications(ctx context.Context) (<-chan *TransactionNotification, error) {
+	select {
+	case stream := <-w.streams:
+		if stream == nil {
+			return nil, fmt.Errorf("connection refused")
+		}
+		return stream, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func newTestHub(wallet WalletService, bufferSize int) *NotificationHub {
+	hub := NewNotificationHub(wallet)
+	hub.bufferSize = bufferSize
+	hub.minBackoff = time.Millisecond
+	hub.maxBackoff = 5 * time.Millisecond
+	return hub
+}
+
+func receive(t *testing.T, sub *Subscription) *TransactionNotification {
+	t.Helper()
+	select {
+	case notification := <-sub.C:
+		return notification
+	case <-time.After(2 * time.Second):
+		t.Fatal("no notification received")
+		return nil
+	}
+}
+
+func TestNotificationHub(t *testing.T) {
+	script := []byte{0x51, 0x20, 0x01}
+	tx := transaction.NewTx(2)
+	// unblinded asset and value
+	asset := append([]byte{0x01}, make([]byte, 32)...)
+	value := append([]byte{0x01}, make([]byte, 8)...)
+	tx.AddOutput(transaction.NewTxOutput(asset, value, script))
+	txHex, err := tx.ToHex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wallet := &streamsWallet{streams: make(chan chan *TransactionNotification, 3)}
+	hub := newTestHub(wallet, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(done)
+	}()
+
+	all := hub.Subscribe(nil)
+	filtered := hub.Subscribe(PaysToScripts(script))
+	lagging := hub.Subscribe(nil)
+
+	// the first stream fails, the second one works
+	first := make(chan *TransactionNotification)
+	wallet.streams <- nil
+	wallet.streams <- first
+
+	first <- &TransactionNotification{TxId: "unparsable", TxHex: "zz"}
+	assert.Equal(t, "unparsable", receive(t, all).TxId)
+	first <- &TransactionNotification{TxId: "match", TxHex: txHex}
+	assert.Equal(t, "match", receive(t, all).TxId)
+	assert.Equal(t, "match", receive(t, filtered).TxId)
+
+	// the notifications beyond the buffer are dropped for the lagging one
+	assert.Equal(t, "unparsable", receive(t, lagging).TxId)
+	select {
+	case notification := <-lagging.C:
+		t.Fatalf("unexpected notification %s", notification.TxId)
+	default:
+	}
+
+	// unsubscribing closes the channel and can be repeated
+	lagging.Unsubscribe()
+	lagging.Unsubscribe()
+	_, ok := <-lagging.C
+	assert.False(t, ok)
+
+	// the stream is opened again once closed
+	second := make(chan *TransactionNotification)
+	close(first)
+	wallet.streams <- second
+	second <- &TransactionNotification{TxId: "after", TxHex: txHex}
+	assert.Equal(t, "after", receive(t, all).TxId)
+	assert.Equal(t, "after", receive(t, filtered).TxId)
+
+	// the subscriptions are closed when the hub stops
+	cancel()
+	<-done
+	_, ok = <-all.C
+	assert.False(t, ok)
+	_, ok = <-filtered.C
+	assert.False(t, ok)
+	_, ok = <-hub.Subscribe(nil).C
+	assert.False(t, ok)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// inMemoryOrderRepository is the OrderRepository keeping the orders in memory,
+// to run Banco without a database, e.g. in tests.
+type inMemoryOrderRepository struct {
+	lock     sync.RWMutex
+	orders   map[string]Order
+	ids      []string
+	statuses map[string][]*StatusChange
+}
+
+func NewInMemoryOrderRepository() OrderRepository {
+	return &inMemoryOrderRepository{
+		orders:   make(map[string]Order),
+		statuses: make(map[string][]*StatusChange),
+	}
+}
+
+func (r *inMemoryOrderRepository) Close() error {
+	return nil
+}
+
+func (r *inMemoryOrderRepository) SaveOrder(order *Order) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.orders[order.ID]; ok {
+		return fmt.Errorf("order %s already exists", order.ID)
+	}
+	r.orders[order.ID] = *order
+	r.ids = append(r.ids, order.ID)
+	r.statuses[order.ID] = []*StatusChange{{
+		Status:    "Pending",
+		Timestamp: order.Timestamp.UTC().Truncate(time.Second),
+	}}
+	return nil
+}
+
+func (r *inMemoryOrderRepository) UpdateOrderStatus(id string, status string) error {
+	return r.appendOrderStatus(id, status, "")
+}
+
+func (r *inMemoryOrderRepository) UpdateOrderStatusWithTxHash(id string, status string, txHash string) error {
+	return r.appendOrderStatus(id, status, txHash)
+}
+
+func (r *inMemoryOrderRepository) appendOrderStatus(id string, status string, txHash string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	statuses := r.statuses[id]
+	if len(statuses) > 0 {
+		// an empty txHash matches the current status whatever its txid
+		current := statuses[len(statuses)-1]
+		if current.Status == status && (txHash == "" || current.TxHash == txHash) {
+			return nil
+		}
+	}
+	r.statuses[id] = append(statuses, &StatusChange{
+		Status:    status,
+		Timestamp: time.Now().UTC().Truncate(time.Second),
+		TxHash:    txHash,
+	})
+	return nil
+}
+
+func (r *inMemoryOrderRepository) AddOrderFilledAmount(id string, amount uint64) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	order, ok := r.orders[id]
+	if !ok {
+		return nil
+	}
+	order.FilledAmount += amount
+	r.orders[id] = order
+	return nil
+}
+
+func (r *inMemoryOrderRepository) FlagOrderMismatchedDeposits(id string, mismatched int) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	order, ok := r.orders[id]
+	if !ok {
+		return nil
+	}
+	order.Mismatched = mismatched
+	r.orders[id] = order
+	return nil
+}
+
+func (r *inMemoryOrderRepository) FetchOrdersToFulfill(networkName string) ([]*Order, error) {
+	return r.fetchOrders(networkName, func(order *Order, current *StatusChange) bool {
+		switch current.Status {
+		case "Pending", "Funded", "Underfunded", "Overfunded", "PartiallyFilled":
+			return true
+		}
+		return false
+	})
+}
+
+func (r *inMemoryOrderRepository) FetchOrdersToSweep(networkName string) ([]*Order, error) {
+	return r.fetchOrders(networkName, func(order *Order, current *StatusChange) bool {
+		return current.Status == "Expired" && len(order.ExpiryScript) > 0 && current.TxHash == ""
+	})
+}
+
+func (r *inMemoryOrderRepository) fetchOrders(networkName string, filter func(order *Order, current *StatusChange) bool) ([]*Order, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var orders []*Order
+	for _, id := range r.ids {
+		order := r.orders[id]
+		statuses := r.statuses[id]
+		if order.Network == nil || order.Network.Name != networkName {
+			continue
+		}
+		if filter(&order, statuses[len(statuses)-1]) {
+			orders = append(orders, &order)
+		}
+	}
+	return orders, nil
+}
+
+func (r *inMemoryOrderRepository) FetchOrderIDByAddress(address string) (string, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, id := range r.ids {
+		if r.orders[id].Address == address {
+			return id, nil
+		}
+	}
+	return "", sql.ErrNoRows
+}
+
+func (r *inMemoryOrderRepository) FetchOrderByID(id string) (*Order, string, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	order, ok := r.orders[id]
+	if !ok {
+		return nil, "", sql.ErrNoRows
+	}
+	statuses := r.statuses[id]
+	return &order, statuses[len(statuses)-1].Status, nil
+}
+
+func (r *inMemoryOrderRepository) FetchOrderStatuses(id string) ([]*StatusChange, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	statuses := make([]*StatusChange, 0, len(r.statuses[id]))
+	for _, status := range r.statuses[id] {
+		change := *status
+		statuses = append(statuses, &change)
+	}
+	return statuses, nil
+}
+
+func (r *inMemoryOrderRepository) ListOrders(networkName string, status string, limit int, offset int) ([]*OrderWithStatus, error) {
+	if limit <= 0 && offset > 0 {
+		return nil, fmt.Errorf("offset requires a limit")
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	orders := []*OrderWithStatus{}
+	for _, id := range r.ids {
+		order := r.orders[id]
+		statuses := r.statuses[id]
+		current := statuses[len(statuses)-1].Status
+		if !order.IsOnNetwork(SupportedNetworks[networkName]) || (status != "" && current != status) {
+			continue
+		}
+		orders = append(orders, &OrderWithStatus{Order: &order, Status: current})
+	}
+	// newest first, as the timestamps are stored to the second
+	sort.SliceStable(orders, func(i, j int) bool {
+		return orders[i].Order.Timestamp.Truncate(time.Second).After(orders[j].Order.Timestamp.Truncate(time.Second))
+	})
+
+	if offset >= len(orders) {
+		return []*OrderWithStatus{}, nil
+	}
+	orders = orders[offset:]
+	if limit > 0 && limit < len(orders) {
+		orders = orders[:limit]
+	}
+	return orders, nil
+}
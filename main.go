@@ -1,10 +1,9 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/hex"
 	"fmt"
-	"html/template"
 	"io"
 	"math"
 	"net/http"
@@ -28,8 +27,23 @@ func main() {
 	viper.SetDefault("WEB_DIR", "web")
 	viper.SetDefault("OCEAN_URL", "localhost:18000")
 	viper.SetDefault("OCEAN_ACCOUNT_NAME", "default")
-	viper.SetDefault("WATCH_INTERVAL_SECONDS", "-1")
+	viper.SetDefault("OCEAN_CONFIDENTIAL", false)
+	viper.SetDefault("WATCH_INTERVAL_SECONDS", 60)
+	viper.SetDefault("ORDER_EXPIRY_MINUTES", 10)
+	viper.SetDefault("FEE_RATE", 0)
+	viper.SetDefault("FEE_CONFIRMATION_TARGET", 1)
 	viper.SetDefault("NETWORK", "liquid")
+	viper.SetDefault("DB_TYPE", "sqlite")
+	viper.SetDefault("DB_DSN", "")
+	viper.SetDefault("GRPC_PORT", "9090")
+	viper.SetDefault("CHAIN_SOURCE", "esplora")
+	viper.SetDefault("CHAIN_SOURCE_URL", "")
+	viper.SetDefault("EXPLORER_URL", "")
+	viper.SetDefault("PRICE_FEEDS", "kraken,bitfinex,binance")
+	viper.SetDefault("STATIC_PRICES", "")
+	viper.SetDefault("PRICE_FEED_URL", "")
+	viper.SetDefault("PRICE_MAX_DEVIATION", 2)
+	viper.SetDefault("PRICE_MAX_AGE_SECONDS", 60)
 
 	// Set up Logrus for logging
 	log.SetFormatter(&log.TextFormatter{})
@@ -39,8 +53,32 @@ func main() {
 	webDir := viper.GetString("WEB_DIR")
 	oceanURL := viper.GetString("OCEAN_URL")
 	oceanAccountName := viper.GetString("OCEAN_ACCOUNT_NAME")
+	oceanConfidential := viper.GetBool("OCEAN_CONFIDENTIAL")
 	networkName := viper.GetString("NETWORK")
 	watchInterval := viper.GetInt("WATCH_INTERVAL_SECONDS")
+	orderExpiry := time.Duration(viper.GetInt("ORDER_EXPIRY_MINUTES")) * time.Minute
+	feeRate := viper.GetFloat64("FEE_RATE")
+	feeConfirmationTarget := viper.GetInt("FEE_CONFIRMATION_TARGET")
+	dbType := viper.GetString("DB_TYPE")
+	dbDSN := viper.GetString("DB_DSN")
+	grpcPort := viper.GetString("GRPC_PORT")
+	chainSource := viper.GetString("CHAIN_SOURCE")
+	chainSourceURL := viper.GetString("CHAIN_SOURCE_URL")
+	explorerURL := viper.GetString("EXPLORER_URL")
+	priceFeeds := strings.Split(viper.GetString("PRICE_FEEDS"), ",")
+	staticPrices := viper.GetString("STATIC_PRICES")
+	priceFeedURL := viper.GetString("PRICE_FEED_URL")
+	priceMaxDeviation := viper.GetFloat64("PRICE_MAX_DEVIATION")
+	priceMaxAge := time.Duration(viper.GetInt("PRICE_MAX_AGE_SECONDS")) * time.Second
+
+	// banco migrate [up|check|down] manages the database schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := runMigrate(dbType, dbDSN, os.Args[2:])
+		if err != nil {
+			log.Fatal("migrate: ", err)
+		}
+		return
+	}
 
 	// validate network
 	net, ok := SupportedNetworks[networkName]
@@ -49,58 +87,67 @@ func main() {
 	}
 
 	// initialize database
-	_, err := initDB()
+	repo, err := NewOrderRepository(dbType, dbDSN)
 	if err != nil {
 		log.Fatal("connect to db: ", err)
 	}
+	defer repo.Close()
 
 	// setup connection with wallet
-	walletSvc, err := NewWalletService(oceanURL, oceanAccountName)
+	walletSvc, err := NewWalletService(oceanURL, oceanAccountName, oceanConfidential)
 	if err != nil {
 		log.Fatal("start wallet service: %w", err)
 	}
 
-	// new instance of an Esplora HTTP client
-	esplora, err := NewEsplora(networkName)
-	if err != nil {
-		log.Fatal("esplora initialization error: %w", err)
+	// one transaction notifications stream with the wallet, shared by all
+	// the order events streams
+	hub := NewNotificationHub(walletSvc)
+	go hub.Run(context.Background())
+
+	// links to the transactions on a self-hosted explorer
+	if explorerURL != "" {
+		EsploraURLs[networkName] = strings.TrimSuffix(explorerURL, "/")
 	}
 
-	// Start processing pending trades
-	if watchInterval > 0 {
-		// start watching
-		go startWatching(func() {
-			orders, err := fetchOrdersToFulfill()
-			if err != nil {
-				log.Error(fmt.Errorf("error in fetching orders: %w", err))
-				return
-			}
+	// Esplora, Electrum or elementsd to read the chain
+	chain, err := NewChainSource(chainSource, chainSourceURL, networkName)
+	if err != nil {
+		log.Fatal("chain source initialization error: ", err)
+	}
 
-			for _, order := range orders {
-				err = watchForTrades(order, walletSvc, esplora)
-				if err != nil {
-					log.Error(fmt.Errorf("error in fulfilling order of %f %s: ID %s : %w", float64(order.Output.Amount), order.Output.Asset, order.ID, err))
-					continue
-				}
-			}
+	// fixed fee rate, if set, or the one estimated by Esplora
+	feeEstimator := NewFeeEstimator(chain, feeRate, feeConfirmationTarget)
 
-		}, watchInterval)
-	}
+	// fulfill the orders as their deposits are notified, reconciling all of
+	// them and sweeping the expired ones every interval
+	watcher := NewWatcher(repo, walletSvc, hub, chain, feeEstimator, net, time.Duration(watchInterval)*time.Second)
+	go watcher.Run(context.Background())
 
-	// rates client
-	rates := NewKrakenClient()
-	if rates == nil {
-		log.Fatalf("cant be nil")
+	// median price of the feeds, the markets are halted while none is fresh
+	rates, err := NewRatesClient(priceFeeds, staticPrices, priceFeedURL, priceMaxDeviation, priceMaxAge)
+	if err != nil {
+		log.Fatal("price feeds: ", err)
 	}
-
 	err = rates.Subscribe()
 	if err != nil {
-		log.Fatalf("failed to subscribe to kraken ws: %v", err)
+		log.Error(fmt.Errorf("failed to subscribe to the price feeds: %w", err))
 	}
 
 	router := gin.Default()
 	router.LoadHTMLGlob(webDir + "/*")
 
+	// JSON API
+	api := NewAPI(repo, walletSvc, rates, net, orderExpiry, oceanConfidential)
+	api.Register(router)
+
+	// gRPC TradeService, next to the web server
+	go func() {
+		err := serveGRPC(grpcPort, NewTradeServer(api, hub, watcher))
+		if err != nil {
+			log.Fatal("serve gRPC: ", err)
+		}
+	}()
+
 	// API
 	router.GET("/pair", func(c *gin.Context) {
 		pair := c.Query("pair")
@@ -120,10 +167,17 @@ func main() {
 			return
 		}
 
-		rate, err := rates.MarketPrice(mkt.BaseAsset, mkt.QuoteAsset)
+		// the ask or bid, spread included, of the top of the book
+		rateText := ""
+		ticker, err := rates.MarketTicker(mkt.BaseAsset, mkt.QuoteAsset)
+		if err == nil {
+			var rate float64
+			rate, err = TradePrice(ticker, mkt, tradeType, 0)
+			rateText = fmt.Sprintf("Rate <strong>%s</strong> %s", fmt.Sprint(rate), mkt.QuoteAsset)
+		}
 		if err != nil {
-			rate = -1
-			return
+			// no fresh price, trading is halted until there is one
+			rateText = "Market halted, no recent price available"
 		}
 
 		limit := mkt.BuyLimit
@@ -143,9 +197,9 @@ func main() {
 			<label id="limitText" class="block text-sm font-medium text-gray-700">Limit <strong>%s</strong> %s</label>
 		</div>
 		<div class="mb-4">
-			<label id="rateText" class="block text-sm font-medium text-gray-700">Rate <strong>%s</strong> %s</label>
+			<label id="rateText" class="block text-sm font-medium text-gray-700">%s</label>
 		</div>
-	</div>`, fmt.Sprint(limitFractional), currency, fmt.Sprint(rate), mkt.QuoteAsset)
+	</div>`, fmt.Sprint(limitFractional), currency, rateText)
 
 		// Return the HTML string
 		c.String(http.StatusOK, outputValueHTML)
@@ -172,32 +226,25 @@ func main() {
 			return
 		}
 
-		rate, err := rates.MarketPrice(mkt.BaseAsset, mkt.QuoteAsset)
+		// the fee of the market for the side and size of the trade
+		quote, err := QuoteTrade(rates, mkt, tradeType, amount)
 		if err != nil {
-			c.String(http.StatusInternalServerError, fmt.Sprintf("error getting price from stream: %v", err))
+			c.String(http.StatusServiceUnavailable, err.Error())
 			return
 		}
 
-		feePercentage := rates.FeePercentage(mkt.BaseAsset, mkt.QuoteAsset)
-
-		// Calculate the output amount
-		previewAmt := rate * amount
-
-		// Adjust the output amount based on the trade type and determine the action
-		var action string
-		if tradeType == "Buy" {
-			previewAmt *= (1 + feePercentage/100) // Add the fee to the output amount
-			action = "You Send"
-		} else { // Sell
-			previewAmt *= (1 - feePercentage/100) // Subtract the fee from the output amount
-			action = "You Receive"
+		// Determine the action and the amount sent or received in the quote asset
+		action, previewAmt := "You Send", quote.InputValue
+		if tradeType == "Sell" {
+			action, previewAmt = "You Receive", quote.OutputValue
 		}
 
 		// Return the output amount
 		outputValueHTML := fmt.Sprintf(`<div id="recapBox" class="p-4 bg-gray-100 rounded-lg">
     	<label id="recapText" class="block text-sm font-medium text-gray-700">%s</label>
     <p id="recapAmount" class="text-lg font-semibold">%f %s</p>
-</div>`, action, previewAmt, mkt.QuoteAsset)
+    <p id="recapFee" class="text-sm text-gray-700">Fee included %f %s (%v%%)</p>
+</div>`, action, previewAmt, mkt.QuoteAsset, quote.Fee, quote.FeeCurrency, quote.FeePercentage)
 
 		// Return the HTML string
 		c.String(http.StatusOK, outputValueHTML)
@@ -206,6 +253,9 @@ func main() {
 	router.POST("/trade", func(c *gin.Context) {
 
 		traderScriptHex := c.PostForm("traderScript")
+		makerPubKeyHex := c.PostForm("makerPubKey")
+		makerBlindingKeyHex := c.PostForm("makerBlindingKey")
+		partialFill := c.PostForm("partialFill") == "true"
 		tradingPair := c.PostForm("pair")
 		amountStr := c.PostForm("amount")
 		tradeType := c.PostForm("type")
@@ -215,6 +265,12 @@ func main() {
 			return
 		}
 
+		// confidential orders need Ocean's blinded addresses to blind our outputs
+		if makerBlindingKeyHex != "" && !oceanConfidential {
+			c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": "confidential trades are not enabled"})
+			return
+		}
+
 		log.Infof("tradingPair: %s, tradeType: %s, amountStr: %s", tradingPair, tradeType, amountStr)
 
 		// Convert the input value to a float
@@ -232,49 +288,29 @@ func main() {
 			return
 		}
 
-		price, err := rates.MarketPrice(mkt.BaseAsset, mkt.QuoteAsset)
-		if err != nil {
-			c.String(http.StatusInternalServerError, fmt.Sprintf("error getting price from stream: %v", err))
-			return
-		}
-
-		feePercentage := rates.FeePercentage(mkt.BaseAsset, mkt.QuoteAsset)
 		// Determine the input value, input currency, output value, and output currency based on the trade type
-		var inputValue, outputValue float64
-		var inputCurrency, outputCurrency string
-		if tradeType == "Buy" {
-			inputValue = amount * price * (1 + feePercentage/100) // Add the fee to the input value
-			inputCurrency = mkt.QuoteAsset
-			outputValue = amount
-			outputCurrency = mkt.BaseAsset
-		} else if tradeType == "Sell" { // Sell
-			inputValue = amount
-			inputCurrency = mkt.BaseAsset
-			outputValue = amount * price * (1 - feePercentage/100) // Subtract the fee from the output value
-			outputCurrency = mkt.QuoteAsset
-		}
-
-		log.Infof("inputValue: %v, outputValue: %v", inputValue, outputValue)
-
-		order, err := NewOrder(traderScriptHex, inputCurrency, fmt.Sprintf("%v", inputValue), outputCurrency, fmt.Sprintf("%v", outputValue), price, net)
+		quote, err := QuoteTrade(rates, mkt, tradeType, amount)
 		if err != nil {
-			c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
+			c.String(http.StatusBadRequest, err.Error())
 			return
 		}
 
-		err = saveOrder(order)
+		log.Infof("inputValue: %v, outputValue: %v", quote.InputValue, quote.OutputValue)
+
+		rate, err := MarketRate(rates, mkt, tradeType)
 		if err != nil {
-			c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
+			c.String(http.StatusServiceUnavailable, err.Error())
 			return
 		}
 
-		script, err := address.ToOutputScript(order.Address)
+		order, err := NewOrder(traderScriptHex, makerPubKeyHex, makerBlindingKeyHex, partialFill, orderExpiry, quote, rate, net)
 		if err != nil {
 			c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
 			return
 		}
-		// Start watching the script and get the notification channel
-		err = walletSvc.WatchScript(c.Request.Context(), hex.EncodeToString(script))
+
+		// Store the order and start watching its script
+		err = saveAndWatchOrder(c.Request.Context(), repo, walletSvc, order)
 		if err != nil {
 			c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
 			return
@@ -298,21 +334,28 @@ func main() {
 	router.GET("/offer/address/:address", func(c *gin.Context) {
 		addr := c.Params.ByName("address")
 
-		ID, err := fetchOrderIDByAddress(addr)
+		ID, err := repo.FetchOrderIDByAddress(addr)
 		if err != nil {
 			log.Error(err)
 			c.HTML(http.StatusNotFound, "404.html", gin.H{})
 			return
 		}
 
-		c.Redirect(http.StatusSeeOther, "/offer/"+ID)
+		// the address of an order of another network is not found either
+		order, _, err := repo.FetchOrderByID(ID)
+		if err != nil || !order.IsOnNetwork(net) {
+			c.HTML(http.StatusNotFound, "404.html", gin.H{})
+			return
+		}
+
+		c.Redirect(http.StatusSeeOther, "/offer/"+order.ID)
 	})
 
 	router.GET("/offer/:id", func(c *gin.Context) {
 		id := c.Params.ByName("id")
 
-		order, status, err := fetchOrderByID(id)
-		if err != nil {
+		order, status, err := repo.FetchOrderByID(id)
+		if err != nil || !order.IsOnNetwork(net) {
 			c.HTML(http.StatusNotFound, "404.html", gin.H{})
 			return
 		}
@@ -329,107 +372,207 @@ func main() {
 			return
 		}
 
-		transactions, err := getTransactionsForAddress(order.Address, networkName)
+		transactions, err := chain.FetchTransactionHistory(order.Address)
 		if err != nil {
 			c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
 			return
 		}
 
-		// Split transactions into confirmed and pending
-		var confirmedTransactions, pendingTransactions []map[string]interface{}
-		for _, tx := range transactions {
-			transaction := map[string]interface{}{
-				"txID":          tx.TxID,
-				"txIDShort":     tx.TxID[:6] + "..." + tx.TxID[len(tx.TxID)-6:],
-				"confirmed":     tx.Status.Confirmed,
-				"date":          time.Unix(int64(tx.Status.BlockTime), 0).Format("2006-01-02 15:04:05"),
-				"explorerTxURL": fmt.Sprintf("%s/tx/%s", EsploraURLs[networkName], tx.TxID),
-			}
+		statuses, err := repo.FetchOrderStatuses(order.ID)
+		if err != nil {
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
+			return
+		}
 
-			if tx.Status.Confirmed {
-				confirmedTransactions = append(confirmedTransactions, transaction)
-			} else {
-				pendingTransactions = append(pendingTransactions, transaction)
-			}
+		data := gin.H{
+			"id":             order.ID,
+			"address":        order.Address,
+			"outputValue":    order.OutputValue(),
+			"outputCurrency": assetToCurrency[order.Output.Asset],
+			"inputAssetHash": order.Input.Asset,
+			"inputAmount":    order.Input.Amount,
+			"date":           order.Timestamp.Format("2006-01-02 15:04:05"),
+		}
+		for key, value := range offerStatusData(order, status, statuses, networkName) {
+			data[key] = value
 		}
+		for key, value := range offerTransactionsData(transactions, networkName) {
+			data[key] = value
+		}
+		c.HTML(http.StatusOK, "offer.html", data)
+	})
 
-		inputCurrency := assetToCurrency[order.Input.Asset]
-		outputCurrency := assetToCurrency[order.Output.Asset]
-		date := order.Timestamp.Format("2006-01-02 15:04:05")
-		c.HTML(http.StatusOK, "offer.html", gin.H{
-			"id":                    order.ID,
-			"address":               order.Address,
-			"inputValue":            order.InputValue(),
-			"inputCurrency":         inputCurrency,
-			"outputValue":           order.OutputValue(),
-			"outputCurrency":        outputCurrency,
-			"confirmedTransactions": confirmedTransactions,
-			"pendingTransactions":   pendingTransactions,
-			"inputAssetHash":        order.Input.Asset,
-			"inputAmount":           order.Input.Amount,
-			"status":                status,
-			"date":                  date,
-		})
+	router.POST("/offer/:id/cancel", func(c *gin.Context) {
+		id := c.Params.ByName("id")
+
+		order, status, err := repo.FetchOrderByID(id)
+		if err != nil || !order.IsOnNetwork(net) {
+			c.HTML(http.StatusNotFound, "404.html", gin.H{})
+			return
+		}
+		if !isCancellable(status) {
+			c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": fmt.Sprintf("order is %s and cannot be cancelled", status)})
+			return
+		}
+		// Only the maker can cancel the order
+		if err := order.VerifyOwnership(c.PostForm("signature")); err != nil {
+			c.HTML(http.StatusForbidden, "error.html", gin.H{"error": err.Error()})
+			return
+		}
+
+		_, err = watcher.CancelOrder(order)
+		if err != nil {
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Redirect(http.StatusSeeOther, "/offer/"+order.ID)
+	})
+
+	router.GET("/offer/:id/cancel-pset", func(c *gin.Context) {
+		id := c.Params.ByName("id")
+
+		order, status, err := repo.FetchOrderByID(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if !order.IsOnNetwork(net) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+			return
+		}
+		if !isKeyPathCancellable(status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("order is %s and cannot be cancelled", status)})
+			return
+		}
+
+		utxos, err := chain.FetchUnspents(order.Address)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// The maker funds the fees of contracts holding no L-BTC, with the
+		// unspents given as txid:index
+		feeUtxos, err := fetchUnspentsOf(chain, c.QueryArray("feeUnspent"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ptx, err := PrepareKeyPathCancelTransaction(order, utxos, feeUtxos, net, feeEstimator.FeeRate())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		psetBase64, err := ptx.ToBase64()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Return the unsigned PSET for the maker to sign via key-path
+		c.String(http.StatusOK, psetBase64)
+	})
+
+	router.POST("/offer/:id/cancel-pset", func(c *gin.Context) {
+		id := c.Params.ByName("id")
+
+		order, status, err := repo.FetchOrderByID(id)
+		if err != nil || !order.IsOnNetwork(net) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+			return
+		}
+		if !isKeyPathCancellable(status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("order is %s and cannot be cancelled", status)})
+			return
+		}
+
+		txid, err := watcher.CancelOrderWithSignedPset(order, c.PostForm("pset"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"txid": txid})
 	})
 
 	router.GET("/offer/:id/status", func(c *gin.Context) {
+		id := c.Params.ByName("id")
+
+		order, status, err := repo.FetchOrderByID(id)
+		if err != nil || !order.IsOnNetwork(net) {
+			c.HTML(http.StatusNotFound, "404.html", gin.H{})
+			return
+		}
+		statuses, err := repo.FetchOrderStatuses(order.ID)
+		if err != nil {
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
+			return
+		}
 
+		c.HTML(http.StatusOK, "status.html", offerStatusData(order, status, statuses, networkName))
 	})
 
-	router.GET("/offer/:id/transactions", func(c *gin.Context) {
-		notifChan, err := walletSvc.TransactionNotifications(c.Request.Context())
+	// live status and transactions of the order, rendered as the sections
+	// of the offer page
+	router.GET("/offer/:id/events", func(c *gin.Context) {
+		id := c.Params.ByName("id")
+
+		order, _, err := repo.FetchOrderByID(id)
+		if err != nil || !order.IsOnNetwork(net) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+			return
+		}
+
+		events, err := WatchOrderEvents(c.Request.Context(), repo, hub, order, orderEventsInterval)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
 		c.Stream(func(w io.Writer) bool {
-			var confirmedTransactions, pendingTransactions []map[string]interface{}
-
-			// listen to the tx notification
-			select {
-			case <-c.Request.Context().Done():
+			event, ok := <-events
+			if !ok {
+				// hold the connection after the final status, or the browser
+				// reconnects to get it again
+				<-c.Request.Context().Done()
 				return false
-			case event := <-notifChan:
-				txid := event.TxId
-				confirmed := event.Confirmed
-				timestamp := event.Timestamp
-
-				transaction := map[string]interface{}{
-					"txID":          txid,
-					"txIDShort":     txid[:6] + "..." + txid[len(txid)-6:],
-					"confirmed":     confirmed,
-					"date":          time.Unix(timestamp, 0).Format("2006-01-02 15:04:05"),
-					"explorerTxURL": fmt.Sprintf("%s/tx/%s", EsploraURLs[networkName], txid),
-				}
+			}
 
-				if confirmed {
-					confirmedTransactions = append(confirmedTransactions, transaction)
-				} else {
-					pendingTransactions = append(pendingTransactions, transaction)
+			if event.StatusChange != nil {
+				order, status, err := repo.FetchOrderByID(id)
+				if err != nil {
+					c.SSEvent("error", err.Error())
+					return false
 				}
-
-				data := map[string]interface{}{
-					"confirmedTransactions": confirmedTransactions,
-					"pendingTransactions":   pendingTransactions,
+				statuses, err := repo.FetchOrderStatuses(id)
+				if err != nil {
+					c.SSEvent("error", err.Error())
+					return false
+				}
+				html, err := renderFragment(webDir, "status.html", offerStatusData(order, status, statuses, networkName))
+				if err != nil {
+					c.SSEvent("error", err.Error())
+					return false
 				}
+				c.SSEvent("status", html)
+			}
 
-				// Create a new template
-				tmpl, err := template.ParseFiles(webDir + "/transactions.html")
+			if event.Transaction != nil {
+				transactions, err := chain.FetchTransactionHistory(order.Address)
 				if err != nil {
 					c.SSEvent("error", err.Error())
 					return false
 				}
-				// Execute the template with the data and write the result to a string
-				var html bytes.Buffer
-				err = tmpl.Execute(&html, data)
+				transactions = withNotifiedTransaction(transactions, event.Transaction)
+				html, err := renderFragment(webDir, "transactions.html", offerTransactionsData(transactions, networkName))
 				if err != nil {
 					c.SSEvent("error", err.Error())
 					return false
 				}
-				htmlStr := strings.ReplaceAll(html.String(), "\n", " ")
-				// Send the notification to the client as SSE
-				c.SSEvent("transactions", htmlStr)
+				c.SSEvent("transactions", html)
 			}
 			return true
 		})
@@ -455,11 +598,3 @@ func main() {
 
 	router.Run(":8080")
 }
-
-func startWatching(fn func(), watchInterval int) {
-	for {
-		fn()
-		// Wait for a defined interval before polling again
-		time.Sleep(time.Duration(watchInterval) * time.Second)
-	}
-}
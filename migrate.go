@@ -0,0 +1,368 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Migration is a versioned change of the database schema, read from the
+// migrations/<adapter>/<version>_<name>.{up,down}.sql files.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrator applies and rolls back the migrations of a database, keeping
+// track of the applied ones in the schema_version table.
+type Migrator struct {
+	db         *sql.DB
+	adapter    string
+	migrations []*Migration
+}
+
+func NewMigrator(db *sql.DB, adapter string) (*Migrator, error) {
+	m, err := openMigrator(db, adapter)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.init()
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// openMigrator returns a Migrator that leaves the database untouched: legacy
+// databases are not upgraded and the schema_version table is not created.
+func openMigrator(db *sql.DB, adapter string) (*Migrator, error) {
+	migrations, err := loadMigrations(adapter)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, adapter: adapter, migrations: migrations}, nil
+}
+
+func loadMigrations(adapter string) ([]*Migration, error) {
+	dir := path.Join("migrations", adapter)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for %s: %w", adapter, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		// <version>_<name>.<direction>.sql
+		versionStr, migrationName, ok := strings.Cut(strings.TrimSuffix(name, "."+direction+".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name: %s", name)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version: %s", name)
+		}
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: migrationName}
+			byVersion[version] = migration
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d of %s lacks the up or down script", migration.Version, adapter)
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func (m *Migrator) init() error {
+	// SQLite databases created before the migrations were upgraded in place
+	// at startup, they must reach the initial schema before tracking it
+	if m.adapter == sqliteAdapter {
+		legacy, err := m.isLegacySQLite()
+		if err != nil {
+			return err
+		}
+		if legacy {
+			err = upgradeLegacySQLite(m.db)
+			if err != nil {
+				return fmt.Errorf("upgrade legacy database: %w", err)
+			}
+		}
+	}
+
+	_, err := m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name TEXT,
+		applied_at TEXT
+	)`)
+	if err != nil {
+		return fmt.Errorf("create table schema_version: %w", err)
+	}
+	return nil
+}
+
+func (m *Migrator) isLegacySQLite() (bool, error) {
+	rows, err := m.db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name IN ('orders', 'schema_version')`)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	tables := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		tables[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	return tables["orders"] && !tables["schema_version"], nil
+}
+
+// isTracked tells whether the schema_version table exists.
+func (m *Migrator) isTracked() (bool, error) {
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'`
+	if m.adapter == postgresAdapter {
+		query = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_version'`
+	}
+
+	var count int
+	err := m.db.QueryRow(query).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Version returns the version of the latest applied migration, 0 if none.
+func (m *Migrator) Version() (int, error) {
+	tracked, err := m.isTracked()
+	if err != nil {
+		return 0, err
+	}
+	if !tracked {
+		return 0, nil
+	}
+
+	var version int
+	err = m.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Pending returns the migrations not applied yet, in the order to apply them.
+// It fails if the database has been migrated by a newer version of Banco.
+func (m *Migrator) Pending() ([]*Migration, error) {
+	version, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	if latest := m.latestVersion(); version > latest {
+		return nil, fmt.Errorf("database schema version %d is newer than the latest supported %d", version, latest)
+	}
+
+	pending := []*Migration{}
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies the pending migrations, each one in its own transaction, and
+// returns them.
+func (m *Migrator) Up() ([]*Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, migration := range pending {
+		err := m.apply(migration.Up, func(tx *sql.Tx) error {
+			_, err := tx.Exec(rebindQuery(m.adapter, `INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)`),
+				migration.Version, migration.Name, time.Now().UTC().Format("2006-01-02 15:04:05"))
+			return err
+		})
+		if err != nil {
+			return pending[:i], fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return pending, nil
+}
+
+// Down rolls back the latest applied migration and returns it.
+func (m *Migrator) Down() (*Migration, error) {
+	version, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("no migration to roll back")
+	}
+
+	var migration *Migration
+	for _, known := range m.migrations {
+		if known.Version == version {
+			migration = known
+		}
+	}
+	if migration == nil {
+		return nil, fmt.Errorf("database schema version %d is unknown to this version of Banco", version)
+	}
+
+	err = m.apply(migration.Down, func(tx *sql.Tx) error {
+		_, err := tx.Exec(rebindQuery(m.adapter, `DELETE FROM schema_version WHERE version = ?`), migration.Version)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rollback of migration %d %s: %w", migration.Version, migration.Name, err)
+	}
+	return migration, nil
+}
+
+func (m *Migrator) apply(script string, record func(tx *sql.Tx) error) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(script); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := record(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) latestVersion() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// runMigrate runs the banco migrate subcommand: up applies the pending
+// migrations, check lists them and fails if any, down rolls back the latest
+// applied one.
+func runMigrate(dbType, dsn string, args []string) error {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	db, err := openDatabase(dbType, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// check only reads the database, the other commands get it ready to be
+	// migrated
+	var migrator *Migrator
+	if action == "check" {
+		migrator, err = openMigrator(db, dbType)
+	} else {
+		migrator, err = NewMigrator(db, dbType)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Printf("applied migration %d %s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+	case "check":
+		if dbType == sqliteAdapter {
+			legacy, err := migrator.isLegacySQLite()
+			if err != nil {
+				return err
+			}
+			if legacy {
+				return fmt.Errorf("legacy schema, needs upgrade: run banco migrate up")
+			}
+		}
+		version, err := migrator.Version()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("database schema version %d\n", version)
+		pending, err := migrator.Pending()
+		if err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			fmt.Printf("pending migration %d %s\n", migration.Version, migration.Name)
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migrations", len(pending))
+		}
+	case "down":
+		migration, err := migrator.Down()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rolled back migration %d %s\n", migration.Version, migration.Name)
+	default:
+		return fmt.Errorf("unknown migrate command %s, expected up, check or down", action)
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vulpemventures/go-elements/network"
+)
+
+func TestMigrator(t *testing.T) {
+	db, err := openDatabase(sqliteAdapter, filepath.Join(t.TempDir(), "banco.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrator, err := NewMigrator(db, sqliteAdapter)
+	if err != nil {
+		t.Fatal("NewMigrator", err)
+	}
+	pending, err := migrator.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, pending, len(migrator.migrations))
+
+	applied, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Equal(t, pending, applied)
+	version, err := migrator.Version()
+	assert.NoError(t, err)
+	assert.Equal(t, migrator.latestVersion(), version)
+
+	applied, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	// roll back all the migrations and apply them again
+	for i := len(migrator.migrations) - 1; i >= 0; i-- {
+		migration, err := migrator.Down()
+		assert.NoError(t, err)
+		assert.Equal(t, migrator.migrations[i], migration)
+	}
+	_, err = migrator.Down()
+	assert.Error(t, err)
+	_, err = db.Exec(`SELECT id FROM orders`)
+	assert.Error(t, err)
+
+	applied, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(migrator.migrations))
+
+	// a database migrated by a newer version is left untouched
+	_, err = db.Exec(`INSERT INTO schema_version (version, name) VALUES (9999, 'future')`)
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.Error(t, err)
+}
+
+func TestMigrator_LegacySQLite(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "banco.db")
+	db, err := openDatabase(sqliteAdapter, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// schema of the databases created before the expiry leaf and the
+	// partial fills
+	statements := []string{
+		`CREATE TABLE orders (id TEXT PRIMARY KEY, timestamp TEXT, address TEXT, fulfill_script BLOB, refund_script BLOB, trader_script BLOB, input_asset TEXT, input_amount INTEGER UNSIGNED, output_asset TEXT, output_amount INTEGER UNSIGNED)`,
+		`CREATE TABLE order_statuses (id INTEGER PRIMARY KEY AUTOINCREMENT, order_id TEXT, status TEXT CHECK(status IN ('Pending', 'Fulfilled', 'Cancelled', 'Expired')), timestamp TEXT, tx_hash TEXT, FOREIGN KEY(order_id) REFERENCES orders(id))`,
+		`INSERT INTO orders (id, timestamp, address, input_asset, input_amount, output_asset, output_amount) VALUES ('legacy', '2024-01-01 10:00:00', 'tex1pq7vfr2ftdwsyuzqr05wz7y7x3dvf5w7y8w3jv5mlnhzwd3z5w4qsxkcxwk', 'asset', 1000, 'asset', 1010)`,
+		`INSERT INTO order_statuses (order_id, status, timestamp) VALUES ('legacy', 'Pending', '2024-01-01 10:00:00')`,
+	}
+	for _, statement := range statements {
+		_, err := db.Exec(statement)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	repo, err := NewSQLiteOrderRepository(filename)
+	if err != nil {
+		t.Fatal("NewSQLiteOrderRepository", err)
+	}
+	defer repo.Close()
+
+	order, status, err := repo.FetchOrderByID("legacy")
+	assert.NoError(t, err)
+	assert.Equal(t, "Pending", status)
+	// legacy orders expire 10 minutes after their creation
+	assert.Equal(t, order.Timestamp.Unix()+600, order.Expiry.Unix())
+
+	// the network of legacy orders is the one of their address
+	assert.True(t, order.IsOnNetwork(&network.Testnet))
+
+	// the new statuses are accepted
+	assert.NoError(t, repo.UpdateOrderStatus("legacy", "PartiallyFilled"))
+}
+
+func TestRunMigrate_Check(t *testing.T) {
+	schemaVersionTables := func(filename string) int {
+		db, err := openDatabase(sqliteAdapter, filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		var count int
+		err = db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'`).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	// a fresh database has all the migrations pending and is left untouched
+	filename := filepath.Join(t.TempDir(), "banco.db")
+	err := runMigrate(sqliteAdapter, filename, []string{"check"})
+	assert.ErrorContains(t, err, "pending migrations")
+	assert.Equal(t, 0, schemaVersionTables(filename))
+
+	assert.NoError(t, runMigrate(sqliteAdapter, filename, []string{"up"}))
+	assert.NoError(t, runMigrate(sqliteAdapter, filename, []string{"check"}))
+
+	// a legacy database is reported and not upgraded
+	filename = filepath.Join(t.TempDir(), "banco.db")
+	db, err := openDatabase(sqliteAdapter, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE orders (id TEXT PRIMARY KEY, timestamp TEXT, address TEXT, fulfill_script BLOB, refund_script BLOB, trader_script BLOB, input_asset TEXT, input_amount INTEGER UNSIGNED, output_asset TEXT, output_amount INTEGER UNSIGNED)`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = runMigrate(sqliteAdapter, filename, []string{"check"})
+	assert.ErrorContains(t, err, "legacy schema, needs upgrade")
+	assert.Equal(t, 0, schemaVersionTables(filename))
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS order_statuses_order_id;
+DROP TABLE IF EXISTS order_statuses;
+DROP TABLE IF EXISTS orders;
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS orders (
+	id TEXT PRIMARY KEY,
+	timestamp TEXT,
+	address TEXT,
+	fulfill_script BYTEA,
+	refund_script BYTEA,
+	trader_script BYTEA,
+	input_asset TEXT,
+	input_amount BIGINT,
+	output_asset TEXT,
+	output_amount BIGINT,
+	maker_pubkey BYTEA,
+	expiry BIGINT,
+	expiry_script BYTEA,
+	batch_fulfill_script BYTEA,
+	partial_fill_script BYTEA,
+	filled_amount BIGINT DEFAULT 0,
+	maker_blinding_key BYTEA,
+	blinding_key BYTEA,
+	asset_blinder BYTEA,
+	value_blinder BYTEA,
+	mismatched_deposits INTEGER DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS order_statuses (
+	id BIGSERIAL PRIMARY KEY,
+	order_id TEXT REFERENCES orders(id),
+	status TEXT CHECK(status IN ('Pending', 'Funded', 'Underfunded', 'Overfunded', 'WrongAsset', 'PartiallyFilled', 'Fulfilled', 'Cancelled', 'Expired')),
+	timestamp TEXT,
+	tx_hash TEXT
+);
+
+-- the current status of an order is the latest row of its timeline
+CREATE INDEX IF NOT EXISTS order_statuses_order_id ON order_statuses(order_id, id);
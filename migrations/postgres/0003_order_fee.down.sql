@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN fee_amount;
+ALTER TABLE orders DROP COLUMN fee_asset;
@@ -0,0 +1,3 @@
+-- the fee charged on the trade, unknown for the orders created so far
+ALTER TABLE orders ADD COLUMN fee_asset TEXT;
+ALTER TABLE orders ADD COLUMN fee_amount BIGINT DEFAULT 0;
@@ -0,0 +1,4 @@
+UPDATE order_statuses SET status = 'Expired' WHERE status = 'ExpiredEmpty';
+ALTER TABLE order_statuses DROP CONSTRAINT order_statuses_status_check;
+ALTER TABLE order_statuses ADD CONSTRAINT order_statuses_status_check
+	CHECK(status IN ('Pending', 'Funded', 'Underfunded', 'Overfunded', 'WrongAsset', 'PartiallyFilled', 'Fulfilled', 'Cancelled', 'Expired'));
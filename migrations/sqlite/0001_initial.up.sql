@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS orders (
+	id TEXT PRIMARY KEY,
+	timestamp TEXT,
+	address TEXT,
+	fulfill_script BLOB,
+	refund_script BLOB,
+	trader_script BLOB,
+	input_asset TEXT,
+	input_amount INTEGER UNSIGNED,
+	output_asset TEXT,
+	output_amount INTEGER UNSIGNED,
+	maker_pubkey BLOB,
+	expiry INTEGER,
+	expiry_script BLOB,
+	batch_fulfill_script BLOB,
+	partial_fill_script BLOB,
+	filled_amount INTEGER UNSIGNED DEFAULT 0,
+	maker_blinding_key BLOB,
+	blinding_key BLOB,
+	asset_blinder BLOB,
+	value_blinder BLOB,
+	mismatched_deposits INTEGER DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS order_statuses (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	order_id TEXT,
+	status TEXT CHECK(status IN ('Pending', 'Funded', 'Underfunded', 'Overfunded', 'WrongAsset', 'PartiallyFilled', 'Fulfilled', 'Cancelled', 'Expired')),
+	timestamp TEXT,
+	tx_hash TEXT,
+	FOREIGN KEY(order_id) REFERENCES orders(id)
+);
+
+-- the current status of an order is the latest row of its timeline
+CREATE INDEX IF NOT EXISTS order_statuses_order_id ON order_statuses(order_id, id);
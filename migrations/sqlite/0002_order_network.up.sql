@@ -0,0 +1,9 @@
+ALTER TABLE orders ADD COLUMN network TEXT;
+
+-- the network of the orders created so far is the one of their address
+UPDATE orders SET network = CASE
+	WHEN address LIKE 'tex1%' OR address LIKE 'tlq1%' THEN 'testnet'
+	WHEN address LIKE 'ert1%' OR address LIKE 'el1%' THEN 'regtest'
+	WHEN address LIKE 'ex1%' OR address LIKE 'lq1%' THEN 'liquid'
+END
+WHERE network IS NULL;
@@ -0,0 +1,15 @@
+-- expired orders with nothing to refund end up ExpiredEmpty, SQLite can't
+-- alter a CHECK constraint hence the table is rebuilt
+CREATE TABLE order_statuses_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	order_id TEXT,
+	status TEXT CHECK(status IN ('Pending', 'Funded', 'Underfunded', 'Overfunded', 'WrongAsset', 'PartiallyFilled', 'Fulfilled', 'Cancelled', 'Expired', 'ExpiredEmpty')),
+	timestamp TEXT,
+	tx_hash TEXT,
+	FOREIGN KEY(order_id) REFERENCES orders(id)
+);
+INSERT INTO order_statuses_new (id, order_id, status, timestamp, tx_hash)
+SELECT id, order_id, status, timestamp, tx_hash FROM order_statuses;
+DROP TABLE order_statuses;
+ALTER TABLE order_statuses_new RENAME TO order_statuses;
+CREATE INDEX IF NOT EXISTS order_statuses_order_id ON order_statuses(order_id, id);
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
+	"log"
 
 	"github.com/vulpemventures/go-elements/address"
 	"github.com/vulpemventures/go-elements/elementsutil"
@@ -13,11 +14,10 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
-const MSATS_PER_BYTE = 110
-
 type service struct {
 	addr          string
 	accountName   string
+	confidential  bool
 	conn          *grpc.ClientConn
 	walletClient  pb.WalletServiceClient
 	accountClient pb.AccountServiceClient
@@ -33,7 +33,6 @@ type WalletService interface {
 	SignPset(
 		ctx context.Context, pset string, extractRawTx bool,
 	) (string, error)
-	Transfer(ctx context.Context, outs []TxOutput) (string, error)
 	BroadcastTransaction(ctx context.Context, txHex string) (string, error)
 	TransactionNotifications(ctx context.Context) (<-chan *TransactionNotification, error)
 	WatchScript(ctx context.Context, script string) error
@@ -54,11 +53,6 @@ type TxInput interface {
 	GetWitnessSize() int
 }
 
-type TxOutput interface {
-	GetAmount() uint64
-	GetAsset() string
-	GetScript() string
-}
 type Utxo interface {
 	GetTxid() string
 	GetIndex() uint32
@@ -92,16 +86,24 @@ func (w walletStatus) IsSynced() bool {
 }
 
 type UTXO struct {
-	Txid    string `json:"txid"`
-	Index   int    `json:"index"`
-	Value   uint64 `json:"value"`
-	Prevout *transaction.TxOutput
-	Status  struct {
+	Txid            string `json:"txid"`
+	Index           int    `json:"vout"`
+	Value           uint64 `json:"value"`
+	Asset           string `json:"asset"`
+	ValueCommitment string `json:"valuecommitment"`
+	AssetCommitment string `json:"assetcommitment"`
+	AssetBlinder    []byte `json:"-"`
+	ValueBlinder    []byte `json:"-"`
+	Prevout         *transaction.TxOutput
+	Status          struct {
 		Confirmed bool `json:"confirmed"`
 	} `json:"status"`
 }
 
-func NewWalletService(addr, accountName string) (WalletService, error) {
+// NewWalletService connects to Ocean and creates the given account if needed.
+// Confidential accounts derive blinded addresses, required to trade
+// confidential orders.
+func NewWalletService(addr, accountName string, confidential bool) (WalletService, error) {
 	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		return nil, err
@@ -113,6 +115,7 @@ func NewWalletService(addr, accountName string) (WalletService, error) {
 	svc := &service{
 		addr:          addr,
 		accountName:   accountName,
+		confidential:  confidential,
 		conn:          conn,
 		walletClient:  walletClient,
 		accountClient: accountClient,
@@ -144,7 +147,7 @@ func NewWalletService(addr, accountName string) (WalletService, error) {
 	if !found {
 		if _, err := accountClient.CreateAccountBIP44(ctx, &pb.CreateAccountBIP44Request{
 			Label:          svc.accountName,
-			Unconfidential: true,
+			Unconfidential: !confidential,
 		}); err != nil {
 			return nil, err
 		}
@@ -231,6 +234,7 @@ func (s *service) GetAddress(
 
 type TransactionNotification struct {
 	TxId      string
+	TxHex     string
 	Confirmed bool
 	Timestamp int64
 }
@@ -250,15 +254,19 @@ func (s *service) TransactionNotifications(ctx context.Context) (<-chan *Transac
 
 	// Start a goroutine to receive notifications from the Notification RPC and send them to the channel
 	go func() {
+		defer close(notifChan)
 		for {
 			resp, err := notifStream.Recv()
 			if err != nil {
-				// Handle error...
-				break
+				if ctx.Err() == nil {
+					log.Printf("transaction notifications stream closed: %v", err)
+				}
+				return
 			}
 
 			notif := &TransactionNotification{
 				TxId:      resp.GetTxid(),
+				TxHex:     resp.GetTxhex(),
 				Confirmed: false,
 				Timestamp: 0,
 			}
@@ -269,7 +277,12 @@ func (s *service) TransactionNotifications(ctx context.Context) (<-chan *Transac
 				notif.Timestamp = blockDetails.GetTimestamp()
 			}
 
-			notifChan <- notif
+			// the stream ends along with the context, stop if nobody reads
+			select {
+			case notifChan <- notif:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}()
 
@@ -298,15 +311,30 @@ func (s *service) SelectUtxos(
 	}
 	utxos := make([]UTXO, len(res.GetUtxos()))
 	for i, utxo := range res.GetUtxos() {
-		assetBytes, _ := elementsutil.AssetHashToBytes(utxo.Asset)
-		valueBytes, _ := elementsutil.ValueToBytes(utxo.Value)
-		scritpBytes, _ := hex.DecodeString(utxo.Script)
-		prevout := transaction.NewTxOutput(assetBytes, valueBytes, scritpBytes)
+		assetBlinder, _ := hex.DecodeString(utxo.GetAssetBlinder())
+		valueBlinder, _ := hex.DecodeString(utxo.GetValueBlinder())
+
+		var prevout *transaction.TxOutput
+		if isBlinded(assetBlinder, valueBlinder) {
+			// the prevout of blinded utxos must carry commitments and proofs
+			prevout, err = s.fetchPrevout(ctx, utxo.GetTxid(), utxo.GetIndex())
+			if err != nil {
+				return nil, 0, err
+			}
+		} else {
+			assetBytes, _ := elementsutil.AssetHashToBytes(utxo.Asset)
+			valueBytes, _ := elementsutil.ValueToBytes(utxo.Value)
+			scritpBytes, _ := hex.DecodeString(utxo.Script)
+			prevout = transaction.NewTxOutput(assetBytes, valueBytes, scritpBytes)
+		}
 		utxos[i] = UTXO{
-			Txid:    utxo.GetTxid(),
-			Index:   int(utxo.GetIndex()),
-			Value:   utxo.GetValue(),
-			Prevout: prevout,
+			Txid:         utxo.GetTxid(),
+			Index:        int(utxo.GetIndex()),
+			Value:        utxo.GetValue(),
+			Asset:        utxo.GetAsset(),
+			AssetBlinder: assetBlinder,
+			ValueBlinder: valueBlinder,
+			Prevout:      prevout,
 			Status: struct {
 				Confirmed bool `json:"confirmed"`
 			}{
@@ -317,6 +345,30 @@ func (s *service) SelectUtxos(
 	return utxos, res.GetChange(), nil
 }
 
+func (s *service) fetchPrevout(ctx context.Context, txid string, index uint32) (*transaction.TxOutput, error) {
+	res, err := s.txClient.GetTransaction(ctx, &pb.GetTransactionRequest{Txid: txid})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction %s: %w", txid, err)
+	}
+	tx, err := transaction.NewTxFromHex(res.GetTxHex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse transaction %s: %w", txid, err)
+	}
+	if int(index) >= len(tx.Outputs) {
+		return nil, fmt.Errorf("output %d not found in transaction %s", index, txid)
+	}
+	return tx.Outputs[index], nil
+}
+
+func isBlinded(assetBlinder, valueBlinder []byte) bool {
+	for _, b := range append(assetBlinder, valueBlinder...) {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *service) SignPset(
 	ctx context.Context, pset string, extractRawTx bool,
 ) (string, error) {
@@ -330,20 +382,6 @@ func (s *service) SignPset(
 	return signedPset, nil
 }
 
-func (s *service) Transfer(
-	ctx context.Context, outs []TxOutput,
-) (string, error) {
-	res, err := s.txClient.Transfer(ctx, &pb.TransferRequest{
-		AccountName:      s.accountName,
-		Receivers:        outputList(outs).toProto(),
-		MillisatsPerByte: MSATS_PER_BYTE,
-	})
-	if err != nil {
-		return "", err
-	}
-	return res.GetTxHex(), nil
-}
-
 func (s *service) BroadcastTransaction(
 	ctx context.Context, txHex string,
 ) (string, error) {
@@ -357,17 +395,3 @@ func (s *service) BroadcastTransaction(
 	}
 	return res.GetTxid(), nil
 }
-
-type outputList []TxOutput
-
-func (l outputList) toProto() []*pb.Output {
-	list := make([]*pb.Output, 0, len(l))
-	for _, out := range l {
-		list = append(list, &pb.Output{
-			Amount: out.GetAmount(),
-			Script: out.GetScript(),
-			Asset:  out.GetAsset(),
-		})
-	}
-	return list
-}
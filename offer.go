@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/vulpemventures/go-elements/network"
@@ -13,34 +14,66 @@ import (
 )
 
 const (
+	OP_INSPECTINPUTASSET         = 0xc8
+	OP_INSPECTINPUTVALUE         = 0xc9
+	OP_INSPECTINPUTSCRIPTPUBKEY  = 0xca
 	OP_INSPECTOUTPUTSCRIPTPUBKEY = 0xd1
 	OP_INSPECTOUTPUTASSET        = 0xce
 	OP_INSPECTOUTPUTVALUE        = 0xcf
 	OP_PUSHCURRENTINPUTINDEX     = 0xcd
+	OP_SUB64                     = 0xd8
+	OP_MUL64                     = 0xd9
+	OP_LESSTHANOREQUAL64         = 0xdd
+	OP_GREATERTHAN64             = 0xde
 	UNSPENDABLE_POINT            = "0250929b74c1a04954b78b4b6035e97a5e078a5a0f28ec96d547bfee9ace803ac0"
 )
 
-func CreateFundingOutput(fulfillScript []byte, refundScript []byte, net *network.Network) (*payment.Payment, error) {
+// CreateFundingOutput returns the taproot payment of the trade contract.
+// The partial fill, expiry and batch fulfill scripts, if given, are added as
+// further leaves. The fulfill script can be left out for contracts fulfilled
+// only via the batch fulfill leaf.
+// The maker x-only public key, if given, is used as internal key to let the
+// maker cancel via key-path, otherwise the key-path is made unspendable.
+// The blinding public key, if given, makes the contract address confidential.
+func CreateFundingOutput(fulfillScript []byte, refundScript []byte, partialFillScript []byte, expiryScript []byte, batchFulfillScript []byte, makerPubKey []byte, blindingPubKey []byte, net *network.Network) (*payment.Payment, error) {
 	if net == nil {
 		net = &network.Liquid
 	}
 
-	unspendableKeyBytes, err := hex.DecodeString(UNSPENDABLE_POINT)
+	internalKey, err := fundingInternalKey(makerPubKey)
 	if err != nil {
-		return nil, fmt.Errorf("error decoding unspendable key bytes: %w", err)
+		return nil, err
 	}
 
-	unspendableKey, err := secp256k1.ParsePubKey(unspendableKeyBytes)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing unspendable key: %w", err)
+	var blindingKey *secp256k1.PublicKey
+	if len(blindingPubKey) > 0 {
+		blindingKey, err = secp256k1.ParsePubKey(blindingPubKey)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing blinding key: %w", err)
+		}
+	}
+
+	if len(fulfillScript) == 0 && len(batchFulfillScript) == 0 {
+		return nil, fmt.Errorf("missing fulfill script")
 	}
 
-	leafTaprootTree := taproot.AssembleTaprootScriptTree(
-		taproot.NewBaseTapElementsLeaf(fulfillScript),
-		taproot.NewBaseTapElementsLeaf(refundScript),
-	)
+	leaves := []taproot.TapElementsLeaf{}
+	if len(fulfillScript) > 0 {
+		leaves = append(leaves, taproot.NewBaseTapElementsLeaf(fulfillScript))
+	}
+	leaves = append(leaves, taproot.NewBaseTapElementsLeaf(refundScript))
+	if len(partialFillScript) > 0 {
+		leaves = append(leaves, taproot.NewBaseTapElementsLeaf(partialFillScript))
+	}
+	if len(expiryScript) > 0 {
+		leaves = append(leaves, taproot.NewBaseTapElementsLeaf(expiryScript))
+	}
+	if len(batchFulfillScript) > 0 {
+		leaves = append(leaves, taproot.NewBaseTapElementsLeaf(batchFulfillScript))
+	}
+	leafTaprootTree := taproot.AssembleTaprootScriptTree(leaves...)
 
-	payment, err := payment.FromTaprootScriptTree(unspendableKey, leafTaprootTree, net, nil)
+	payment, err := payment.FromTaprootScriptTree(internalKey, leafTaprootTree, net, blindingKey)
 	if err != nil {
 		return nil, fmt.Errorf("error creating payment from taproot script tree: %w", err)
 	}
@@ -48,6 +81,27 @@ func CreateFundingOutput(fulfillScript []byte, refundScript []byte, net *network
 	return payment, nil
 }
 
+func fundingInternalKey(makerPubKey []byte) (*secp256k1.PublicKey, error) {
+	if len(makerPubKey) > 0 {
+		makerKey, err := schnorr.ParsePubKey(makerPubKey)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing maker public key: %w", err)
+		}
+		return makerKey, nil
+	}
+
+	unspendableKeyBytes, err := hex.DecodeString(UNSPENDABLE_POINT)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding unspendable key bytes: %w", err)
+	}
+
+	unspendableKey, err := secp256k1.ParsePubKey(unspendableKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing unspendable key: %w", err)
+	}
+	return unspendableKey, nil
+}
+
 func FulfillScript(recipientScript []byte, outputAmount uint64, outputAsset []byte) ([]byte, error) {
 	var scriptVersion int
 	switch recipientScript[0] {
@@ -69,6 +123,58 @@ func FulfillScript(recipientScript []byte, outputAmount uint64, outputAsset []by
 	return fulfillScript, nil
 }
 
+// BatchFulfillScript is the FulfillScript counterpart that checks the output
+// at twice the index of the spending input instead of the first one, so that
+// many contracts can be fulfilled in the same transaction.
+func BatchFulfillScript(recipientScript []byte, outputAmount uint64, outputAsset []byte) ([]byte, error) {
+	var scriptVersion int
+	switch recipientScript[0] {
+	case 0x4f:
+		scriptVersion = -1 // OP_1NEGATE
+	case 0x00:
+		scriptVersion = 0 // OP_0
+	case 0x51:
+		scriptVersion = 1 // OP_1
+	default:
+		return nil, fmt.Errorf("unknown script version")
+	}
+
+	scriptProgram := recipientScript[2:]
+	batchFulfillScript, err := compileBatchFulfillClause(scriptVersion, scriptProgram, outputAmount, outputAsset)
+	if err != nil {
+		return nil, fmt.Errorf("error building the batch fulfill script: %w", err)
+	}
+	return batchFulfillScript, nil
+}
+
+// ConfidentialFulfillScript is the FulfillScript counterpart for confidential
+// contracts: the first output must have the given asset and value commitments
+// instead of an explicit asset and value.
+func ConfidentialFulfillScript(recipientScript []byte, assetCommitment []byte, valueCommitment []byte) ([]byte, error) {
+	var scriptVersion int
+	switch recipientScript[0] {
+	case 0x4f:
+		scriptVersion = -1 // OP_1NEGATE
+	case 0x00:
+		scriptVersion = 0 // OP_0
+	case 0x51:
+		scriptVersion = 1 // OP_1
+	default:
+		return nil, fmt.Errorf("unknown script version")
+	}
+
+	scriptProgram := recipientScript[2:]
+	fulfillScript, err := compileConfidentialFulfillClause(0, scriptVersion, scriptProgram, assetCommitment, valueCommitment)
+	if err != nil {
+		return nil, fmt.Errorf("error building the confidential fulfill script: %w", err)
+	}
+	return fulfillScript, nil
+}
+
+// RefundScript returns the leaf that lets anyone return the contract unspent
+// to the recipient. The output at twice the index of the spending input must
+// pay back the order amount, so that each contract input is refunded on its
+// own.
 func RefundScript(recipientScript []byte, inputAmount uint64, inputAsset []byte) ([]byte, error) {
 	// TODO properly check the scritp version type like FulfillScript
 	var scriptVersion int
@@ -83,13 +189,99 @@ func RefundScript(recipientScript []byte, inputAmount uint64, inputAsset []byte)
 		return nil, fmt.Errorf("unknown script version")
 	}
 	scriptProgram := recipientScript[2:]
-	refundScript, err := compileRefundClause(0, scriptVersion, scriptProgram, inputAmount, inputAsset)
+	refundScript, err := compileRefundClause(scriptVersion, scriptProgram, inputAmount, inputAsset)
 	if err != nil {
 		return nil, fmt.Errorf("error building the refund script: %w", err)
 	}
 	return refundScript, nil
 }
 
+// PartialFillScript returns the leaf that lets a taker fill any fraction of
+// the contract unspent at the fixed price outputAmount/inputAmount. The output
+// at twice the index of the spending input pays the output asset to the
+// recipient, while the remainder of the input asset, if any, goes back to the
+// contract in the next one, so that each contract input is paid for on its own.
+func PartialFillScript(recipientScript []byte, inputAmount uint64, inputAsset []byte, outputAmount uint64, outputAsset []byte) ([]byte, error) {
+	var scriptVersion int
+	switch recipientScript[0] {
+	case 0x4f:
+		scriptVersion = -1 // OP_1NEGATE
+	case 0x00:
+		scriptVersion = 0 // OP_0
+	case 0x51:
+		scriptVersion = 1 // OP_1
+	default:
+		return nil, fmt.Errorf("unknown script version")
+	}
+
+	scriptProgram := recipientScript[2:]
+	priceNum, priceDen := priceRatio(outputAmount, inputAmount)
+	partialFillScript, err := compilePartialFillClause(scriptVersion, scriptProgram, inputAsset, outputAsset, priceNum, priceDen)
+	if err != nil {
+		return nil, fmt.Errorf("error building the partial fill script: %w", err)
+	}
+	return partialFillScript, nil
+}
+
+// PartialRefundScript is the refund leaf of partial fill contracts: the output
+// at twice the index of the spending input must return the whole value of the
+// spent contract unspent, which is less than the order amount once partially
+// filled. It's also the base of the expiry leaf, refunding any deposit.
+func PartialRefundScript(recipientScript []byte, inputAsset []byte) ([]byte, error) {
+	var scriptVersion int
+	switch recipientScript[0] {
+	case 0x4f:
+		scriptVersion = -1 // OP_1NEGATE
+	case 0x00:
+		scriptVersion = 0 // OP_0
+	case 0x51:
+		scriptVersion = 1 // OP_1
+	default:
+		return nil, fmt.Errorf("unknown script version")
+	}
+
+	scriptProgram := recipientScript[2:]
+	refundScript, err := compilePartialRefundClause(scriptVersion, scriptProgram, inputAsset)
+	if err != nil {
+		return nil, fmt.Errorf("error building the partial refund script: %w", err)
+	}
+	return refundScript, nil
+}
+
+// ExpiryScript returns the leaf that lets anyone spend the contract unspents
+// via the given refund script once the expiry, a unix timestamp, is reached.
+// The spending transaction must have its locktime set to the expiry.
+func ExpiryScript(refundScript []byte, expiry uint32) ([]byte, error) {
+	if expiry < txscript.LockTimeThreshold {
+		return nil, fmt.Errorf("expiry must be a unix timestamp, got %d", expiry)
+	}
+
+	builder := txscript.NewScriptBuilder()
+	builder.AddInt64(int64(expiry))
+	builder.AddOp(txscript.OP_CHECKLOCKTIMEVERIFY)
+	builder.AddOp(txscript.OP_DROP)
+	builder.AddOps(refundScript)
+
+	expiryScript, err := builder.Script()
+	if err != nil {
+		return nil, fmt.Errorf("error building the expiry script: %w", err)
+	}
+	return expiryScript, nil
+}
+
+// priceRatio reduces outputAmount/inputAmount to lowest terms, to keep the
+// products computed by the partial fill script far from overflowing.
+func priceRatio(outputAmount, inputAmount uint64) (uint64, uint64) {
+	a, b := outputAmount, inputAmount
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if a == 0 {
+		return outputAmount, inputAmount
+	}
+	return outputAmount / a, inputAmount / a
+}
+
 func compileFulfillClause(outputIndex uint64, traderFulfillScriptVersion int, traderFulfillScriptProgram []byte, outputAmount uint64, outputAsset []byte) ([]byte, error) {
 
 	index := scriptNum(outputIndex).Bytes()
@@ -127,8 +319,52 @@ func compileFulfillClause(outputIndex uint64, traderFulfillScriptVersion int, tr
 	return script, nil
 }
 
-func compileRefundClause(outputIndex uint64, traderRefundScriptVersion int, traderRefundScriptProgram []byte, inputAmount uint64, inputAsset []byte) ([]byte, error) {
-	index := scriptNum(outputIndex).Bytes()
+// addPaymentIndex pushes the index of the output paying for the spending
+// input, twice its index. The next output is left to the remainder of partial
+// fills, so that the outputs checked by different contract inputs never
+// overlap whatever leaf spends them.
+func addPaymentIndex(builder *txscript.ScriptBuilder) {
+	builder.AddOp(OP_PUSHCURRENTINPUTINDEX)
+	builder.AddOp(txscript.OP_DUP)
+	builder.AddOp(txscript.OP_ADD)
+}
+
+func compileBatchFulfillClause(traderFulfillScriptVersion int, traderFulfillScriptProgram []byte, outputAmount uint64, outputAsset []byte) ([]byte, error) {
+	scriptVersion := scriptNum(traderFulfillScriptVersion).Bytes()
+	assetBuffer := outputAsset[1:]
+	amountBuffer := make([]byte, 8)
+	binary.LittleEndian.PutUint64(amountBuffer, outputAmount)
+
+	builder := txscript.NewScriptBuilder()
+
+	addPaymentIndex(builder)
+	builder.AddOp(OP_INSPECTOUTPUTSCRIPTPUBKEY)
+	builder.AddData(scriptVersion)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddData(traderFulfillScriptProgram)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+
+	addPaymentIndex(builder)
+	builder.AddOp(OP_INSPECTOUTPUTASSET)
+	builder.AddOp(txscript.OP_DROP)
+	builder.AddData(assetBuffer)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+
+	addPaymentIndex(builder)
+	builder.AddOp(OP_INSPECTOUTPUTVALUE)
+	builder.AddOp(txscript.OP_DROP)
+	builder.AddData(amountBuffer)
+	builder.AddOp(txscript.OP_EQUAL)
+
+	script, err := builder.Script()
+	if err != nil {
+		return nil, err
+	}
+
+	return script, nil
+}
+
+func compileRefundClause(traderRefundScriptVersion int, traderRefundScriptProgram []byte, inputAmount uint64, inputAsset []byte) ([]byte, error) {
 	scriptVersion := scriptNum(traderRefundScriptVersion).Bytes()
 	assetBuffer := inputAsset[1:]
 	amountBuffer := make([]byte, 8)
@@ -136,20 +372,22 @@ func compileRefundClause(outputIndex uint64, traderRefundScriptVersion int, trad
 
 	builder := txscript.NewScriptBuilder()
 
-	builder.AddData(index)
+	// relative to the spending input, otherwise a single output could refund
+	// many unspents of the same contract
+	addPaymentIndex(builder)
 	builder.AddOp(OP_INSPECTOUTPUTSCRIPTPUBKEY)
 	builder.AddData(scriptVersion)
 	builder.AddOp(txscript.OP_EQUALVERIFY)
 	builder.AddData(traderRefundScriptProgram)
 	builder.AddOp(txscript.OP_EQUALVERIFY)
 
-	builder.AddData(index)
+	addPaymentIndex(builder)
 	builder.AddOp(OP_INSPECTOUTPUTASSET)
 	builder.AddOp(txscript.OP_DROP)
 	builder.AddData(assetBuffer)
 	builder.AddOp(txscript.OP_EQUALVERIFY)
 
-	builder.AddData(index)
+	addPaymentIndex(builder)
 	builder.AddOp(OP_INSPECTOUTPUTVALUE)
 	builder.AddOp(txscript.OP_DROP)
 	builder.AddData(amountBuffer)
@@ -162,3 +400,182 @@ func compileRefundClause(outputIndex uint64, traderRefundScriptVersion int, trad
 
 	return script, nil
 }
+
+func compileConfidentialFulfillClause(outputIndex uint64, traderFulfillScriptVersion int, traderFulfillScriptProgram []byte, assetCommitment []byte, valueCommitment []byte) ([]byte, error) {
+	if len(assetCommitment) != 33 || len(valueCommitment) != 33 {
+		return nil, fmt.Errorf("asset and value commitments must be 33 bytes")
+	}
+
+	index := scriptNum(outputIndex).Bytes()
+	scriptVersion := scriptNum(traderFulfillScriptVersion).Bytes()
+
+	builder := txscript.NewScriptBuilder()
+
+	builder.AddData(index)
+	builder.AddOp(OP_INSPECTOUTPUTSCRIPTPUBKEY)
+	builder.AddData(scriptVersion)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddData(traderFulfillScriptProgram)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+
+	// the prefix of the commitment is pushed on top of the stack
+	builder.AddData(index)
+	builder.AddOp(OP_INSPECTOUTPUTASSET)
+	builder.AddData(assetCommitment[:1])
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddData(assetCommitment[1:])
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+
+	builder.AddData(index)
+	builder.AddOp(OP_INSPECTOUTPUTVALUE)
+	builder.AddData(valueCommitment[:1])
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddData(valueCommitment[1:])
+	builder.AddOp(txscript.OP_EQUAL)
+
+	script, err := builder.Script()
+	if err != nil {
+		return nil, err
+	}
+
+	return script, nil
+}
+
+func compilePartialFillClause(traderScriptVersion int, traderScriptProgram []byte, inputAsset []byte, outputAsset []byte, priceNum uint64, priceDen uint64) ([]byte, error) {
+	scriptVersion := scriptNum(traderScriptVersion).Bytes()
+	explicitPrefix := []byte{0x01}
+	le64 := func(n uint64) []byte {
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, n)
+		return buf
+	}
+
+	builder := txscript.NewScriptBuilder()
+
+	// the outputs checked are relative to the spending input, otherwise many
+	// contract inputs could share the same payment and remainder
+	remainderIndex := func() {
+		addPaymentIndex(builder)
+		builder.AddOp(txscript.OP_1ADD)
+	}
+
+	// the output at twice the input index pays the output asset to the trader
+	addPaymentIndex(builder)
+	builder.AddOp(OP_INSPECTOUTPUTSCRIPTPUBKEY)
+	builder.AddData(scriptVersion)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddData(traderScriptProgram)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+
+	addPaymentIndex(builder)
+	builder.AddOp(OP_INSPECTOUTPUTASSET)
+	builder.AddOp(txscript.OP_DROP)
+	builder.AddData(outputAsset[1:])
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+
+	// the spent unspent holds the explicit input asset
+	builder.AddOp(OP_PUSHCURRENTINPUTINDEX)
+	builder.AddOp(OP_INSPECTINPUTASSET)
+	builder.AddData(explicitPrefix)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddData(inputAsset[1:])
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+
+	// the remainder is the value of the next output if it goes back to the
+	// contract, zero otherwise
+	builder.AddOp(OP_PUSHCURRENTINPUTINDEX)
+	builder.AddOp(OP_INSPECTINPUTSCRIPTPUBKEY)
+	remainderIndex()
+	builder.AddOp(OP_INSPECTOUTPUTSCRIPTPUBKEY)
+	builder.AddOp(txscript.OP_ROT)
+	builder.AddOp(txscript.OP_EQUAL)
+	builder.AddOp(txscript.OP_ROT)
+	builder.AddOp(txscript.OP_ROT)
+	builder.AddOp(txscript.OP_EQUAL)
+	builder.AddOp(txscript.OP_BOOLAND)
+	builder.AddOp(txscript.OP_IF)
+	remainderIndex()
+	builder.AddOp(OP_INSPECTOUTPUTASSET)
+	builder.AddData(explicitPrefix)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddData(inputAsset[1:])
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	remainderIndex()
+	builder.AddOp(OP_INSPECTOUTPUTVALUE)
+	builder.AddData(explicitPrefix)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddOp(txscript.OP_ELSE)
+	builder.AddData(le64(0))
+	builder.AddOp(txscript.OP_ENDIF)
+
+	// taken = input value - remainder, more than zero not to let anyone spend
+	// the contract for free
+	builder.AddOp(OP_PUSHCURRENTINPUTINDEX)
+	builder.AddOp(OP_INSPECTINPUTVALUE)
+	builder.AddData(explicitPrefix)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddOp(txscript.OP_SWAP)
+	builder.AddOp(OP_SUB64)
+	builder.AddOp(txscript.OP_VERIFY)
+	builder.AddOp(txscript.OP_DUP)
+	builder.AddData(le64(0))
+	builder.AddOp(OP_GREATERTHAN64)
+	builder.AddOp(txscript.OP_VERIFY)
+
+	// taken * priceNum <= paid * priceDen
+	builder.AddData(le64(priceNum))
+	builder.AddOp(OP_MUL64)
+	builder.AddOp(txscript.OP_VERIFY)
+	addPaymentIndex(builder)
+	builder.AddOp(OP_INSPECTOUTPUTVALUE)
+	builder.AddData(explicitPrefix)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddData(le64(priceDen))
+	builder.AddOp(OP_MUL64)
+	builder.AddOp(txscript.OP_VERIFY)
+	builder.AddOp(OP_LESSTHANOREQUAL64)
+
+	script, err := builder.Script()
+	if err != nil {
+		return nil, err
+	}
+
+	return script, nil
+}
+
+func compilePartialRefundClause(traderScriptVersion int, traderScriptProgram []byte, inputAsset []byte) ([]byte, error) {
+	scriptVersion := scriptNum(traderScriptVersion).Bytes()
+
+	builder := txscript.NewScriptBuilder()
+
+	// relative to the spending input, otherwise a single output could refund
+	// many unspents of the same value
+	addPaymentIndex(builder)
+	builder.AddOp(OP_INSPECTOUTPUTSCRIPTPUBKEY)
+	builder.AddData(scriptVersion)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddData(traderScriptProgram)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+
+	addPaymentIndex(builder)
+	builder.AddOp(OP_INSPECTOUTPUTASSET)
+	builder.AddOp(txscript.OP_DROP)
+	builder.AddData(inputAsset[1:])
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+
+	// the output returns the whole value of the spent unspent
+	addPaymentIndex(builder)
+	builder.AddOp(OP_INSPECTOUTPUTVALUE)
+	builder.AddOp(OP_PUSHCURRENTINPUTINDEX)
+	builder.AddOp(OP_INSPECTINPUTVALUE)
+	builder.AddOp(txscript.OP_ROT)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddOp(txscript.OP_EQUAL)
+
+	script, err := builder.Script()
+	if err != nil {
+		return nil, err
+	}
+
+	return script, nil
+}
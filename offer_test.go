@@ -1,16 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
 	"testing"
 
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/vulpemventures/go-elements/address"
 	"github.com/vulpemventures/go-elements/elementsutil"
 	"github.com/vulpemventures/go-elements/network"
 	"github.com/vulpemventures/go-elements/payment"
 	"github.com/vulpemventures/go-elements/psetv2"
 	"github.com/vulpemventures/go-elements/taproot"
+	"github.com/vulpemventures/go-elements/transaction"
 )
 
 var traderPubKeyBytes = []byte{0x2, 0x26, 0xb7, 0xb1, 0xc5, 0xd9, 0xe6, 0xf7, 0xa1, 0x46, 0xc5, 0xa1, 0x1a, 0x3d, 0xf, 0x36, 0x5b, 0xe1, 0xc5, 0x73, 0x6d, 0xce, 0xc6, 0x12, 0xe2, 0xac, 0xeb, 0x6d, 0x45, 0xcd, 0x22, 0x29, 0x89}
@@ -40,7 +47,7 @@ func TestCreateFundingOutput(t *testing.T) {
 	fulfillScript, _ := FulfillScript(traderPayment.Script, outputAmount, outputAsset)
 	refundScript, _ := RefundScript(traderPayment.Script, inputAmount, inputAsset)
 
-	output, err := CreateFundingOutput(fulfillScript, refundScript, &network.Testnet)
+	output, err := CreateFundingOutput(fulfillScript, refundScript, nil, nil, nil, nil, nil, &network.Testnet)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -50,7 +57,7 @@ func TestCreateFundingOutput(t *testing.T) {
 		t.Fatal(err)
 	}
 	assert.NoError(t, err)
-	assert.Equal(t, "tex1p76lwg57r3xshexvqyd06g927sc6yf62fzgdnxdk56cy43m6ru8xquqhyvp", addr)
+	assert.Equal(t, "tex1pvrhjauk266egrpxwagln3wewqwxjjv3n765jsgyfapusccm3hfps4gm68x", addr)
 }
 
 func TestFulfillTransaction(t *testing.T) {
@@ -293,3 +300,537 @@ func TestTapscriptSpend(t *testing.T) {
 }
 
 */
+
+// leafEngine interprets the subset of the Elements tapscript used by the
+// contract leaves, to check them against the spending transactions.
+type leafEngine struct {
+	tx         *transaction.Transaction
+	prevouts   []*transaction.TxOutput
+	inputIndex int
+	stack      [][]byte
+}
+
+// evalLeaf runs the leaf script spending the input at inputIndex of tx, whose
+// prevouts are given, and fails unless it leaves a single true value.
+func evalLeaf(script []byte, tx *transaction.Transaction, prevouts []*transaction.TxOutput, inputIndex int) error {
+	e := &leafEngine{tx: tx, prevouts: prevouts, inputIndex: inputIndex}
+	conditions := []bool{}
+	executing := func() bool {
+		for _, c := range conditions {
+			if !c {
+				return false
+			}
+		}
+		return true
+	}
+
+	tokenizer := txscript.MakeScriptTokenizer(0, script)
+	for tokenizer.Next() {
+		op := tokenizer.Opcode()
+		switch op {
+		case txscript.OP_IF:
+			condition := false
+			if executing() {
+				data, err := e.pop()
+				if err != nil {
+					return err
+				}
+				condition = castToBool(data)
+			}
+			conditions = append(conditions, condition)
+			continue
+		case txscript.OP_ELSE:
+			if len(conditions) == 0 {
+				return fmt.Errorf("unbalanced conditional")
+			}
+			conditions[len(conditions)-1] = !conditions[len(conditions)-1]
+			continue
+		case txscript.OP_ENDIF:
+			if len(conditions) == 0 {
+				return fmt.Errorf("unbalanced conditional")
+			}
+			conditions = conditions[:len(conditions)-1]
+			continue
+		}
+		if !executing() {
+			continue
+		}
+		if err := e.step(op, tokenizer.Data()); err != nil {
+			return fmt.Errorf("opcode %#x: %w", op, err)
+		}
+	}
+	if err := tokenizer.Err(); err != nil {
+		return err
+	}
+	if len(conditions) > 0 {
+		return fmt.Errorf("unbalanced conditional")
+	}
+	if len(e.stack) != 1 || !castToBool(e.stack[0]) {
+		return fmt.Errorf("script evaluated to false, stack %x", e.stack)
+	}
+	return nil
+}
+
+func (e *leafEngine) step(op byte, data []byte) error {
+	switch {
+	case op == txscript.OP_0:
+		e.push(nil)
+		return nil
+	case op >= txscript.OP_DATA_1 && op <= txscript.OP_PUSHDATA4:
+		e.push(data)
+		return nil
+	case op == txscript.OP_1NEGATE:
+		e.push(scriptNum(-1).Bytes())
+		return nil
+	case op >= txscript.OP_1 && op <= txscript.OP_16:
+		e.push(scriptNum(op - txscript.OP_1 + 1).Bytes())
+		return nil
+	}
+
+	switch op {
+	case txscript.OP_DUP:
+		a, err := e.pop()
+		if err != nil {
+			return err
+		}
+		e.push(a)
+		e.push(a)
+	case txscript.OP_DROP:
+		_, err := e.pop()
+		return err
+	case txscript.OP_SWAP, txscript.OP_ROT:
+		n := 2
+		if op == txscript.OP_ROT {
+			n = 3
+		}
+		if len(e.stack) < n {
+			return fmt.Errorf("stack underflow")
+		}
+		top := len(e.stack) - n
+		e.stack = append(append(e.stack[:top:top], e.stack[top+1:]...), e.stack[top])
+	case txscript.OP_EQUAL, txscript.OP_EQUALVERIFY:
+		b, err := e.pop()
+		if err != nil {
+			return err
+		}
+		a, err := e.pop()
+		if err != nil {
+			return err
+		}
+		if op == txscript.OP_EQUALVERIFY {
+			if !bytes.Equal(a, b) {
+				return fmt.Errorf("%x != %x", a, b)
+			}
+			return nil
+		}
+		e.pushBool(bytes.Equal(a, b))
+	case txscript.OP_VERIFY:
+		a, err := e.pop()
+		if err != nil {
+			return err
+		}
+		if !castToBool(a) {
+			return fmt.Errorf("verify failed")
+		}
+	case txscript.OP_BOOLAND:
+		b, err := e.pop()
+		if err != nil {
+			return err
+		}
+		a, err := e.pop()
+		if err != nil {
+			return err
+		}
+		e.pushBool(castToBool(a) && castToBool(b))
+	case txscript.OP_ADD, txscript.OP_1ADD:
+		b := int64(1)
+		if op == txscript.OP_ADD {
+			n, err := e.popNum()
+			if err != nil {
+				return err
+			}
+			b = n
+		}
+		a, err := e.popNum()
+		if err != nil {
+			return err
+		}
+		e.push(scriptNum(a + b).Bytes())
+	case OP_PUSHCURRENTINPUTINDEX:
+		e.push(scriptNum(e.inputIndex).Bytes())
+	case OP_INSPECTINPUTASSET, OP_INSPECTINPUTVALUE, OP_INSPECTINPUTSCRIPTPUBKEY,
+		OP_INSPECTOUTPUTASSET, OP_INSPECTOUTPUTVALUE, OP_INSPECTOUTPUTSCRIPTPUBKEY:
+		outputs := e.tx.Outputs
+		if op == OP_INSPECTINPUTASSET || op == OP_INSPECTINPUTVALUE || op == OP_INSPECTINPUTSCRIPTPUBKEY {
+			outputs = e.prevouts
+		}
+		index, err := e.popNum()
+		if err != nil {
+			return err
+		}
+		if index < 0 || index >= int64(len(outputs)) {
+			return fmt.Errorf("index %d out of range", index)
+		}
+		out := outputs[index]
+		switch op {
+		case OP_INSPECTINPUTASSET, OP_INSPECTOUTPUTASSET:
+			e.push(out.Asset[1:])
+			e.push(out.Asset[:1])
+		case OP_INSPECTINPUTVALUE, OP_INSPECTOUTPUTVALUE:
+			// explicit values are pushed little endian
+			if out.Value[0] == 0x01 {
+				e.push(elementsutil.ReverseBytes(append([]byte{}, out.Value[1:]...)))
+			} else {
+				e.push(out.Value[1:])
+			}
+			e.push(out.Value[:1])
+		default:
+			version, program := witnessProgram(out.Script)
+			e.push(program)
+			e.push(version)
+		}
+	case OP_SUB64, OP_MUL64:
+		b, err := e.popInt64()
+		if err != nil {
+			return err
+		}
+		a, err := e.popInt64()
+		if err != nil {
+			return err
+		}
+		result := new(big.Int)
+		if op == OP_SUB64 {
+			result.Sub(big.NewInt(a), big.NewInt(b))
+		} else {
+			result.Mul(big.NewInt(a), big.NewInt(b))
+		}
+		if !result.IsInt64() {
+			e.pushBool(false)
+			return nil
+		}
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, uint64(result.Int64()))
+		e.push(buf)
+		e.pushBool(true)
+	case OP_LESSTHANOREQUAL64, OP_GREATERTHAN64:
+		b, err := e.popInt64()
+		if err != nil {
+			return err
+		}
+		a, err := e.popInt64()
+		if err != nil {
+			return err
+		}
+		if op == OP_LESSTHANOREQUAL64 {
+			e.pushBool(a <= b)
+		} else {
+			e.pushBool(a > b)
+		}
+	default:
+		return fmt.Errorf("unsupported opcode")
+	}
+	return nil
+}
+
+func (e *leafEngine) push(data []byte) {
+	e.stack = append(e.stack, data)
+}
+
+func (e *leafEngine) pushBool(b bool) {
+	if b {
+		e.push([]byte{1})
+	} else {
+		e.push(nil)
+	}
+}
+
+func (e *leafEngine) pop() ([]byte, error) {
+	if len(e.stack) == 0 {
+		return nil, fmt.Errorf("stack underflow")
+	}
+	data := e.stack[len(e.stack)-1]
+	e.stack = e.stack[:len(e.stack)-1]
+	return data, nil
+}
+
+func (e *leafEngine) popNum() (int64, error) {
+	data, err := e.pop()
+	if err != nil {
+		return 0, err
+	}
+	if len(data) > 4 {
+		return 0, fmt.Errorf("number %x out of range", data)
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	var n int64
+	for i, b := range data {
+		n |= int64(b) << (8 * i)
+	}
+	if data[len(data)-1]&0x80 != 0 {
+		return -(n &^ (int64(0x80) << (8 * (len(data) - 1)))), nil
+	}
+	return n, nil
+}
+
+func (e *leafEngine) popInt64() (int64, error) {
+	data, err := e.pop()
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != 8 {
+		return 0, fmt.Errorf("%x is not a 64-bit integer", data)
+	}
+	return int64(binary.LittleEndian.Uint64(data)), nil
+}
+
+func castToBool(data []byte) bool {
+	for i, b := range data {
+		if b != 0 {
+			// negative zero is false as well
+			return i != len(data)-1 || b != 0x80
+		}
+	}
+	return false
+}
+
+// witnessProgram returns the version and program pushed by the inspection of
+// the given script, the sha256 of non-segwit ones with version -1.
+func witnessProgram(script []byte) ([]byte, []byte) {
+	if len(script) >= 4 && len(script) <= 42 && int(script[1]) == len(script)-2 {
+		if script[0] == txscript.OP_0 {
+			return scriptNum(0).Bytes(), script[2:]
+		}
+		if script[0] >= txscript.OP_1 && script[0] <= txscript.OP_16 {
+			return scriptNum(script[0] - txscript.OP_1 + 1).Bytes(), script[2:]
+		}
+	}
+	hash := sha256.Sum256(script)
+	return scriptNum(-1).Bytes(), hash[:]
+}
+
+// explicitOutput returns an unblinded output of the given asset and amount.
+func explicitOutput(asset string, amount uint64, script []byte) *transaction.TxOutput {
+	assetBytes, _ := elementsutil.AssetHashToBytes(asset)
+	value, _ := elementsutil.ValueToBytes(amount)
+	return transaction.NewTxOutput(assetBytes, value, script)
+}
+
+// spendingTx returns a transaction spending one input for each prevout with
+// the given outputs.
+func spendingTx(prevouts []*transaction.TxOutput, outputs ...*transaction.TxOutput) *transaction.Transaction {
+	tx := transaction.NewTx(2)
+	for i := range prevouts {
+		hash := sha256.Sum256([]byte{byte(i)})
+		tx.AddInput(transaction.NewTxInput(hash[:], uint32(i)))
+	}
+	for _, out := range outputs {
+		tx.AddOutput(out)
+	}
+	return tx
+}
+
+func TestPartialFillScript(t *testing.T) {
+	lbtc := currencyToAsset["L-BTC"].AssetHash
+	usdt := currencyToAsset["USDT"].AssetHash
+	lbtcBytes, _ := elementsutil.AssetHashToBytes(lbtc)
+	usdtBytes, _ := elementsutil.AssetHashToBytes(usdt)
+
+	// 100000 sats of L-BTC for 3000000 of USDT, 30 USDT units per sat
+	partialFillScript, err := PartialFillScript(traderScriptExpected, 100000, lbtcBytes, 3000000, usdtBytes)
+	assert.NoError(t, err)
+	fulfillScript, _ := FulfillScript(traderScriptExpected, 3000000, usdtBytes)
+	refundScript, _ := PartialRefundScript(traderScriptExpected, lbtcBytes)
+	contract, err := CreateFundingOutput(fulfillScript, refundScript, partialFillScript, nil, nil, nil, nil, &network.Testnet)
+	assert.NoError(t, err)
+	providerScript := []byte{0x00, 0x14, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x13, 0x14}
+
+	deposit := explicitOutput(lbtc, 100000, contract.Script)
+	pay := func(amount uint64) *transaction.TxOutput { return explicitOutput(usdt, amount, traderScriptExpected) }
+	remainder := func(amount uint64) *transaction.TxOutput { return explicitOutput(lbtc, amount, contract.Script) }
+	take := func(amount uint64) *transaction.TxOutput { return explicitOutput(lbtc, amount, providerScript) }
+
+	tests := []struct {
+		name     string
+		prevouts []*transaction.TxOutput
+		outputs  []*transaction.TxOutput
+		// whether each contract input is accepted
+		valid []bool
+	}{
+		{"half fill", []*transaction.TxOutput{deposit}, []*transaction.TxOutput{pay(1500000), remainder(50000), take(50000)}, []bool{true}},
+		{"full fill", []*transaction.TxOutput{deposit}, []*transaction.TxOutput{pay(3000000), take(100000)}, []bool{true}},
+		{"underpaid", []*transaction.TxOutput{deposit}, []*transaction.TxOutput{pay(1499999), remainder(50000), take(50000)}, []bool{false}},
+		{"zero fill", []*transaction.TxOutput{deposit}, []*transaction.TxOutput{pay(0), remainder(100000)}, []bool{false}},
+		{
+			"two inputs paid for each",
+			[]*transaction.TxOutput{deposit, deposit},
+			[]*transaction.TxOutput{pay(1500000), remainder(50000), pay(3000000), take(100000), take(50000)},
+			[]bool{true, true},
+		},
+		{
+			"two inputs against one payment",
+			[]*transaction.TxOutput{deposit, deposit},
+			[]*transaction.TxOutput{pay(1500000), remainder(50000), take(150000)},
+			[]bool{true, false},
+		},
+		{
+			"remainder spent along a deposit against one payment",
+			[]*transaction.TxOutput{remainder(50000), deposit},
+			[]*transaction.TxOutput{pay(1500000), remainder(50000), take(100000)},
+			[]bool{false, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := spendingTx(tt.prevouts, tt.outputs...)
+			for i, valid := range tt.valid {
+				err := evalLeaf(partialFillScript, tx, tt.prevouts, i)
+				if valid {
+					assert.NoError(t, err, "input %d", i)
+				} else {
+					assert.Error(t, err, "input %d", i)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchFulfillScript(t *testing.T) {
+	order := newTestOrder(t, testOrder{partialFill: true})
+	// no fixed-index leaf next to the batch fulfill one
+	assert.Empty(t, order.FulfillScript)
+	assert.NotEmpty(t, order.BatchFulfillScript)
+
+	lbtc := currencyToAsset["L-BTC"].AssetHash
+	contract, err := address.ToOutputScript(order.Address)
+	assert.NoError(t, err)
+	providerScript := append([]byte{0x51, 0x20}, make([]byte, 32)...)
+
+	deposit := explicitOutput(lbtc, order.Input.Amount, contract)
+	pay := explicitOutput(lbtc, order.Output.Amount, traderScriptExpected)
+	take := explicitOutput(lbtc, order.Input.Amount, providerScript)
+
+	tests := []struct {
+		name     string
+		prevouts []*transaction.TxOutput
+		outputs  []*transaction.TxOutput
+		// the leaf spending each contract input and whether it's accepted
+		leaves [][]byte
+		valid  []bool
+	}{
+		{
+			"single input",
+			[]*transaction.TxOutput{deposit},
+			[]*transaction.TxOutput{pay, take},
+			[][]byte{order.BatchFulfillScript},
+			[]bool{true},
+		},
+		{
+			"two inputs paid for each",
+			[]*transaction.TxOutput{deposit, deposit},
+			[]*transaction.TxOutput{pay, take, pay, take},
+			[][]byte{order.BatchFulfillScript, order.BatchFulfillScript},
+			[]bool{true, true},
+		},
+		{
+			"two inputs against one payment",
+			[]*transaction.TxOutput{deposit, deposit},
+			[]*transaction.TxOutput{pay, take, take},
+			[][]byte{order.BatchFulfillScript, order.BatchFulfillScript},
+			[]bool{true, false},
+		},
+		{
+			"partial fill and batch fulfill against one payment",
+			[]*transaction.TxOutput{deposit, deposit, deposit},
+			[]*transaction.TxOutput{pay, take, pay, take, take},
+			[][]byte{order.BatchFulfillScript, order.PartialFillScript, order.BatchFulfillScript},
+			[]bool{true, true, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := spendingTx(tt.prevouts, tt.outputs...)
+			for i, valid := range tt.valid {
+				err := evalLeaf(tt.leaves[i], tx, tt.prevouts, i)
+				if valid {
+					assert.NoError(t, err, "input %d", i)
+				} else {
+					assert.Error(t, err, "input %d", i)
+				}
+			}
+		})
+	}
+}
+
+func TestPartialRefundScript(t *testing.T) {
+	lbtc := currencyToAsset["L-BTC"].AssetHash
+	lbtcBytes, _ := elementsutil.AssetHashToBytes(lbtc)
+	refundScript, err := PartialRefundScript(traderScriptExpected, lbtcBytes)
+	assert.NoError(t, err)
+	contract := append([]byte{0x51, 0x20}, make([]byte, 32)...)
+
+	deposit := explicitOutput(lbtc, 40000, contract)
+	refund := explicitOutput(lbtc, 40000, traderScriptExpected)
+	other := explicitOutput(lbtc, 40000, append([]byte{0x00, 0x14}, make([]byte, 20)...))
+
+	tests := []struct {
+		name    string
+		outputs []*transaction.TxOutput
+		valid   []bool
+	}{
+		{"each deposit refunded", []*transaction.TxOutput{refund, other, refund}, []bool{true, true}},
+		{"two deposits against one refund", []*transaction.TxOutput{refund, other}, []bool{true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prevouts := []*transaction.TxOutput{deposit, deposit}
+			tx := spendingTx(prevouts, tt.outputs...)
+			for i, valid := range tt.valid {
+				err := evalLeaf(refundScript, tx, prevouts, i)
+				if valid {
+					assert.NoError(t, err, "input %d", i)
+				} else {
+					assert.Error(t, err, "input %d", i)
+				}
+			}
+		})
+	}
+}
+
+func TestRefundScript(t *testing.T) {
+	lbtc := currencyToAsset["L-BTC"].AssetHash
+	lbtcBytes, _ := elementsutil.AssetHashToBytes(lbtc)
+	refundScript, err := RefundScript(traderScriptExpected, 40000, lbtcBytes)
+	assert.NoError(t, err)
+	contract := append([]byte{0x51, 0x20}, make([]byte, 32)...)
+
+	// same terms, same contract: the deposits have the same value
+	deposit := explicitOutput(lbtc, 40000, contract)
+	refund := explicitOutput(lbtc, 40000, traderScriptExpected)
+	other := explicitOutput(lbtc, 40000, append([]byte{0x00, 0x14}, make([]byte, 20)...))
+
+	tests := []struct {
+		name    string
+		outputs []*transaction.TxOutput
+		valid   []bool
+	}{
+		{"single deposit", []*transaction.TxOutput{refund}, []bool{true}},
+		{"each deposit refunded", []*transaction.TxOutput{refund, other, refund}, []bool{true, true}},
+		{"two deposits against one refund", []*transaction.TxOutput{refund, other}, []bool{true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prevouts := []*transaction.TxOutput{deposit, deposit}[:len(tt.valid)]
+			tx := spendingTx(prevouts, tt.outputs...)
+			for i, valid := range tt.valid {
+				err := evalLeaf(refundScript, tx, prevouts, i)
+				if valid {
+					assert.NoError(t, err, "input %d", i)
+				} else {
+					assert.Error(t, err, "input %d", i)
+				}
+			}
+		})
+	}
+}
@@ -1,15 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"math"
-	"strconv"
+	"math/bits"
 	"time"
 
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/google/uuid"
+	"github.com/vulpemventures/go-elements/confidential"
 	"github.com/vulpemventures/go-elements/elementsutil"
 	"github.com/vulpemventures/go-elements/network"
+	"github.com/vulpemventures/go-elements/payment"
 )
 
 type Order struct {
@@ -20,7 +27,28 @@ type Order struct {
 	FulfillScript []byte
 	RefundScript  []byte
 	TraderScript  []byte
-	Input         struct {
+	MakerPubKey   []byte
+	// Expiring orders only: the leaf that refunds the trader once the expiry
+	// is reached. Orders with zero expiry never expire.
+	ExpiryScript []byte
+	Expiry       time.Time
+	// Explicit orders only: the fulfill leaf checking the output at the index
+	// of the contract input, to fulfill many orders in one transaction. Their
+	// contracts have no fixed-index fulfill leaf.
+	BatchFulfillScript []byte
+	// Partial fill orders only: the leaf that lets takers fill any fraction
+	// and the input amount already filled.
+	PartialFillScript []byte
+	FilledAmount      uint64
+	// Confidential orders only: the maker blinding public key, the private
+	// blinding key of the contract and the blinders of the first output
+	// committed to by the fulfill script.
+	MakerBlindingKey []byte
+	BlindingKey      []byte
+	AssetBlinder     []byte
+	ValueBlinder     []byte
+	Mismatched       int
+	Input            struct {
 		Asset  string
 		Amount uint64
 	}
@@ -28,51 +56,117 @@ type Order struct {
 		Asset  string
 		Amount uint64
 	}
+	// the fee charged by Banco on the trade, included in the input or taken
+	// from the output
+	Fee struct {
+		Asset  string
+		Amount uint64
+	}
 }
 type OrderStatus string
 
-func NewOrder(traderScriptHex, inputCurrency, inputValue, outputCurrency, outputValue string, rate float64, net *network.Network) (*Order, error) {
+// NewOrder creates the order trading the input for the output of the given
+// quote, whose fee is recorded on the order. The price of the order amounts
+// must be within the slippage tolerance of the given market rate.
+func NewOrder(traderScriptHex, makerPubKeyHex, makerBlindingKeyHex string, partialFill bool, expiry time.Duration, quote *Quote, rate float64, net *network.Network) (*Order, error) {
 	traderScript, err := hex.DecodeString(traderScriptHex)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode trader script: %w", err)
 	}
 
-	inputAsset, ok := currencyToAsset[inputCurrency]
+	// The maker public key is optional and, if given, must be x-only
+	var makerPubKey []byte
+	if makerPubKeyHex != "" {
+		makerPubKey, err = hex.DecodeString(makerPubKeyHex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode maker public key: %w", err)
+		}
+		if len(makerPubKey) != 32 {
+			return nil, fmt.Errorf("maker public key must be 32 bytes x-only, got %d bytes", len(makerPubKey))
+		}
+	}
+
+	// The maker blinding public key is optional and, if given, makes the
+	// order confidential
+	var makerBlindingKey []byte
+	if makerBlindingKeyHex != "" {
+		makerBlindingKey, err = hex.DecodeString(makerBlindingKeyHex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode maker blinding key: %w", err)
+		}
+		if _, err := secp256k1.ParsePubKey(makerBlindingKey); err != nil {
+			return nil, fmt.Errorf("invalid maker blinding key: %w", err)
+		}
+	}
+
+	// Partial fills compute the price on explicit amounts
+	if partialFill && makerBlindingKey != nil {
+		return nil, fmt.Errorf("confidential orders can't be partially filled")
+	}
+
+	if quote.Type != "Buy" && quote.Type != "Sell" {
+		return nil, fmt.Errorf("invalid trade type %s, expected Buy or Sell", quote.Type)
+	}
+
+	inputAsset, ok := currencyToAsset[quote.InputCurrency]
 	if !ok {
-		return nil, fmt.Errorf("failed to get input asset for currency: %s", inputCurrency)
+		return nil, fmt.Errorf("failed to get input asset for currency: %s", quote.InputCurrency)
 	}
 	inputAssetBytes, err := elementsutil.AssetHashToBytes(inputAsset.AssetHash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert input asset hash: %w", err)
 	}
-	inputValueFloat, err := strconv.ParseFloat(inputValue, 64)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse input value: %w", err)
-	}
-	inputAmount := uint64(inputValueFloat * math.Pow(10, float64(inputAsset.Precision)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get input asset: %w", err)
-	}
+	inputAmount := uint64(quote.InputValue * math.Pow(10, float64(inputAsset.Precision)))
 
-	outputAsset, ok := currencyToAsset[outputCurrency]
+	outputAsset, ok := currencyToAsset[quote.OutputCurrency]
 	if !ok {
-		return nil, fmt.Errorf("failed to get output asset for currency: %s", outputCurrency)
+		return nil, fmt.Errorf("failed to get output asset for currency: %s", quote.OutputCurrency)
 	}
 	outputAssetBytes, err := elementsutil.AssetHashToBytes(outputAsset.AssetHash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert output asset hash: %w", err)
 	}
-	outputValueFloat, err := strconv.ParseFloat(outputValue, 64)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse output value: %w", err)
-	}
-	outputAmount := uint64(outputValueFloat * math.Pow(10, float64(outputAsset.Precision)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get output asset: %w", err)
+	outputAmount := uint64(quote.OutputValue * math.Pow(10, float64(outputAsset.Precision)))
+
+	order := &Order{
+		ID:               uuid.New().String(),
+		Timestamp:        time.Now(),
+		Network:          net,
+		TraderScript:     traderScript,
+		MakerPubKey:      makerPubKey,
+		MakerBlindingKey: makerBlindingKey,
+		Input: struct {
+			Asset  string
+			Amount uint64
+		}{
+			Asset:  inputAsset.AssetHash,
+			Amount: inputAmount,
+		},
+		Output: struct {
+			Asset  string
+			Amount uint64
+		}{
+			Asset:  outputAsset.AssetHash,
+			Amount: outputAmount,
+		},
 	}
 
-	// Calculate the expected output value with the slippage tolerance
-	proposedRate := 1 / (outputValueFloat / inputValueFloat)
+	setOrderFee(order, quote)
+
+	// Check the price, in quote asset per base asset, with the slippage
+	// tolerance. A Buy sends the quote asset for the base one and a Sell the
+	// other way round. The amounts are the ones before the fee added to the
+	// input of a Buy or taken from the output of a Sell, or a fixed fee would
+	// not fit in the tolerance of small trades
+	if inputAmount == 0 || outputAmount == 0 {
+		return nil, fmt.Errorf("amounts of %v %s and %v %s are too small to trade", quote.InputValue, quote.InputCurrency, quote.OutputValue, quote.OutputCurrency)
+	}
+	var proposedRate float64
+	if quote.Type == "Buy" {
+		proposedRate = (order.InputValue() - order.FeeValue()) / order.OutputValue()
+	} else {
+		proposedRate = (order.OutputValue() + order.FeeValue()) / order.InputValue()
+	}
 	slippage := rate * 0.03 // 3% slippage
 	minExpectedRate := rate - slippage
 	maxExpectedRate := rate + slippage
@@ -81,48 +175,72 @@ func NewOrder(traderScriptHex, inputCurrency, inputValue, outputCurrency, output
 		return nil, fmt.Errorf("proposed rate %f is outside the expected range (%f to %f)", proposedRate, minExpectedRate, maxExpectedRate)
 	}
 
-	fulfillScript, err := FulfillScript(traderScript, outputAmount, outputAssetBytes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create fulfill script: %w", err)
+	// Confidential orders commit to the blinded first output of the fulfill
+	// transaction instead of its explicit asset and value
+	if order.IsConfidential() {
+		if err := order.generateBlindingKeys(); err != nil {
+			return nil, err
+		}
+		assetCommitment, valueCommitment, err := order.OutputCommitments()
+		if err != nil {
+			return nil, err
+		}
+		order.FulfillScript, err = ConfidentialFulfillScript(traderScript, assetCommitment, valueCommitment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create fulfill script: %w", err)
+		}
+	} else {
+		// A fixed-index fulfill leaf next to this one would let a single
+		// payment fulfill many contracts spent in the same transaction
+		order.BatchFulfillScript, err = BatchFulfillScript(traderScript, outputAmount, outputAssetBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create batch fulfill script: %w", err)
+		}
 	}
 
-	refundScript, err := RefundScript(traderScript, inputAmount, inputAssetBytes)
+	// Once partially filled, the contract holds less than the order amount,
+	// hence the refund leaf returns whatever the spent unspent holds
+	if partialFill {
+		order.PartialFillScript, err = PartialFillScript(traderScript, inputAmount, inputAssetBytes, outputAmount, outputAssetBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create partial fill script: %w", err)
+		}
+		order.RefundScript, err = PartialRefundScript(traderScript, inputAssetBytes)
+	} else {
+		order.RefundScript, err = RefundScript(traderScript, inputAmount, inputAssetBytes)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create refund script: %w", err)
 	}
 
-	output, err := CreateFundingOutput(fulfillScript, refundScript, net)
+	// After the expiry anyone can refund the trader with the whole value of
+	// any deposit, under-funded ones included
+	if expiry > 0 {
+		expiryRefundScript, err := PartialRefundScript(traderScript, inputAssetBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create expiry refund script: %w", err)
+		}
+		order.Expiry = order.Timestamp.Add(expiry).Truncate(time.Second)
+		order.ExpiryScript, err = ExpiryScript(expiryRefundScript, uint32(order.Expiry.Unix()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create expiry script: %w", err)
+		}
+	}
+
+	output, err := order.FundingOutput(net)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create funding output: %w", err)
 	}
-	address, err := output.TaprootAddress()
+	if order.IsConfidential() {
+		order.Address, err = output.ConfidentialTaprootAddress()
+	} else {
+		order.Address, err = output.TaprootAddress()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get taproot address: %w", err)
 	}
 
-	return &Order{
-		ID:            uuid.New().String(),
-		Timestamp:     time.Now(),
-		Address:       address,
-		Network:       net,
-		FulfillScript: fulfillScript,
-		RefundScript:  refundScript,
-		TraderScript:  traderScript,
-		Input: struct {
-			Asset  string
-			Amount uint64
-		}{
-			Asset:  inputAsset.AssetHash,
-			Amount: inputAmount,
-		},
-		Output: struct {
-			Asset  string
-			Amount uint64
-		}{
-			Asset:  outputAsset.AssetHash,
-			Amount: outputAmount,
-		},
-	}, nil
+	return order, nil
 }
 
 func (o *Order) OutputValue() float64 {
@@ -134,3 +252,189 @@ func (o *Order) InputValue() float64 {
 	precision := currencyToAsset[assetToCurrency[o.Input.Asset]].Precision
 	return float64(o.Input.Amount) / float64(math.Pow10(precision))
 }
+
+func (o *Order) FeeValue() float64 {
+	precision := currencyToAsset[assetToCurrency[o.Fee.Asset]].Precision
+	return float64(o.Fee.Amount) / float64(math.Pow10(precision))
+}
+
+// FundingOutput returns the taproot payment of the trade contract.
+func (o *Order) FundingOutput(net *network.Network) (*payment.Payment, error) {
+	return CreateFundingOutput(o.FulfillScript, o.RefundScript, o.PartialFillScript, o.ExpiryScript, o.BatchFulfillScript, o.MakerPubKey, o.BlindingPubKey(), net)
+}
+
+// FulfillLeaf returns the leaf fulfilling the order, the batch fulfill one if
+// the contract has it.
+func (o *Order) FulfillLeaf() []byte {
+	if len(o.BatchFulfillScript) > 0 {
+		return o.BatchFulfillScript
+	}
+	return o.FulfillScript
+}
+
+// OwnerKey returns the x-only public key proving the ownership of the order:
+// the maker public key or, if not given, the key of a taproot trader script.
+func (o *Order) OwnerKey() ([]byte, error) {
+	if len(o.MakerPubKey) > 0 {
+		return o.MakerPubKey, nil
+	}
+	// OP_1 <32-byte program>
+	if len(o.TraderScript) == 34 && o.TraderScript[0] == 0x51 && o.TraderScript[1] == 0x20 {
+		return o.TraderScript[2:], nil
+	}
+	return nil, fmt.Errorf("order has no maker public key nor taproot trader script to prove its ownership")
+}
+
+// VerifyOwnership checks the BIP-340 signature of the sha256 of the order ID
+// by the owner key of the order.
+func (o *Order) VerifyOwnership(signatureHex string) error {
+	ownerKey, err := o.OwnerKey()
+	if err != nil {
+		return err
+	}
+	pubKey, err := schnorr.ParsePubKey(ownerKey)
+	if err != nil {
+		return fmt.Errorf("invalid owner key: %w", err)
+	}
+	sigBytes, err := hex.DecodeString(signatureHex)
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+	signature, err := schnorr.ParseSignature(sigBytes)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	hash := sha256.Sum256([]byte(o.ID))
+	if !signature.Verify(hash[:], pubKey) {
+		return fmt.Errorf("signature does not match the owner key of the order")
+	}
+	return nil
+}
+
+// IsOnNetwork tells whether the order has been created for the given network.
+func (o *Order) IsOnNetwork(net *network.Network) bool {
+	return o.Network != nil && net != nil && o.Network.Name == net.Name
+}
+
+// IsExpired tells whether the order expiry has been reached.
+func (o *Order) IsExpired(now time.Time) bool {
+	return !o.Expiry.IsZero() && !now.Before(o.Expiry)
+}
+
+// RefundsWholeValue tells whether the given refund or expiry leaf returns the
+// whole value of any deposit rather than the order amount.
+func (o *Order) RefundsWholeValue(leafScript []byte) bool {
+	return o.IsPartial() || bytes.Equal(leafScript, o.ExpiryScript)
+}
+
+// CombinesDeposits tells whether deposits lower than the order amount can be
+// topped up and fulfilled together against a single payment. Only the
+// fixed-index fulfill leaf of confidential orders allows it, the batch fulfill
+// one pays each contract input on its own.
+func (o *Order) CombinesDeposits() bool {
+	return len(o.FulfillScript) > 0
+}
+
+// IsPartial tells whether the order can be partially filled.
+func (o *Order) IsPartial() bool {
+	return len(o.PartialFillScript) > 0
+}
+
+// RemainingAmount returns the input amount not yet filled.
+func (o *Order) RemainingAmount() uint64 {
+	if o.FilledAmount >= o.Input.Amount {
+		return 0
+	}
+	return o.Input.Amount - o.FilledAmount
+}
+
+// PartialFillAmounts returns how much of the output asset must be paid to fill
+// the given value of a contract unspent, at most the given limit, and the
+// input amount taken in exchange. The price is the one enforced by the partial
+// fill script, rounded in favour of the trader.
+func (o *Order) PartialFillAmounts(value, limit uint64) (uint64, uint64, error) {
+	priceNum, priceDen := priceRatio(o.Output.Amount, o.Input.Amount)
+
+	// ceil(value * priceNum / priceDen) to take the whole value
+	paid, err := mulDiv(value, priceNum, priceDen, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	if paid <= limit {
+		return paid, value, nil
+	}
+
+	// floor(limit * priceDen / priceNum) to take a fraction of it
+	taken, err := mulDiv(limit, priceDen, priceNum, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	if taken == 0 {
+		return 0, 0, fmt.Errorf("limit %d is too low to fill the order", limit)
+	}
+	return limit, taken, nil
+}
+
+// mulDiv returns a * b / c, checking the product fits the signed 64-bit
+// arithmetic of the script.
+func mulDiv(a, b, c uint64, roundUp bool) (uint64, error) {
+	hi, lo := bits.Mul64(a, b)
+	if hi != 0 || lo > math.MaxInt64 {
+		return 0, fmt.Errorf("amount %d overflows the 64-bit price arithmetic", a)
+	}
+	result := lo / c
+	if roundUp && lo%c != 0 {
+		result++
+	}
+	return result, nil
+}
+
+// IsConfidential tells whether the maker asked for a confidential trade.
+func (o *Order) IsConfidential() bool {
+	return len(o.MakerBlindingKey) > 0
+}
+
+// BlindingPubKey returns the blinding public key of the contract address, nil
+// for explicit orders.
+func (o *Order) BlindingPubKey() []byte {
+	if len(o.BlindingKey) == 0 {
+		return nil
+	}
+	return secp256k1.PrivKeyFromBytes(o.BlindingKey).PubKey().SerializeCompressed()
+}
+
+// OutputCommitments returns the asset and value commitments of the first
+// output of the fulfill transaction of a confidential order.
+func (o *Order) OutputCommitments() ([]byte, []byte, error) {
+	outputAssetBytes, err := elementsutil.AssetHashToBytes(o.Output.Asset)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to convert output asset hash: %w", err)
+	}
+	assetCommitment, err := confidential.AssetCommitment(outputAssetBytes[1:], o.AssetBlinder)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create asset commitment: %w", err)
+	}
+	valueCommitment, err := confidential.ValueCommitment(o.Output.Amount, assetCommitment, o.ValueBlinder)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create value commitment: %w", err)
+	}
+	return assetCommitment, valueCommitment, nil
+}
+
+func (o *Order) generateBlindingKeys() error {
+	blindingKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate blinding key: %w", err)
+	}
+	o.BlindingKey = blindingKey.Serialize()
+
+	o.AssetBlinder = make([]byte, 32)
+	if _, err := rand.Read(o.AssetBlinder); err != nil {
+		return fmt.Errorf("failed to generate asset blinder: %w", err)
+	}
+	o.ValueBlinder = make([]byte, 32)
+	if _, err := rand.Read(o.ValueBlinder); err != nil {
+		return fmt.Errorf("failed to generate value blinder: %w", err)
+	}
+	return nil
+}
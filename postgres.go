@@ -0,0 +1,11 @@
+package main
+
+import (
+	_ "github.com/lib/pq"
+)
+
+// NewPostgresOrderRepository connects to the PostgreSQL database of the given
+// connection string, applying the pending migrations if needed.
+func NewPostgresOrderRepository(dsn string) (OrderRepository, error) {
+	return newSQLRepository(postgresAdapter, dsn)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// tickers buffered for each price subscriber before it misses updates
+const tickersBufferSize = 32
+
+// Ticker is the top of the book of a market pair at a given time, along with
+// the order book if the source has it.
+type Ticker struct {
+	Pair      string
+	Bid       float64
+	Ask       float64
+	Last      float64
+	Timestamp time.Time
+	// the levels of the order book, best first
+	Bids []PriceLevel
+	Asks []PriceLevel
+}
+
+// PriceLevel is the volume of the base asset offered at a price.
+type PriceLevel struct {
+	Price  float64
+	Volume float64
+}
+
+// PriceStore keeps the last ticker of each market pair of a price source and
+// notifies its subscribers of the updates. It is safe for concurrent use.
+type PriceStore struct {
+	Source string
+	// how old a ticker can be before it is stale, never if zero
+	MaxAge time.Duration
+
+	lock          sync.RWMutex
+	tickers       map[string]Ticker
+	subscriptions map[*PriceSubscription]struct{}
+}
+
+// PriceSubscription receives the ticker updates on C until it is
+// unsubscribed.
+type PriceSubscription struct {
+	C <-chan Ticker
+
+	store     *PriceStore
+	tickers   chan Ticker
+	dropped   int
+	closeOnce sync.Once
+}
+
+func NewPriceStore(source string, maxAge time.Duration) *PriceStore {
+	return &PriceStore{
+		Source:        source,
+		MaxAge:        maxAge,
+		tickers:       make(map[string]Ticker),
+		subscriptions: make(map[*PriceSubscription]struct{}),
+	}
+}
+
+// Update stores the ticker, timestamped now unless it already is, and sends
+// it to the subscribers without waiting for them: the ones whose buffer is
+// full miss it.
+func (s *PriceStore) Update(ticker Ticker) {
+	if ticker.Timestamp.IsZero() {
+		ticker.Timestamp = time.Now()
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tickers[ticker.Pair] = ticker
+	for sub := range s.subscriptions {
+		select {
+		case sub.tickers <- ticker:
+		default:
+			sub.dropped++
+			log.Printf("price subscriber lagging behind, dropped %s ticker of %s (%d so far)", s.Source, ticker.Pair, sub.dropped)
+		}
+	}
+}
+
+// Ticker returns the last ticker of the market pair, failing with
+// ErrStalePrice if older than MaxAge.
+func (s *PriceStore) Ticker(marketPair string) (Ticker, error) {
+	s.lock.RLock()
+	ticker, ok := s.tickers[marketPair]
+	s.lock.RUnlock()
+	if !ok {
+		return Ticker{}, fmt.Errorf("no %s price available for market pair: %s", s.Source, marketPair)
+	}
+	if age := time.Since(ticker.Timestamp); s.MaxAge > 0 && age > s.MaxAge {
+		return Ticker{}, fmt.Errorf("%w: %s price of %s is %s old", ErrStalePrice, s.Source, marketPair, age.Round(time.Second))
+	}
+	return ticker, nil
+}
+
+// MarketTicker returns the last ticker of base in quote, at par for the same
+// asset.
+func (s *PriceStore) MarketTicker(base, quote string) (Ticker, error) {
+	if base == quote {
+		return Ticker{Pair: base + "/" + quote, Bid: 1, Ask: 1, Last: 1, Timestamp: time.Now()}, nil
+	}
+	return s.Ticker(base + "/" + quote)
+}
+
+// Price returns the ask price of base in quote, the one paid to buy it.
+func (s *PriceStore) Price(base, quote string) (float64, error) {
+	ticker, err := s.MarketTicker(base, quote)
+	if err != nil {
+		return 0, err
+	}
+	return ticker.Ask, nil
+}
+
+// Subscribe returns a subscription to the ticker updates of all the market
+// pairs.
+func (s *PriceStore) Subscribe() *PriceSubscription {
+	tickers := make(chan Ticker, tickersBufferSize)
+	sub := &PriceSubscription{
+		C:       tickers,
+		store:   s,
+		tickers: tickers,
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.subscriptions[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe stops the updates and closes C. It is safe to call more than
+// once.
+func (sub *PriceSubscription) Unsubscribe() {
+	sub.store.lock.Lock()
+	defer sub.store.lock.Unlock()
+	delete(sub.store.subscriptions, sub)
+	sub.closeOnce.Do(func() { close(sub.tickers) })
+}
+
+// depthWeightedPrice returns the average price to take the amount of the base
+// asset from the levels, best first, failing if they aren't deep enough.
+func depthWeightedPrice(levels []PriceLevel, amount float64) (float64, error) {
+	if len(levels) == 0 {
+		return 0, fmt.Errorf("empty order book")
+	}
+	if amount <= 0 {
+		return levels[0].Price, nil
+	}
+
+	remaining, cost := amount, 0.0
+	for _, level := range levels {
+		volume := math.Min(remaining, level.Volume)
+		cost += volume * level.Price
+		remaining -= volume
+		if remaining <= 0 {
+			return cost / amount, nil
+		}
+	}
+	return 0, fmt.Errorf("amount %v exceeds the order book depth of %v", amount, amount-remaining)
+}
@@ -1,103 +1,254 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"sort"
 	"time"
 
 	ws "github.com/aopoltorzhicky/go_kraken/websocket"
 )
 
+const (
+	// the Kraken websocket is dropped once quiet for krakenIdleTimeout and
+	// connected again, waiting twice as much each time up to krakenMaxBackoff
+	krakenIdleTimeout = time.Minute
+	krakenMinBackoff  = time.Second
+	krakenMaxBackoff  = time.Minute
+)
+
 type RatesClient interface {
 	Subscribe() error
+	// MarketPrice returns the ask price of base in quote.
 	MarketPrice(base, quote string) (float64, error)
-	FeePercentage(base, quote string) float64
+	// MarketTicker returns the bid and ask of base in quote, with the order
+	// book if known.
+	MarketTicker(base, quote string) (Ticker, error)
 }
 
 type Rates struct {
 	Client RatesClient
 }
 
+// ErrStalePrice is returned for the prices older than the max age of their
+// source, which halts the market until a new price is received.
+var ErrStalePrice = errors.New("stale price")
+
 type KrakenClient struct {
-	ws           *ws.Kraken
-	PriceStreams map[string]chan float64
-	LastPrices   map[string]float64
+	URL string
+	// the wait before reconnecting, doubled up to krakenMaxBackoff
+	Backoff time.Duration
+	Prices  *PriceStore
 }
 
-func NewKrakenClient() *KrakenClient {
-	kraken := ws.NewKraken(ws.ProdBaseURL)
-	if err := kraken.Connect(); err != nil {
-		log.Fatalf("Error connecting to web socket: %s", err.Error())
-	}
+// NewKrakenClient returns the client of the Kraken websocket, connected on
+// Subscribe, with the prices stale once older than maxPriceAge.
+func NewKrakenClient(maxPriceAge time.Duration) *KrakenClient {
 	return &KrakenClient{
-		ws:           kraken,
-		PriceStreams: make(map[string]chan float64),
-		LastPrices:   make(map[string]float64),
+		URL:     ws.ProdBaseURL,
+		Backoff: krakenMinBackoff,
+		Prices:  NewPriceStore("kraken", maxPriceAge),
 	}
 }
 
-func (kc *KrakenClient) FeePercentage(base, quote string) float64 {
-	// Set a fixed fee percentage
-	const feePercentage = 1 // 1% fee
-	return feePercentage
+func (kc *KrakenClient) MarketPrice(base, quote string) (float64, error) {
+	return kc.Prices.Price(base, quote)
 }
 
-func (kc *KrakenClient) MarketPrice(base, quote string) (float64, error) {
-	marketPair := base + "/" + quote
-	price, ok := kc.LastPrices[marketPair]
-	if !ok {
-		return 0, fmt.Errorf("no price available for market pair: %s", marketPair)
-	}
-	return price, nil
+func (kc *KrakenClient) MarketTicker(base, quote string) (Ticker, error) {
+	return kc.Prices.MarketTicker(base, quote)
 }
 
+// Subscribe streams the ticker of XBT/USDT in the background, connecting
+// again with exponential backoff whenever the websocket drops or goes quiet.
 func (kc *KrakenClient) Subscribe() error {
-	// Initialize the map
-	kc.PriceStreams = make(map[string]chan float64)
-
-	// Create channels to stream the market prices
-	kc.PriceStreams["L-BTC/USDT"] = make(chan float64)
-	kc.PriceStreams["L-BTC/L-BTC"] = make(chan float64)
-
-	// Start a goroutine for each market pair to read from the WebSocket and update the last price
-	for marketPair, priceStream := range kc.PriceStreams {
-		go func(marketPair string, priceStream chan float64) {
-			for price := range priceStream {
-				kc.LastPrices[marketPair] = price
+	go func() {
+		backoff := kc.Backoff
+		for {
+			received, err := kc.stream()
+			log.Printf("kraken price stream closed: %v", err)
+			if received {
+				backoff = kc.Backoff
 			}
-		}(marketPair, priceStream)
-	}
+			time.Sleep(backoff)
+			backoff = min(backoff*2, krakenMaxBackoff)
+		}
+	}()
+	return nil
+}
 
-	// XBT/USDT
-	// Subscribe to ticker information for the trading pair
-	if err := kc.ws.SubscribeTicker([]string{ws.BTCUSDT}); err != nil {
-		return fmt.Errorf("SubscribeTicker error: %s", err.Error())
+// stream connects to the websocket and records the ticker updates until no
+// update is received for krakenIdleTimeout. It tells whether any was.
+func (kc *KrakenClient) stream() (bool, error) {
+	kraken := ws.NewKraken(kc.URL)
+	// the websocket takes up to its read timeout to close
+	defer func() { go kraken.Close() }()
+
+	if err := kraken.Connect(); err != nil {
+		return false, fmt.Errorf("error connecting to kraken websocket: %w", err)
+	}
+	if err := kraken.SubscribeTicker([]string{ws.BTCUSDT}); err != nil {
+		return false, fmt.Errorf("error subscribing to kraken ticker: %w", err)
 	}
 
-	go func() {
-		defer close(kc.PriceStreams["L-BTC/USDT"])
-
-		for update := range kc.ws.Listen() {
-			switch data := update.Data.(type) {
-			case ws.TickerUpdate:
-				price, err := data.Ask.Price.Float64()
-				if err != nil {
-					log.Println("Error parsing price:", err)
-					continue
-				}
-				kc.PriceStreams["L-BTC/USDT"] <- price
-			default:
-				return
+	received := false
+	idle := time.NewTimer(krakenIdleTimeout)
+	defer idle.Stop()
+	for {
+		select {
+		case update, ok := <-kraken.Listen():
+			if !ok {
+				return received, fmt.Errorf("kraken websocket closed")
+			}
+			// the other messages, e.g. subscription statuses, are skipped
+			data, ok := update.Data.(ws.TickerUpdate)
+			if !ok {
+				continue
+			}
+			ticker, err := krakenTicker("L-BTC/USDT", data)
+			if err != nil {
+				log.Println("Error parsing price:", err)
+				continue
 			}
+			kc.Prices.Update(ticker)
+			received = true
+			idle.Reset(krakenIdleTimeout)
+		case <-idle.C:
+			return received, fmt.Errorf("no kraken ticker update in %s", krakenIdleTimeout)
 		}
-	}()
+	}
+}
 
-	// L-BTC/L-BTC
-	go func() {
-		for {
-			kc.PriceStreams["L-BTC/L-BTC"] <- 1.00
-			time.Sleep(1 * time.Second)
+func krakenTicker(marketPair string, data ws.TickerUpdate) (Ticker, error) {
+	bid, err := data.Bid.Price.Float64()
+	if err != nil {
+		return Ticker{}, fmt.Errorf("invalid bid %s: %w", data.Bid.Price, err)
+	}
+	ask, err := data.Ask.Price.Float64()
+	if err != nil {
+		return Ticker{}, fmt.Errorf("invalid ask %s: %w", data.Ask.Price, err)
+	}
+	last, err := data.Close.Today.Float64()
+	if err != nil {
+		return Ticker{}, fmt.Errorf("invalid last price %s: %w", data.Close.Today, err)
+	}
+	return Ticker{Pair: marketPair, Bid: bid, Ask: ask, Last: last}, nil
+}
+
+// AggregateRatesClient quotes the median price of its sources, once the ones
+// deviating more than MaxDeviation percent from it are left out. A source
+// down or sending a bad tick neither stops trading nor misprices the orders.
+type AggregateRatesClient struct {
+	Sources      []RatesClient
+	MaxDeviation float64
+}
+
+func NewAggregateRatesClient(maxDeviation float64, sources ...RatesClient) *AggregateRatesClient {
+	return &AggregateRatesClient{
+		Sources:      sources,
+		MaxDeviation: maxDeviation,
+	}
+}
+
+// Subscribe subscribes to all the sources, failing only if none is available.
+func (a *AggregateRatesClient) Subscribe() error {
+	available := 0
+	for _, source := range a.Sources {
+		if err := source.Subscribe(); err != nil {
+			log.Printf("error subscribing to price source: %v", err)
+			continue
 		}
-	}()
+		available++
+	}
+	if available == 0 {
+		return fmt.Errorf("no price source available")
+	}
 	return nil
 }
+
+func (a *AggregateRatesClient) MarketPrice(base, quote string) (float64, error) {
+	ticker, err := a.MarketTicker(base, quote)
+	if err != nil {
+		return 0, err
+	}
+	return ticker.Ask, nil
+}
+
+// MarketTicker returns the median bid and ask of the sources, taken apart,
+// and the order book of the first source that has one.
+func (a *AggregateRatesClient) MarketTicker(base, quote string) (Ticker, error) {
+	tickers := []Ticker{}
+	var lastErr error
+	for _, source := range a.Sources {
+		ticker, err := source.MarketTicker(base, quote)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if ticker.Bid > 0 && ticker.Ask > 0 {
+			tickers = append(tickers, ticker)
+		}
+	}
+	if len(tickers) == 0 {
+		if lastErr != nil {
+			return Ticker{}, fmt.Errorf("no price available for market pair %s/%s: %w", base, quote, lastErr)
+		}
+		return Ticker{}, fmt.Errorf("no price available for market pair: %s/%s", base, quote)
+	}
+
+	aggregated := Ticker{Pair: base + "/" + quote}
+	bids, asks, lasts := []float64{}, []float64{}, []float64{}
+	for _, ticker := range tickers {
+		bids = append(bids, ticker.Bid)
+		asks = append(asks, ticker.Ask)
+		if ticker.Last > 0 {
+			lasts = append(lasts, ticker.Last)
+		}
+		if ticker.Timestamp.After(aggregated.Timestamp) {
+			aggregated.Timestamp = ticker.Timestamp
+		}
+		if len(aggregated.Bids) == 0 && len(aggregated.Asks) == 0 {
+			aggregated.Bids, aggregated.Asks = ticker.Bids, ticker.Asks
+		}
+	}
+
+	var ok bool
+	if aggregated.Bid, ok = a.consensusPrice(bids); !ok {
+		return Ticker{}, fmt.Errorf("the price sources disagree on the bid of market pair: %s/%s", base, quote)
+	}
+	if aggregated.Ask, ok = a.consensusPrice(asks); !ok {
+		return Ticker{}, fmt.Errorf("the price sources disagree on the ask of market pair: %s/%s", base, quote)
+	}
+	if len(lasts) > 0 {
+		aggregated.Last = medianPrice(lasts)
+	}
+	return aggregated, nil
+}
+
+// consensusPrice returns the median of the prices, once the ones deviating
+// more than MaxDeviation percent from it are left out, if any is left.
+func (a *AggregateRatesClient) consensusPrice(prices []float64) (float64, bool) {
+	median := medianPrice(prices)
+	inliers := []float64{}
+	for _, price := range prices {
+		if math.Abs(price-median)/median*100 <= a.MaxDeviation {
+			inliers = append(inliers, price)
+		}
+	}
+	if len(inliers) == 0 {
+		return 0, false
+	}
+	return medianPrice(inliers), true
+}
+
+func medianPrice(prices []float64) float64 {
+	sorted := append([]float64{}, prices...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
@@ -1,7 +1,18 @@
 package main
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
 )
 
 type MockRatesClient struct{}
@@ -10,12 +21,12 @@ func (mrc *MockRatesClient) MarketPrice(base, quote string) (float64, error) {
 	return 0.0, nil
 }
 
-func (mrc *MockRatesClient) Subscribe() error {
-	return nil
+func (mrc *MockRatesClient) MarketTicker(base, quote string) (Ticker, error) {
+	return Ticker{}, nil
 }
 
-func (mrc *MockRatesClient) FeePercentage(inputTicker, outputTicker string) float64 {
-	return 0.0
+func (mrc *MockRatesClient) Subscribe() error {
+	return nil
 }
 
 func TestRates(t *testing.T) {
@@ -30,14 +41,360 @@ func TestRates(t *testing.T) {
 		t.Errorf("Error calling MarketPriceStream: %s", err.Error())
 	}
 
-	// Test FeePercentage
-	fee := rates.Client.FeePercentage("L-BTC", "USD")
-	if fee != 0.0 {
-		t.Errorf("Expected fee percentage to be 0.0, got %f", fee)
-	}
-
 	_, err = rates.Client.MarketPrice("L-BTC", "USDT")
 	if err != nil {
 		t.Errorf("Error calling MarketPrice: %s", err.Error())
 	}
 }
+
+// fixedRatesClient quotes L-BTC/USDT at a fixed price, or fails if zero.
+type fixedRatesClient struct {
+	MockRatesClient
+	price float64
+}
+
+func (c *fixedRatesClient) MarketPrice(base, quote string) (float64, error) {
+	if c.price == 0 {
+		return 0, fmt.Errorf("source down")
+	}
+	return c.price, nil
+}
+
+func (c *fixedRatesClient) MarketTicker(base, quote string) (Ticker, error) {
+	price, err := c.MarketPrice(base, quote)
+	if err != nil {
+		return Ticker{}, err
+	}
+	return Ticker{Pair: base + "/" + quote, Bid: price, Ask: price, Last: price}, nil
+}
+
+func TestAggregateRatesClient(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices []float64
+		price  float64
+		err    bool
+	}{
+		{"single source", []float64{30000}, 30000, false},
+		{"median of odd sources", []float64{30100, 30000, 30050}, 30050, false},
+		{"median of even sources", []float64{30000, 30100}, 30050, false},
+		{"source down", []float64{30000, 0, 30100}, 30050, false},
+		{"bad tick dropped", []float64{30000, 30100, 3000, 30050}, 30050, false},
+		{"all sources down", []float64{0, 0}, 0, true},
+		{"sources disagree", []float64{30000, 40000}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := []RatesClient{}
+			for _, price := range tt.prices {
+				sources = append(sources, &fixedRatesClient{price: price})
+			}
+			price, err := NewAggregateRatesClient(2, sources...).MarketPrice("L-BTC", "USDT")
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.price, price)
+		})
+	}
+}
+
+func TestAggregateRatesClient_MarketTicker(t *testing.T) {
+	first := NewPollingRatesClient("first", time.Second, time.Minute, nil)
+	second := NewPollingRatesClient("second", time.Second, time.Minute, nil)
+	third := NewPollingRatesClient("third", time.Second, time.Minute, nil)
+	first.Prices.Update(Ticker{Pair: "L-BTC/USDT", Bid: 29990, Ask: 30010, Last: 30000})
+	second.Prices.Update(Ticker{
+		Pair: "L-BTC/USDT", Bid: 29980, Ask: 30020,
+		Bids: []PriceLevel{{29980, 1}}, Asks: []PriceLevel{{30020, 1}},
+	})
+	// a bad bid is dropped without dropping the ask of the same source
+	third.Prices.Update(Ticker{Pair: "L-BTC/USDT", Bid: 3000, Ask: 30030, Last: 30020})
+
+	ticker, err := NewAggregateRatesClient(2, first, second, third).MarketTicker("L-BTC", "USDT")
+	assert.NoError(t, err)
+	assert.Equal(t, "L-BTC/USDT", ticker.Pair)
+	assert.Equal(t, 29985.0, ticker.Bid)
+	assert.Equal(t, 30020.0, ticker.Ask)
+	assert.Equal(t, 30010.0, ticker.Last)
+	assert.Equal(t, []PriceLevel{{29980, 1}}, ticker.Bids)
+	assert.Equal(t, []PriceLevel{{30020, 1}}, ticker.Asks)
+}
+
+func TestDepthWeightedPrice(t *testing.T) {
+	levels := []PriceLevel{{30000, 0.5}, {30100, 1}, {30400, 0.5}}
+
+	tests := []struct {
+		amount float64
+		price  float64
+	}{
+		{0, 30000},
+		{0.2, 30000},
+		{0.5, 30000},
+		{1, 30050},
+		{2, (15000 + 30100 + 15200) / 2.0},
+	}
+	for _, tt := range tests {
+		price, err := depthWeightedPrice(levels, tt.amount)
+		assert.NoError(t, err)
+		assert.InDelta(t, tt.price, price, 1e-9, tt.amount)
+	}
+
+	_, err := depthWeightedPrice(levels, 2.1)
+	assert.Error(t, err)
+	_, err = depthWeightedPrice(nil, 1)
+	assert.Error(t, err)
+}
+
+func TestPriceFeeds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bitfinex":
+			w.Write([]byte("[29990,1.5,30010,2.1,-100,-0.003,30000,1000,30500,29500]"))
+		case "/binance":
+			w.Write([]byte(`{"lastUpdateId":1,"bids":[["29995.00","1.0"],["29990.00","2.0"]],"asks":[["30005.00","1.0"],["30010.00","2.0"]]}`))
+		case "/feed":
+			w.Write([]byte(`{"L-BTC/USDT": 30020}`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	feedFile := filepath.Join(t.TempDir(), "prices.json")
+	if err := os.WriteFile(feedFile, []byte(`{"L-BTC/USDT": 30030}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		client *PollingRatesClient
+		price  float64
+	}{
+		{"bitfinex", NewBitfinexClient(server.URL+"/bitfinex", time.Minute), 30010},
+		{"binance", NewBinanceClient(server.URL+"/binance", time.Minute), 30005},
+		{"http feed", NewFeedClient(server.URL+"/feed", time.Minute), 30020},
+		{"file feed", NewFeedClient("file://"+feedFile, time.Minute), 30030},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.client.MarketPrice("L-BTC", "USDT")
+			assert.Error(t, err)
+
+			tt.client.poll()
+			price, err := tt.client.MarketPrice("L-BTC", "USDT")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.price, price)
+
+			price, err = tt.client.MarketPrice("L-BTC", "L-BTC")
+			assert.NoError(t, err)
+			assert.Equal(t, 1.0, price)
+		})
+	}
+
+	// the order book of binance is kept, best first
+	binance := NewBinanceClient(server.URL+"/binance", time.Minute)
+	binance.poll()
+	ticker, err := binance.MarketTicker("L-BTC", "USDT")
+	assert.NoError(t, err)
+	assert.Equal(t, 29995.0, ticker.Bid)
+	assert.Equal(t, []PriceLevel{{29995, 1}, {29990, 2}}, ticker.Bids)
+	assert.Equal(t, []PriceLevel{{30005, 1}, {30010, 2}}, ticker.Asks)
+
+	// the last price is kept while the feed is down
+	down := NewFeedClient(server.URL+"/down", time.Minute)
+	down.Prices.Update(Ticker{Pair: "L-BTC/USDT", Ask: 30000})
+	down.poll()
+	price, err := down.MarketPrice("L-BTC", "USDT")
+	assert.NoError(t, err)
+	assert.Equal(t, 30000.0, price)
+}
+
+func TestNewRatesClient(t *testing.T) {
+	rates, err := NewRatesClient([]string{"static"}, "L-BTC/USDT=30000, L-BTC/EUR=28000", "", 2, time.Minute)
+	assert.NoError(t, err)
+	assert.NoError(t, rates.Subscribe())
+	price, err := rates.MarketPrice("L-BTC", "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, 28000.0, price)
+	_, err = rates.MarketPrice("L-BTC", "CHF")
+	assert.Error(t, err)
+
+	rates, err = NewRatesClient([]string{"kraken", "bitfinex", "binance"}, "", "", 2, time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, rates.Sources, 3)
+
+	invalid := []struct {
+		feeds        []string
+		staticPrices string
+	}{
+		{[]string{"coinbase"}, ""},
+		{[]string{""}, ""},
+		{[]string{"feed"}, ""},
+		{[]string{"static"}, "L-BTC/USDT"},
+		{[]string{"static"}, "L-BTC/USDT=-1"},
+	}
+	for _, tt := range invalid {
+		_, err := NewRatesClient(tt.feeds, tt.staticPrices, "", 2, time.Minute)
+		assert.Error(t, err, tt.feeds, tt.staticPrices)
+	}
+}
+
+func TestPriceStore(t *testing.T) {
+	store := NewPriceStore("test", time.Minute)
+	_, err := store.Price("L-BTC", "USDT")
+	assert.Error(t, err)
+
+	store.Update(Ticker{Pair: "L-BTC/USDT", Bid: 29990, Ask: 30010, Last: 30000})
+	ticker, err := store.Ticker("L-BTC/USDT")
+	assert.NoError(t, err)
+	assert.Equal(t, 29990.0, ticker.Bid)
+	assert.Equal(t, 30000.0, ticker.Last)
+	assert.WithinDuration(t, time.Now(), ticker.Timestamp, time.Second)
+	price, err := store.Price("L-BTC", "USDT")
+	assert.NoError(t, err)
+	assert.Equal(t, 30010.0, price)
+
+	// the price is stale once older than the max age
+	store.Update(Ticker{Pair: "L-BTC/USDT", Ask: 30010, Timestamp: time.Now().Add(-2 * time.Minute)})
+	_, err = store.Price("L-BTC", "USDT")
+	assert.ErrorIs(t, err, ErrStalePrice)
+	price, err = store.Price("L-BTC", "L-BTC")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, price)
+
+	// or never if there is no max age
+	store.MaxAge = 0
+	_, err = store.Price("L-BTC", "USDT")
+	assert.NoError(t, err)
+}
+
+func TestPriceStore_Subscribe(t *testing.T) {
+	store := NewPriceStore("test", time.Minute)
+	sub := store.Subscribe()
+	lagging := store.Subscribe()
+
+	for i := 1; i <= tickersBufferSize+1; i++ {
+		store.Update(Ticker{Pair: "L-BTC/USDT", Ask: float64(i)})
+		ticker := <-sub.C
+		assert.Equal(t, float64(i), ticker.Ask)
+	}
+
+	// the lagging subscriber misses the updates past its buffer and doesn't
+	// block the others
+	assert.Len(t, lagging.C, tickersBufferSize)
+	assert.Equal(t, 1, lagging.dropped)
+
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+	_, ok := <-sub.C
+	assert.False(t, ok)
+	store.Update(Ticker{Pair: "L-BTC/USDT", Ask: 1})
+	lagging.Unsubscribe()
+}
+
+// TestPriceStore_Concurrency is meant to be run with -race.
+func TestPriceStore_Concurrency(t *testing.T) {
+	store := NewPriceStore("test", time.Minute)
+	pairs := []string{"L-BTC/USDT", "L-BTC/EUR"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		// writers
+		go func(i int) {
+			defer wg.Done()
+			for j := 1; j <= 100; j++ {
+				store.Update(Ticker{Pair: pairs[j%len(pairs)], Bid: float64(j), Ask: float64(j + i)})
+			}
+		}(i)
+		// readers
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				store.Price("L-BTC", "USDT")
+				store.Ticker("L-BTC/EUR")
+			}
+		}()
+		// subscribers coming and going
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				sub := store.Subscribe()
+				select {
+				case <-sub.C:
+				default:
+				}
+				sub.Unsubscribe()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, pair := range pairs {
+		ticker, err := store.Ticker(pair)
+		assert.NoError(t, err)
+		assert.Greater(t, ticker.Ask, 0.0)
+	}
+	assert.Empty(t, store.subscriptions)
+}
+
+func TestAggregateRatesClient_StalePrices(t *testing.T) {
+	fresh := NewPollingRatesClient("fresh", time.Second, time.Minute, nil)
+	stale := NewPollingRatesClient("stale", time.Second, time.Minute, nil)
+	fresh.Prices.Update(Ticker{Pair: "L-BTC/USDT", Bid: 29990, Ask: 30000})
+	stale.Prices.Update(Ticker{Pair: "L-BTC/USDT", Bid: 24990, Ask: 25000, Timestamp: time.Now().Add(-2 * time.Minute)})
+
+	// the stale source is left out
+	rates := NewAggregateRatesClient(2, fresh, stale)
+	price, err := rates.MarketPrice("L-BTC", "USDT")
+	assert.NoError(t, err)
+	assert.Equal(t, 30000.0, price)
+
+	// and the market is halted once all of them are
+	fresh.Prices.Update(Ticker{Pair: "L-BTC/USDT", Bid: 29990, Ask: 30000, Timestamp: time.Now().Add(-2 * time.Minute)})
+	_, err = rates.MarketPrice("L-BTC", "USDT")
+	assert.ErrorIs(t, err, ErrStalePrice)
+
+	mkt := getTradingPair(GetMarkets(), "L-BTC/USDT")
+	_, err = QuoteTrade(rates, mkt, "Buy", 0.001)
+	assert.ErrorIs(t, err, ErrStalePrice)
+}
+
+func TestKrakenClient(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// the ticker subscription
+		conn.ReadMessage()
+
+		// a message of another channel doesn't close the stream
+		conn.WriteMessage(websocket.TextMessage, []byte(`[1,["29990.1","30010.2","1700000000.1","1.0","1.0"],"spread","XBT/USDT"]`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`[2,{"a":["30010.5",1,"1.000"],"b":["29990.1",1,"1.000"],"c":["30000.0","0.01"]},"ticker","XBT/USDT"]`))
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	kraken := NewKrakenClient(time.Minute)
+	kraken.URL = "ws" + strings.TrimPrefix(server.URL, "http")
+	assert.NoError(t, kraken.Subscribe())
+
+	assert.Eventually(t, func() bool {
+		price, err := kraken.MarketPrice("L-BTC", "USDT")
+		return err == nil && price == 30010.5
+	}, 5*time.Second, 10*time.Millisecond)
+
+	ticker, err := kraken.Prices.Ticker("L-BTC/USDT")
+	assert.NoError(t, err)
+	assert.Equal(t, 29990.1, ticker.Bid)
+	assert.Equal(t, 30000.0, ticker.Last)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// OrderRepository stores the orders and the timeline of their statuses. The
+// current status of an order is the latest one of its timeline.
+type OrderRepository interface {
+	SaveOrder(order *Order) error
+	// UpdateOrderStatus appends the status to the timeline of the order,
+	// unless it's already the current one.
+	UpdateOrderStatus(id string, status string) error
+	// UpdateOrderStatusWithTxHash appends the status and the txid of the
+	// funding, fulfill or refund transaction to the timeline of the order,
+	// unless they're already the current ones.
+	UpdateOrderStatusWithTxHash(id string, status string, txHash string) error
+	AddOrderFilledAmount(id string, amount uint64) error
+	FlagOrderMismatchedDeposits(id string, mismatched int) error
+	// FetchOrdersToFulfill returns the orders of the given network waiting
+	// for deposits or to be fulfilled.
+	FetchOrdersToFulfill(networkName string) ([]*Order, error)
+	// FetchOrdersToSweep returns the expired orders of the given network with
+	// an expiry leaf whose deposits have not been refunded yet.
+	FetchOrdersToSweep(networkName string) ([]*Order, error)
+	FetchOrderIDByAddress(address string) (string, error)
+	// FetchOrderByID returns the order along with its current status.
+	FetchOrderByID(id string) (*Order, string, error)
+	// FetchOrderStatuses returns the status timeline of the order, oldest
+	// first.
+	FetchOrderStatuses(id string) ([]*StatusChange, error)
+	// ListOrders returns the orders of the given network along with their
+	// current status, newest first. An empty status matches any status, a
+	// zero limit any number of orders.
+	ListOrders(networkName string, status string, limit int, offset int) ([]*OrderWithStatus, error)
+	Close() error
+}
+
+// StatusChange is an entry of the status timeline of an order. TxHash is the
+// funding txid for the funding statuses and the one of the transaction that
+// spent the contract otherwise, if any.
+type StatusChange struct {
+	Status    string
+	Timestamp time.Time
+	TxHash    string
+}
+
+// OrderWithStatus is an order along with its current status.
+type OrderWithStatus struct {
+	Order  *Order
+	Status string
+}
+
+// NewOrderRepository returns the repository of the given type, either
+// "sqlite", "postgres" or "memory". The dsn is the database file for SQLite,
+// db/banco.db if empty, and the connection string for PostgreSQL.
+func NewOrderRepository(dbType, dsn string) (OrderRepository, error) {
+	switch dbType {
+	case sqliteAdapter:
+		return NewSQLiteOrderRepository(dsn)
+	case postgresAdapter:
+		return NewPostgresOrderRepository(dsn)
+	case "memory":
+		return NewInMemoryOrderRepository(), nil
+	default:
+		return nil, fmt.Errorf("unsupported database type: %s", dbType)
+	}
+}
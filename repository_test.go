@@ -0,0 +1,182 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vulpemventures/go-elements/network"
+)
+
+// testRepositories are the repositories tested by name, PostgreSQL needs a
+// running server and is left out.
+var testRepositories = map[string]func(t *testing.T) OrderRepository{
+	"memory": func(t *testing.T) OrderRepository {
+		return NewInMemoryOrderRepository()
+	},
+	"sqlite": func(t *testing.T) OrderRepository {
+		repo, err := NewSQLiteOrderRepository(filepath.Join(t.TempDir(), "banco.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	},
+}
+
+func TestOrderRepository(t *testing.T) {
+	for name, newRepo := range testRepositories {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			defer repo.Close()
+
+			order := newTestOrder(t, testOrder{expiry: time.Hour})
+			order.Fee.Asset = order.Input.Asset
+			order.Fee.Amount = 1000
+			err := repo.SaveOrder(order)
+			if err != nil {
+				t.Fatal("SaveOrder", err)
+			}
+
+			id, err := repo.FetchOrderIDByAddress(order.Address)
+			assert.NoError(t, err)
+			assert.Equal(t, order.ID, id)
+
+			fetched, status, err := repo.FetchOrderByID(order.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, "Pending", status)
+			assert.Equal(t, order.FulfillScript, fetched.FulfillScript)
+			assert.Equal(t, order.BatchFulfillScript, fetched.BatchFulfillScript)
+			assert.Equal(t, order.Input, fetched.Input)
+			assert.Equal(t, order.Fee, fetched.Fee)
+			assert.Equal(t, order.Expiry.Unix(), fetched.Expiry.Unix())
+			assert.Equal(t, &network.Testnet, fetched.Network)
+
+			// a status is appended only when it changes
+			assert.NoError(t, repo.UpdateOrderStatusWithTxHash(order.ID, "Funded", "aa"))
+			assert.NoError(t, repo.UpdateOrderStatusWithTxHash(order.ID, "Funded", "aa"))
+			assert.NoError(t, repo.UpdateOrderStatus(order.ID, "Funded"))
+			assert.NoError(t, repo.FlagOrderMismatchedDeposits(order.ID, 2))
+
+			orders, err := repo.FetchOrdersToFulfill(network.Testnet.Name)
+			assert.NoError(t, err)
+			assert.Len(t, orders, 1)
+			assert.Equal(t, 2, orders[0].Mismatched)
+			assert.True(t, orders[0].IsOnNetwork(&network.Testnet))
+
+			// orders of other networks are never returned
+			orders, err = repo.FetchOrdersToFulfill(network.Liquid.Name)
+			assert.NoError(t, err)
+			assert.Empty(t, orders)
+
+			assert.NoError(t, repo.UpdateOrderStatus(order.ID, "Expired"))
+			orders, err = repo.FetchOrdersToFulfill(network.Testnet.Name)
+			assert.NoError(t, err)
+			assert.Empty(t, orders)
+			orders, err = repo.FetchOrdersToSweep(network.Testnet.Name)
+			assert.NoError(t, err)
+			assert.Len(t, orders, 1)
+
+			assert.NoError(t, repo.UpdateOrderStatusWithTxHash(order.ID, "Expired", "bb"))
+			orders, err = repo.FetchOrdersToSweep(network.Testnet.Name)
+			assert.NoError(t, err)
+			assert.Empty(t, orders)
+
+			// expired orders with nothing to refund are not swept again
+			empty := newTestOrder(t, testOrder{expiry: time.Hour})
+			assert.NoError(t, repo.SaveOrder(empty))
+			assert.NoError(t, repo.UpdateOrderStatus(empty.ID, "Expired"))
+			assert.NoError(t, repo.UpdateOrderStatus(empty.ID, "ExpiredEmpty"))
+			orders, err = repo.FetchOrdersToSweep(network.Testnet.Name)
+			assert.NoError(t, err)
+			assert.Empty(t, orders)
+
+			statuses, err := repo.FetchOrderStatuses(order.ID)
+			assert.NoError(t, err)
+			timeline := []string{}
+			for _, change := range statuses {
+				timeline = append(timeline, change.Status+":"+change.TxHash)
+			}
+			assert.Equal(t, []string{"Pending:", "Funded:aa", "Expired:", "Expired:bb"}, timeline)
+
+			_, _, err = repo.FetchOrderByID("unknown")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestOrderRepository_ListOrders(t *testing.T) {
+	for name, newRepo := range testRepositories {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			defer repo.Close()
+
+			// three testnet orders created a minute apart and a liquid one
+			var ids []string
+			for i, net := range []*network.Network{&network.Testnet, &network.Testnet, &network.Testnet, &network.Liquid} {
+				order := newTestOrder(t, testOrder{net: net})
+				order.Timestamp = time.Date(2024, 1, 1, 10, i, 0, 0, time.UTC)
+				if err := repo.SaveOrder(order); err != nil {
+					t.Fatal("SaveOrder", err)
+				}
+				ids = append(ids, order.ID)
+			}
+			assert.NoError(t, repo.UpdateOrderStatus(ids[1], "Cancelled"))
+
+			orders, err := repo.ListOrders(network.Testnet.Name, "", 0, 0)
+			assert.NoError(t, err)
+			listed := []string{}
+			for _, o := range orders {
+				listed = append(listed, o.Order.ID+":"+o.Status)
+			}
+			assert.Equal(t, []string{ids[2] + ":Pending", ids[1] + ":Cancelled", ids[0] + ":Pending"}, listed)
+
+			orders, err = repo.ListOrders(network.Testnet.Name, "Pending", 1, 1)
+			assert.NoError(t, err)
+			if assert.Len(t, orders, 1) {
+				assert.Equal(t, ids[0], orders[0].Order.ID)
+			}
+
+			orders, err = repo.ListOrders(network.Testnet.Name, "", 10, 5)
+			assert.NoError(t, err)
+			assert.Empty(t, orders)
+
+			_, err = repo.ListOrders(network.Testnet.Name, "", 0, 1)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRecordTrades(t *testing.T) {
+	repo := NewInMemoryOrderRepository()
+	order := newTestOrder(t, testOrder{partialFill: true})
+	assert.NoError(t, repo.SaveOrder(order))
+
+	trade := &Trade{Order: order, TxID: "aa", Filled: 40000, Remainder: 60000}
+	err := recordTrades(repo, order, []*Trade{trade}, 1)
+	assert.NoError(t, err)
+
+	fetched, status, err := repo.FetchOrderByID(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "PartiallyFilled", status)
+	assert.Equal(t, uint64(40000), fetched.FilledAmount)
+
+	trade = &Trade{Order: order, TxID: "bb", Filled: 60000}
+	err = recordTrades(repo, order, []*Trade{trade}, 1)
+	assert.NoError(t, err)
+
+	fetched, status, err = repo.FetchOrderByID(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Fulfilled", status)
+	assert.Equal(t, order.Input.Amount, fetched.FilledAmount)
+}
+
+func TestSQLRepository_Rebind(t *testing.T) {
+	query := `UPDATE orders SET filled_amount = filled_amount + ? WHERE id = ?`
+
+	sqlite := &sqlRepository{adapter: sqliteAdapter}
+	assert.Equal(t, query, sqlite.rebind(query))
+
+	postgres := &sqlRepository{adapter: postgresAdapter}
+	assert.Equal(t, `UPDATE orders SET filled_amount = filled_amount + $1 WHERE id = $2`, postgres.rebind(query))
+}
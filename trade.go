@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"reflect"
@@ -10,10 +11,12 @@ import (
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/tiero/banco/pkg/bufferutil"
+	"github.com/vulpemventures/go-elements/elementsutil"
 	"github.com/vulpemventures/go-elements/network"
 	"github.com/vulpemventures/go-elements/payment"
 	"github.com/vulpemventures/go-elements/psetv2"
 	"github.com/vulpemventures/go-elements/taproot"
+	"github.com/vulpemventures/go-elements/transaction"
 )
 
 const FEE_AMOUNT = 500
@@ -31,19 +34,34 @@ type Trade struct {
 	Status         TradeStatus
 	Order          *Order
 	FundingUnspent *UTXO
+	FundingTopUps  []*UTXO
 	FundingPayment *payment.Payment
-	walletService  WalletService
+	TxID           string
+	// Filled is the input amount taken by the executed fill, Remainder what
+	// is left in the contract after a partial fill.
+	Filled    uint64
+	Remainder uint64
+	// FeeRate is the fee rate in sats/vbyte the transactions are sized at,
+	// zero to pay the fixed FEE_AMOUNT. Fee is the one paid by the last
+	// prepared transaction.
+	FeeRate       float64
+	Fee           uint64
+	walletService WalletService
 }
 
-type CancelTransaction struct{}
-
 // FromFundedOrder accepts an Order and sets it at the funded state.
-func FromFundedOrder(walletSvc WalletService, order *Order, fundingUnspent *UTXO) (*Trade, error) {
+// Optional top-ups are additional contract unspents that, together with the
+// funding one, fund the order and get spent in the same transaction, for the
+// orders whose contract combines deposits.
+func FromFundedOrder(walletSvc WalletService, order *Order, fundingUnspent *UTXO, topUps ...*UTXO) (*Trade, error) {
 	// TODO does this should be raise an error instead?
 	if fundingUnspent == nil {
 		return FromPendingOrder(walletSvc, order), nil
 	}
-	paymentData, err := CreateFundingOutput(order.FulfillScript, order.RefundScript, &network.Testnet)
+	if order.Network == nil {
+		return nil, fmt.Errorf("order %s has an unknown network", order.ID)
+	}
+	paymentData, err := order.FundingOutput(order.Network)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create funding output: %w", err)
 	}
@@ -53,6 +71,7 @@ func FromFundedOrder(walletSvc WalletService, order *Order, fundingUnspent *UTXO
 		Order:          order,
 		Status:         Funded,
 		FundingUnspent: fundingUnspent,
+		FundingTopUps:  topUps,
 		FundingPayment: paymentData,
 	}, nil
 }
@@ -78,6 +97,13 @@ func (t *Trade) PrepareFulfillTransaction(
 	if t.FundingUnspent == nil || t.Status < Funded {
 		return nil, fmt.Errorf("the offer address is not funded or the Trade funding data is missing")
 	}
+	if t.FundedAmount() < t.Order.Input.Amount {
+		return nil, fmt.Errorf("the offer address is under-funded: %d of %d", t.FundedAmount(), t.Order.Input.Amount)
+	}
+	// the batch fulfill leaf pays each contract input with its own output
+	if len(t.FundingTopUps) > 0 && !t.Order.CombinesDeposits() {
+		return nil, fmt.Errorf("the contract unspents of the order can't be fulfilled together")
+	}
 
 	ptx, err := psetv2.New(nil, nil, nil)
 	if err != nil {
@@ -88,36 +114,11 @@ func (t *Trade) PrepareFulfillTransaction(
 		return nil, fmt.Errorf("failed to create updater: %w", err)
 	}
 
-	inputIndex := 0
-	// Offer funding input
-	updater.AddInputs([]psetv2.InputArgs{{
-		Txid:    t.FundingUnspent.Txid,
-		TxIndex: uint32(t.FundingUnspent.Index),
-	}})
-	updater.AddInWitnessUtxo(inputIndex, t.FundingUnspent.Prevout)
-	updater.AddInSighashType(inputIndex, txscript.SigHashDefault)
-
-	// update taproot stuff
-	taprootTree := t.FundingPayment.Taproot.ScriptTree
-	internalKeyBytes := append([]byte{0x02}, t.FundingPayment.Taproot.XOnlyInternalKey...)
-	internalKey, err := secp256k1.ParsePubKey(internalKeyBytes)
+	// Offer funding inputs
+	inputIndex, err := t.addContractInputs(updater, t.Order.FulfillLeaf())
 	if err != nil {
-		return nil, fmt.Errorf("failed to ParsePubKey: %w", err)
-	}
-	for _, proof := range taprootTree.LeafMerkleProofs {
-		// compare the fulfill script to know which leaf to pick
-		if reflect.DeepEqual(proof.Script, t.Order.FulfillScript) {
-			controlBlock := proof.ToControlBlock(internalKey)
-			if err := updater.AddInTapLeafScript(inputIndex, psetv2.TapLeafScript{
-				TapElementsLeaf: taproot.NewBaseTapElementsLeaf(proof.Script),
-				ControlBlock:    controlBlock,
-			}); err != nil {
-				return nil, err
-			}
-
-		}
+		return nil, err
 	}
-	inputIndex++
 
 	// Trading inputs
 	for _, unspent := range *unspentsForTrade {
@@ -155,9 +156,33 @@ func (t *Trade) PrepareFulfillTransaction(
 		},
 	})
 
-	feeAmountWithoutCreatingDust := uint64(FEE_AMOUNT)
+	// Return the excess of over-funded contracts to the trader
+	if excess := t.FundedAmount() - t.Order.Input.Amount; excess > 0 {
+		updater.AddOutputs([]psetv2.OutputArgs{{
+			Asset:  t.Order.Input.Asset,
+			Amount: excess,
+			Script: t.Order.TraderScript,
+		}})
+	}
+
+	t.addChangeAndFeeOutputs(updater, changeProviderScriptOfTradeOutput, changeProviderScriptOfFees, changeProviderAmountOfTradeOutput, changeProviderAmountOfFees)
+
+	return ptx, nil
+}
+
+// addChangeAndFeeOutputs adds the change outputs of the provider inputs and
+// the fee output, folding L-BTC change below the dust amount into the fee.
+func (t *Trade) addChangeAndFeeOutputs(
+	updater *psetv2.Updater,
+	changeProviderScriptOfTradeOutput []byte,
+	changeProviderScriptOfFees []byte,
+	changeProviderAmountOfTradeOutput uint64,
+	changeProviderAmountOfFees uint64,
+) {
+	dust := dustAmount(t.FeeRate, t.Order.IsConfidential())
+	feeAmountWithoutCreatingDust := t.feeAmount()
 	if changeProviderAmountOfTradeOutput > 0 {
-		if t.Order.Output.Asset == currencyToAsset["L-BTC"].AssetHash && changeProviderAmountOfTradeOutput < FEE_AMOUNT {
+		if t.Order.Output.Asset == currencyToAsset["L-BTC"].AssetHash && changeProviderAmountOfTradeOutput < dust {
 			feeAmountWithoutCreatingDust += uint64(changeProviderAmountOfTradeOutput)
 		} else {
 			updater.AddOutputs([]psetv2.OutputArgs{{
@@ -169,7 +194,7 @@ func (t *Trade) PrepareFulfillTransaction(
 	}
 
 	if changeProviderAmountOfFees > 0 {
-		if changeProviderAmountOfFees < FEE_AMOUNT {
+		if changeProviderAmountOfFees < dust {
 			feeAmountWithoutCreatingDust += changeProviderAmountOfFees
 		} else {
 			updater.AddOutputs([]psetv2.OutputArgs{{
@@ -184,6 +209,101 @@ func (t *Trade) PrepareFulfillTransaction(
 		Asset:  currencyToAsset["L-BTC"].AssetHash,
 		Amount: feeAmountWithoutCreatingDust,
 	}})
+}
+
+// PreparePartialFillTransaction builds the transaction that fills a fraction
+// of the funding unspent via the partial fill leaf, paying fillAmount of the
+// Order Output asset to the trader. The input amount taken in exchange goes
+// to the provider and the remainder, if any, back to the contract.
+func (t *Trade) PreparePartialFillTransaction(
+	fillAmount uint64,
+	unspentsForTrade *[]UTXO,
+	unspentsForFees *[]UTXO,
+	providerScriptOfTradeInput []byte,
+	changeProviderScriptOfTradeOutput []byte,
+	changeProviderScriptOfFees []byte,
+	changeProviderAmountOfTradeOutput uint64,
+	changeProviderAmountOfFees uint64,
+) (*psetv2.Pset, error) {
+
+	if t.FundingUnspent == nil || t.Status < Funded {
+		return nil, fmt.Errorf("the offer address is not funded or the Trade funding data is missing")
+	}
+	if !t.Order.IsPartial() {
+		return nil, fmt.Errorf("the order can't be partially filled")
+	}
+	// the partial fill leaf checks the outputs at twice the index of each
+	// input and the next one, the first two for the only contract input
+	if len(t.FundingTopUps) > 0 {
+		return nil, fmt.Errorf("a partial fill spends a single contract unspent")
+	}
+
+	paid, taken, err := t.Order.PartialFillAmounts(t.FundedAmount(), fillAmount)
+	if err != nil {
+		return nil, err
+	}
+	if paid != fillAmount {
+		return nil, fmt.Errorf("fill amount %d exceeds the %d needed to fill the whole contract", fillAmount, paid)
+	}
+	remainder := t.FundedAmount() - taken
+
+	ptx, err := psetv2.New(nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pset: %w", err)
+	}
+	updater, err := psetv2.NewUpdater(ptx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create updater: %w", err)
+	}
+
+	// Offer funding input
+	inputIndex, err := t.addContractInputs(updater, t.Order.PartialFillScript)
+	if err != nil {
+		return nil, err
+	}
+
+	// Trading inputs
+	for _, unspent := range *unspentsForTrade {
+		updater.AddInputs([]psetv2.InputArgs{{
+			Txid:    unspent.Txid,
+			TxIndex: uint32(unspent.Index),
+		}})
+		updater.AddInWitnessUtxo(inputIndex, unspent.Prevout)
+		updater.AddInSighashType(inputIndex, txscript.SigHashAll)
+		inputIndex++
+	}
+
+	// Fee supplier inputs
+	for _, unspent := range *unspentsForFees {
+		updater.AddInputs([]psetv2.InputArgs{{
+			Txid:    unspent.Txid,
+			TxIndex: uint32(unspent.Index),
+		}})
+		updater.AddInWitnessUtxo(inputIndex, unspent.Prevout)
+		updater.AddInSighashType(inputIndex, txscript.SigHashAll)
+		inputIndex++
+	}
+
+	//outputs
+	updater.AddOutputs([]psetv2.OutputArgs{{
+		Asset:  t.Order.Output.Asset,
+		Amount: paid,
+		Script: t.Order.TraderScript,
+	}})
+	if remainder > 0 {
+		updater.AddOutputs([]psetv2.OutputArgs{{
+			Asset:  t.Order.Input.Asset,
+			Amount: remainder,
+			Script: t.FundingPayment.Script,
+		}})
+	}
+	updater.AddOutputs([]psetv2.OutputArgs{{
+		Asset:  t.Order.Input.Asset,
+		Amount: taken,
+		Script: providerScriptOfTradeInput,
+	}})
+
+	t.addChangeAndFeeOutputs(updater, changeProviderScriptOfTradeOutput, changeProviderScriptOfFees, changeProviderAmountOfTradeOutput, changeProviderAmountOfFees)
 
 	return ptx, nil
 }
@@ -197,15 +317,15 @@ func (t *Trade) ExecuteTrade() error {
 	}
 
 	// Get an Address to receive the Trade Input amount
-	_, providerScript, err := t.walletService.GetAddress(context.Background(), false)
+	providerAddress, providerScript, err := t.walletService.GetAddress(context.Background(), false)
 	if err != nil {
 		return fmt.Errorf("error in GetAddress: %w", err)
 	}
-	_, providerChangeScript, err := t.walletService.GetAddress(context.Background(), true)
+	providerChangeAddress, providerChangeScript, err := t.walletService.GetAddress(context.Background(), true)
 	if err != nil {
 		return fmt.Errorf("error in GetAddress: %w", err)
 	}
-	_, feeChangeScript, err := t.walletService.GetAddress(context.Background(), true)
+	feeChangeAddress, feeChangeScript, err := t.walletService.GetAddress(context.Background(), true)
 	if err != nil {
 		return fmt.Errorf("error in GetAddress: %w", err)
 	}
@@ -217,66 +337,516 @@ func (t *Trade) ExecuteTrade() error {
 	}
 
 	// subsidize the tx fees
-	utxosForFees, changeAmountForFees, err := t.walletService.SelectUtxos(context.Background(), currencyToAsset["L-BTC"].AssetHash, FEE_AMOUNT)
+	ptx, err := t.prepareWithFees(func(utxosForFees []UTXO, changeAmountForFees uint64) (*psetv2.Pset, error) {
+		ptx, err := t.PrepareFulfillTransaction(&utxosForTrade, &utxosForFees, providerScript, providerChangeScript, feeChangeScript, changeAmountForTrade, changeAmountForFees)
+		if err != nil {
+			return nil, fmt.Errorf("error in PrepareFulfillTransaction: %w", err)
+		}
+
+		// Blind the maker-bound outputs to the maker key and ours to the keys
+		// of Ocean's confidential addresses, leaving the fee explicit
+		if t.Order.IsConfidential() {
+			blindingKeysByScript, err := blindingKeysOfAddresses(map[string]string{
+				hex.EncodeToString(providerScript):       providerAddress,
+				hex.EncodeToString(providerChangeScript): providerChangeAddress,
+				hex.EncodeToString(feeChangeScript):      feeChangeAddress,
+			})
+			if err != nil {
+				return nil, err
+			}
+			blindingKeysByScript[hex.EncodeToString(t.Order.TraderScript)] = t.Order.MakerBlindingKey
+
+			inputs := append(t.fundingUnspents(), utxoPointers(utxosForTrade, utxosForFees)...)
+			if err := blindTransaction(ptx, t.Order, inputs, outputBlindingKeys(ptx, blindingKeysByScript), true); err != nil {
+				return nil, fmt.Errorf("error in blinding the fulfill transaction: %w", err)
+			}
+		}
+		return ptx, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	txid, err := t.signFinalizeAndBroadcast(ptx, t.Order.FulfillLeaf())
+	if err != nil {
+		return err
+	}
+	if len(txid) > 0 {
+		t.TxID = txid
+		t.Status = Executed
+		t.Filled = t.Order.Input.Amount
+	}
+
+	return nil
+}
+
+// ExecutePartialFill fills a fraction of the funding unspent paying fillAmount
+// of the Order Output asset, as returned by Order.PartialFillAmounts.
+func (t *Trade) ExecutePartialFill(fillAmount uint64) error {
+	if t.Status == Pending {
+		return fmt.Errorf("trade has not being funded yet")
+	}
+	if t.Status == Executed || t.Status == Cancelled {
+		return fmt.Errorf("trade has already been executed or cancelled")
+	}
+
+	// Get an Address to receive the taken Trade Input amount
+	_, providerScript, err := t.walletService.GetAddress(context.Background(), false)
+	if err != nil {
+		return fmt.Errorf("error in GetAddress: %w", err)
+	}
+	_, providerChangeScript, err := t.walletService.GetAddress(context.Background(), true)
+	if err != nil {
+		return fmt.Errorf("error in GetAddress: %w", err)
+	}
+	_, feeChangeScript, err := t.walletService.GetAddress(context.Background(), true)
+	if err != nil {
+		return fmt.Errorf("error in GetAddress: %w", err)
+	}
+
+	// fund the filled amount of the swap
+	utxosForTrade, changeAmountForTrade, err := t.walletService.SelectUtxos(context.Background(), t.Order.Output.Asset, fillAmount)
+	if err != nil {
+		return fmt.Errorf("error in SelectUtxos for trade %s : %d %s : %w", t.Order.ID, fillAmount, t.Order.Output.Asset, err)
+	}
+
+	// subsidize the tx fees
+	ptx, err := t.prepareWithFees(func(utxosForFees []UTXO, changeAmountForFees uint64) (*psetv2.Pset, error) {
+		ptx, err := t.PreparePartialFillTransaction(fillAmount, &utxosForTrade, &utxosForFees, providerScript, providerChangeScript, feeChangeScript, changeAmountForTrade, changeAmountForFees)
+		if err != nil {
+			return nil, fmt.Errorf("error in PreparePartialFillTransaction: %w", err)
+		}
+		return ptx, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	txid, err := t.signFinalizeAndBroadcast(ptx, t.Order.PartialFillScript)
+	if err != nil {
+		return err
+	}
+	if len(txid) > 0 {
+		_, taken, _ := t.Order.PartialFillAmounts(t.FundedAmount(), fillAmount)
+		t.TxID = txid
+		t.Status = Executed
+		t.Filled = taken
+		t.Remainder = t.FundedAmount() - taken
+	}
+
+	return nil
+}
+
+// PrepareCancelTransaction builds the cancel transaction that spends the
+// funding unspent via the refund leaf. The first output returns the amount and
+// asset of the Order Input back to the trader script, as enforced by the
+// refund script. Fees are paid by the given unspents, which can either come
+// from Ocean or be supplied by the maker.
+func (t *Trade) PrepareCancelTransaction(
+	unspentsForFees *[]UTXO,
+	changeScriptOfFees []byte,
+	changeAmountOfFees uint64,
+) (*psetv2.Pset, error) {
+	return t.prepareRefundTransaction(t.Order.RefundScript, nil, unspentsForFees, changeScriptOfFees, changeAmountOfFees)
+}
+
+// PrepareExpiryRefundTransaction builds the transaction that refunds the
+// funding unspent via the expiry leaf, with the same outputs of the cancel
+// one. Its locktime is the order expiry, hence it's valid only once the median
+// time past of the chain tip is after it.
+func (t *Trade) PrepareExpiryRefundTransaction(
+	unspentsForFees *[]UTXO,
+	changeScriptOfFees []byte,
+	changeAmountOfFees uint64,
+) (*psetv2.Pset, error) {
+	if len(t.Order.ExpiryScript) == 0 {
+		return nil, fmt.Errorf("the order has no expiry leaf")
+	}
+	locktime := uint32(t.Order.Expiry.Unix())
+	return t.prepareRefundTransaction(t.Order.ExpiryScript, &locktime, unspentsForFees, changeScriptOfFees, changeAmountOfFees)
+}
+
+func (t *Trade) prepareRefundTransaction(
+	leafScript []byte,
+	locktime *uint32,
+	unspentsForFees *[]UTXO,
+	changeScriptOfFees []byte,
+	changeAmountOfFees uint64,
+) (*psetv2.Pset, error) {
+
+	if t.FundingUnspent == nil || t.Status < Funded {
+		return nil, fmt.Errorf("the offer address is not funded or the Trade funding data is missing")
+	}
+	// The refund leaf enforces the full Order Input amount to be returned,
+	// while the one of partial fill orders and the expiry one the whole value
+	// of the unspent, each one with the output at twice the index of its input
+	if len(t.FundingTopUps) > 0 {
+		return nil, fmt.Errorf("the contract unspents are refunded one at a time")
+	}
+	refundAmount := t.Order.Input.Amount
+	if t.Order.RefundsWholeValue(leafScript) {
+		refundAmount = t.FundedAmount()
+	}
+	if t.FundedAmount() < refundAmount {
+		return nil, fmt.Errorf("under-funded contracts can be cancelled only via key-path: %d of %d", t.FundedAmount(), t.Order.Input.Amount)
+	}
+
+	ptx, err := psetv2.New(nil, nil, locktime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pset: %w", err)
+	}
+	updater, err := psetv2.NewUpdater(ptx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create updater: %w", err)
+	}
+
+	// Offer funding inputs
+	inputIndex, err := t.addContractInputs(updater, leafScript)
+	if err != nil {
+		return nil, err
+	}
+	// OP_CHECKLOCKTIMEVERIFY fails for inputs with final sequence
+	if locktime != nil {
+		for i := 0; i < inputIndex; i++ {
+			ptx.Inputs[i].Sequence = transaction.DefaultSequence - 1
+			ptx.Inputs[i].RequiredTimeLocktime = *locktime
+		}
+	}
+
+	// Fee supplier inputs
+	for _, unspent := range *unspentsForFees {
+		updater.AddInputs([]psetv2.InputArgs{{
+			Txid:    unspent.Txid,
+			TxIndex: uint32(unspent.Index),
+		}})
+		updater.AddInWitnessUtxo(inputIndex, unspent.Prevout)
+		updater.AddInSighashType(inputIndex, txscript.SigHashAll)
+		inputIndex++
+	}
+
+	//outputs
+	updater.AddOutputs([]psetv2.OutputArgs{
+		{
+			Asset:  t.Order.Input.Asset,
+			Amount: refundAmount,
+			Script: t.Order.TraderScript,
+		},
+	})
+
+	// Return the excess of over-funded contracts as well
+	if excess := t.FundedAmount() - refundAmount; excess > 0 {
+		updater.AddOutputs([]psetv2.OutputArgs{{
+			Asset:  t.Order.Input.Asset,
+			Amount: excess,
+			Script: t.Order.TraderScript,
+		}})
+	}
+
+	feeAmountWithoutCreatingDust := t.feeAmount()
+	if changeAmountOfFees > 0 {
+		if changeAmountOfFees < dustAmount(t.FeeRate, t.Order.IsConfidential()) {
+			feeAmountWithoutCreatingDust += changeAmountOfFees
+		} else {
+			updater.AddOutputs([]psetv2.OutputArgs{{
+				Asset:  currencyToAsset["L-BTC"].AssetHash,
+				Amount: changeAmountOfFees,
+				Script: changeScriptOfFees,
+			}})
+		}
+	}
+
+	updater.AddOutputs([]psetv2.OutputArgs{{
+		Asset:  currencyToAsset["L-BTC"].AssetHash,
+		Amount: feeAmountWithoutCreatingDust,
+	}})
+
+	return ptx, nil
+}
+
+// CancelTrade refunds the funding unspent to the trader via the refund leaf,
+// subsidizing the network fees with Ocean's coins.
+func (t *Trade) CancelTrade() error {
+	return t.refundTrade(t.Order.RefundScript, t.PrepareCancelTransaction)
+}
+
+// SweepExpiredTrade refunds the funding unspent to the trader via the expiry
+// leaf, subsidizing the network fees with Ocean's coins.
+func (t *Trade) SweepExpiredTrade() error {
+	return t.refundTrade(t.Order.ExpiryScript, t.PrepareExpiryRefundTransaction)
+}
+
+func (t *Trade) refundTrade(
+	leafScript []byte,
+	prepare func(*[]UTXO, []byte, uint64) (*psetv2.Pset, error),
+) error {
+	if t.Status == Pending {
+		return fmt.Errorf("trade has not being funded yet")
+	}
+	if t.Status == Executed || t.Status == Cancelled {
+		return fmt.Errorf("trade has already been executed or cancelled")
+	}
+
+	feeChangeAddress, feeChangeScript, err := t.walletService.GetAddress(context.Background(), true)
+	if err != nil {
+		return fmt.Errorf("error in GetAddress: %w", err)
+	}
+
+	// subsidize the tx fees
+	ptx, err := t.prepareWithFees(func(utxosForFees []UTXO, changeAmountForFees uint64) (*psetv2.Pset, error) {
+		ptx, err := prepare(&utxosForFees, feeChangeScript, changeAmountForFees)
+		if err != nil {
+			return nil, fmt.Errorf("error in preparing the refund transaction: %w", err)
+		}
+
+		// The refund leaf enforces an explicit first output, the excess and
+		// the fee change get blinded instead
+		if t.Order.IsConfidential() {
+			blindingKeysByScript, err := blindingKeysOfAddresses(map[string]string{
+				hex.EncodeToString(feeChangeScript): feeChangeAddress,
+			})
+			if err != nil {
+				return nil, err
+			}
+			blindingKeysByScript[hex.EncodeToString(t.Order.TraderScript)] = t.Order.MakerBlindingKey
+			blindingKeys := outputBlindingKeys(ptx, blindingKeysByScript)
+			delete(blindingKeys, 0)
+
+			inputs := append(t.fundingUnspents(), utxoPointers(utxosForFees)...)
+			if err := blindTransaction(ptx, t.Order, inputs, blindingKeys, false); err != nil {
+				return nil, fmt.Errorf("error in blinding the cancel transaction: %w", err)
+			}
+		}
+		return ptx, nil
+	})
 	if err != nil {
-		return fmt.Errorf("error in SelectUtxos for fees: %w", err)
+		return err
+	}
+
+	txid, err := t.signFinalizeAndBroadcast(ptx, leafScript)
+	if err != nil {
+		return err
+	}
+	if len(txid) > 0 {
+		t.TxID = txid
+		t.Status = Cancelled
+	}
+
+	return nil
+}
+
+// CancelTradeWithSignedPset completes a cancel transaction created with
+// PrepareKeyPathCancelTransaction and signed by the maker, then broadcasts it.
+// The key-path signatures of the contract inputs prove the ownership of the
+// order, as only the maker knows the private key of the internal key.
+func (t *Trade) CancelTradeWithSignedPset(signedPsetBase64 string) error {
+	if t.Status == Pending {
+		return fmt.Errorf("trade has not being funded yet")
+	}
+	if t.Status == Executed || t.Status == Cancelled {
+		return fmt.Errorf("trade has already been executed or cancelled")
+	}
+
+	ptx, err := psetv2.NewPsetFromBase64(signedPsetBase64)
+	if err != nil {
+		return fmt.Errorf("error in decoding base64: %w", err)
+	}
+
+	// the contract inputs come first, in the order of the trade unspents
+	unspents := t.fundingUnspents()
+	if len(ptx.Inputs) < len(unspents) {
+		return fmt.Errorf("the pset does not spend all the contract unspents")
+	}
+	for i, unspent := range unspents {
+		input := ptx.Inputs[i]
+		txid := elementsutil.TxIDFromBytes(input.PreviousTxid)
+		if txid != unspent.Txid || int(input.PreviousTxIndex) != unspent.Index {
+			return fmt.Errorf("input %d does not spend the contract unspent %s:%d", i, unspent.Txid, unspent.Index)
+		}
+		if !isKeyPathSigned(input) {
+			return fmt.Errorf("input %d is not signed via key-path", i)
+		}
 	}
-	ptx, err := t.PrepareFulfillTransaction(&utxosForTrade, &utxosForFees, providerScript, providerChangeScript, feeChangeScript, changeAmountForTrade, changeAmountForFees)
+
+	for i, input := range ptx.Inputs {
+		if len(input.FinalScriptWitness) > 0 {
+			continue
+		}
+		if err := psetv2.Finalize(ptx, i); err != nil {
+			return fmt.Errorf("error in finalize: %w", err)
+		}
+	}
+
+	txid, err := extractAndBroadcast(t.walletService, ptx)
 	if err != nil {
-		return fmt.Errorf("error in PrepareFulfillTransaction")
+		return err
+	}
+	if len(txid) > 0 {
+		t.TxID = txid
+		t.Status = Cancelled
+	}
+
+	return nil
+}
+
+// isKeyPathSigned tells whether the input has a key-path signature, either
+// as is or already finalized by the wallet. go-elements fails to decode the
+// key-path signatures of non-finalized inputs, hence wallets must finalize
+// them before sending the pset.
+func isKeyPathSigned(input psetv2.Input) bool {
+	if len(input.TapKeySig) > 0 {
+		return true
 	}
+	// a single 64 or 65 bytes witness element
+	witness := input.FinalScriptWitness
+	return (len(witness) == 66 && witness[0] == 1 && witness[1] == 64) ||
+		(len(witness) == 67 && witness[0] == 1 && witness[1] == 65)
+}
 
+// FundedAmount returns the total value of the contract unspents of the Trade.
+func (t *Trade) FundedAmount() uint64 {
+	total := uint64(0)
+	for _, unspent := range t.fundingUnspents() {
+		total += unspent.Value
+	}
+	return total
+}
+
+// feeAmount returns the network fee to pay, FEE_AMOUNT unless estimated.
+func (t *Trade) feeAmount() uint64 {
+	if t.Fee > 0 {
+		return t.Fee
+	}
+	return FEE_AMOUNT
+}
+
+// prepareWithFees selects Ocean's L-BTC unspents to pay the network fees of
+// the transaction built by prepare, at the Trade fee rate.
+func (t *Trade) prepareWithFees(
+	prepare func(utxosForFees []UTXO, changeAmountForFees uint64) (*psetv2.Pset, error),
+) (*psetv2.Pset, error) {
+	return prepareWithFees(t.walletService, t.FeeRate, len(t.fundingUnspents()), &t.Fee, prepare)
+}
+
+func (t *Trade) fundingUnspents() []*UTXO {
+	if t.FundingUnspent == nil {
+		return nil
+	}
+	return append([]*UTXO{t.FundingUnspent}, t.FundingTopUps...)
+}
+
+// addContractInputs adds the contract unspents as next inputs of the given
+// pset along with the tapscript leaf, identified by its script, that is going
+// to spend them. It returns the index of the next input.
+func (t *Trade) addContractInputs(updater *psetv2.Updater, leafScript []byte) (int, error) {
+	// update taproot stuff
+	taprootTree := t.FundingPayment.Taproot.ScriptTree
+	internalKeyBytes := append([]byte{0x02}, t.FundingPayment.Taproot.XOnlyInternalKey...)
+	internalKey, err := secp256k1.ParsePubKey(internalKeyBytes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to ParsePubKey: %w", err)
+	}
+
+	inputIndex := len(updater.Pset.Inputs)
+	for _, unspent := range t.fundingUnspents() {
+		updater.AddInputs([]psetv2.InputArgs{{
+			Txid:    unspent.Txid,
+			TxIndex: uint32(unspent.Index),
+		}})
+		updater.AddInWitnessUtxo(inputIndex, unspent.Prevout)
+		updater.AddInSighashType(inputIndex, txscript.SigHashDefault)
+
+		for _, proof := range taprootTree.LeafMerkleProofs {
+			// compare the leaf script to know which leaf to pick
+			if reflect.DeepEqual(proof.Script, leafScript) {
+				controlBlock := proof.ToControlBlock(internalKey)
+				if err := updater.AddInTapLeafScript(inputIndex, psetv2.TapLeafScript{
+					TapElementsLeaf: taproot.NewBaseTapElementsLeaf(proof.Script),
+					ControlBlock:    controlBlock,
+				}); err != nil {
+					return 0, err
+				}
+
+			}
+		}
+		inputIndex++
+	}
+	return inputIndex, nil
+}
+
+// signFinalizeAndBroadcast signs Ocean's inputs of the given pset, then
+// finalizes and broadcasts it spending the funding input via leafScript.
+func (t *Trade) signFinalizeAndBroadcast(ptx *psetv2.Pset, leafScript []byte) (string, error) {
 	pbase64, err := ptx.ToBase64()
 	if err != nil {
-		return fmt.Errorf("error in ToBase64")
+		return "", fmt.Errorf("error in ToBase64")
 	}
 
 	// Sign Ocean's inputs
 	base64, err := t.walletService.SignPset(context.Background(), pbase64, false)
 	if err != nil {
-		return fmt.Errorf("error in SignPset: %w", err)
+		return "", fmt.Errorf("error in SignPset: %w", err)
 	}
 	ptx, err = psetv2.NewPsetFromBase64(base64)
 	if err != nil {
-		return fmt.Errorf("error in decoding base64: %w", err)
+		return "", fmt.Errorf("error in decoding base64: %w", err)
 	}
-	for i := 1; i < len(ptx.Inputs); i++ {
-		err = psetv2.Finalize(ptx, i)
+
+	return t.finalizeAndBroadcast(ptx, leafScript)
+}
+
+// finalizeAndBroadcast finalizes the already signed inputs of the given pset,
+// sets the witness of the contract inputs spending them via leafScript and
+// broadcasts the resulting transaction.
+func (t *Trade) finalizeAndBroadcast(ptx *psetv2.Pset, leafScript []byte) (string, error) {
+	numOfContractInputs := len(t.fundingUnspents())
+	for i := numOfContractInputs; i < len(ptx.Inputs); i++ {
+		err := psetv2.Finalize(ptx, i)
 		if err != nil {
-			return fmt.Errorf("error in finalize: %w", err)
+			return "", fmt.Errorf("error in finalize: %w", err)
 		}
 	}
 
 	// Manually setting the FinalScriptWitness into the unsigned tx
 	// psetv2 finalizer does not support script without signature
-	taprootTree := t.FundingPayment.Taproot.ScriptTree
+	witness, err := leafWitness(t.FundingPayment, leafScript)
 	if err != nil {
-		return fmt.Errorf("error in decoding tx hex: %w", err)
+		return "", err
+	}
+	for i := 0; i < numOfContractInputs; i++ {
+		ptx.Inputs[i].FinalScriptWitness = witness
 	}
+
+	return extractAndBroadcast(t.walletService, ptx)
+}
+
+// leafWitness returns the serialized witness spending the given contract
+// payment via the tapscript leaf identified by leafScript.
+func leafWitness(fundingPayment *payment.Payment, leafScript []byte) ([]byte, error) {
+	taprootTree := fundingPayment.Taproot.ScriptTree
 	var leafIndex int
 	foundLeaf := false
 	for i, leafProof := range taprootTree.LeafMerkleProofs {
-		if reflect.DeepEqual(leafProof.Script, t.Order.FulfillScript) {
+		if reflect.DeepEqual(leafProof.Script, leafScript) {
 			foundLeaf = true
 			leafIndex = i
 			break
 		}
 	}
 	if !foundLeaf {
-		return fmt.Errorf("tap script not found")
+		return nil, fmt.Errorf("tap script not found")
 	}
 
 	leafProof := taprootTree.LeafMerkleProofs[leafIndex]
-	internalKeyBytes := append([]byte{0x02}, t.FundingPayment.Taproot.XOnlyInternalKey...)
+	internalKeyBytes := append([]byte{0x02}, fundingPayment.Taproot.XOnlyInternalKey...)
 	internalPubKey, err := btcec.ParsePubKey(internalKeyBytes)
 	if err != nil {
-		log.Fatalf("Failed to parse public key: %v", err)
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
 	}
 	controlBlock := leafProof.ToControlBlock(internalPubKey)
 	controlBlockBytes, err := controlBlock.ToBytes()
 
 	if err != nil {
-		return fmt.Errorf("error in encoding control block: %w", err)
+		return nil, fmt.Errorf("error in encoding control block: %w", err)
 	}
 	witness := [][]byte{
 		leafProof.Script,
@@ -284,41 +854,205 @@ func (t *Trade) ExecuteTrade() error {
 	}
 	serializer := bufferutil.NewSerializer(nil)
 	if err := serializer.WriteVector(witness); err != nil {
-		return err
+		return nil, err
 	}
-	ptx.Inputs[0].FinalScriptWitness = serializer.Bytes()
+	return serializer.Bytes(), nil
+}
 
+// extractAndBroadcast extracts the final transaction of the given finalized
+// pset and broadcasts it via Ocean.
+func extractAndBroadcast(walletSvc WalletService, ptx *psetv2.Pset) (string, error) {
 	utx, err := ptx.UnsignedTx()
 	if err != nil {
-		return fmt.Errorf("error in accessing the unsigned tx: %w", err)
+		return "", fmt.Errorf("error in accessing the unsigned tx: %w", err)
 	}
 
 	finalTx, err := psetv2.Extract(ptx)
 	if err != nil {
 		log.Println(utx.ToHex())
-		return fmt.Errorf("error in extracting to tx hex: %w", err)
+		return "", fmt.Errorf("error in extracting to tx hex: %w", err)
 	}
 
 	txHex, err := finalTx.ToHex()
 	if err != nil {
-		return fmt.Errorf("error in serializing tx hex: %w", err)
+		return "", fmt.Errorf("error in serializing tx hex: %w", err)
 	}
 
 	// Broadcast the transaction
-	txid, err := t.walletService.BroadcastTransaction(context.Background(), txHex)
+	txid, err := walletSvc.BroadcastTransaction(context.Background(), txHex)
 	if err != nil {
 		log.Println(txHex)
-		return fmt.Errorf("error in broadcasting transaction: %w", err)
+		return "", fmt.Errorf("error in broadcasting transaction: %w", err)
 	}
-	if len(txid) > 0 {
-		t.Status = Executed
+
+	return txid, nil
+}
+
+// PrepareKeyPathCancelTransaction builds an unsigned transaction that spends
+// all the given contract unspents via key-path back to the trader script,
+// whatever their amounts. The maker signs it with the private key of the
+// public key used as internal key of the trade contract, for example with
+// Marina or any other wallet supporting PSET. Fees are paid by the L-BTC
+// contract unspents if any, otherwise by the given fee unspents, whose change
+// goes back to the trader script as well. The fee is sized at the given fee
+// rate in sats/vbyte, with no fee rate the fixed FEE_AMOUNT is paid.
+func PrepareKeyPathCancelTransaction(
+	order *Order,
+	unspents []*UTXO,
+	feeUnspents []*UTXO,
+	net *network.Network,
+	feeRate float64,
+) (*psetv2.Pset, error) {
+	fee := uint64(FEE_AMOUNT)
+	lowered := false
+	for i := 0; i < maxFeeIterations; i++ {
+		ptx, err := prepareKeyPathCancelTransaction(order, unspents, feeUnspents, net, fee)
+		if err != nil {
+			return nil, err
+		}
+		if feeRate <= 0 {
+			return ptx, nil
+		}
+
+		// contract inputs are spent via key-path as the taproot fee unspents
+		vsize, err := estimateVsize(ptx, 0)
+		if err != nil {
+			return nil, err
+		}
+		// lower the fee only once, as prepareWithFees does
+		estimatedFee := feeForVsize(vsize, feeRate)
+		switch {
+		case estimatedFee > fee:
+			fee = estimatedFee
+		case estimatedFee < fee && !lowered:
+			fee = estimatedFee
+			lowered = true
+		default:
+			return ptx, nil
+		}
 	}
 
-	return nil
+	return nil, fmt.Errorf("fees not settled after %d attempts", maxFeeIterations)
 }
 
-func (t *Trade) CancelTrade() error {
-	t.Status = Cancelled
-	// Implement the logic to cancel the trade here
-	return nil
+func prepareKeyPathCancelTransaction(
+	order *Order,
+	unspents []*UTXO,
+	feeUnspents []*UTXO,
+	net *network.Network,
+	fee uint64,
+) (*psetv2.Pset, error) {
+	if len(order.MakerPubKey) == 0 {
+		return nil, fmt.Errorf("order has no maker public key, key-path is unspendable")
+	}
+	if len(unspents) == 0 {
+		return nil, fmt.Errorf("no contract unspents to cancel")
+	}
+
+	fundingPayment, err := order.FundingOutput(net)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create funding output: %w", err)
+	}
+	tapMerkleRoot := fundingPayment.Taproot.ScriptTree.RootNode.TapHash()
+
+	ptx, err := psetv2.New(nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pset: %w", err)
+	}
+	updater, err := psetv2.NewUpdater(ptx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create updater: %w", err)
+	}
+
+	lbtc := currencyToAsset["L-BTC"].AssetHash
+	amountsByAsset := make(map[string]uint64)
+	assets := make([]string, 0)
+
+	inputIndex := 0
+	inputs := make([]*UTXO, 0, len(unspents))
+	// Contract inputs to be signed by the maker via key-path
+	for _, unspent := range unspents {
+		if order.IsConfidential() {
+			unblindUnspent(order, unspent)
+		}
+		inputs = append(inputs, unspent)
+		asset := unspentAsset(unspent)
+		if asset == "" {
+			return nil, fmt.Errorf("unknown or blinded asset for contract unspent %s:%d", unspent.Txid, unspent.Index)
+		}
+		if _, ok := amountsByAsset[asset]; !ok {
+			assets = append(assets, asset)
+		}
+		amountsByAsset[asset] += unspent.Value
+
+		updater.AddInputs([]psetv2.InputArgs{{
+			Txid:    unspent.Txid,
+			TxIndex: uint32(unspent.Index),
+		}})
+		updater.AddInWitnessUtxo(inputIndex, unspent.Prevout)
+		updater.AddInSighashType(inputIndex, txscript.SigHashDefault)
+		if err := updater.AddInTapInternalKey(inputIndex, order.MakerPubKey); err != nil {
+			return nil, err
+		}
+		if err := updater.AddInTapMerkleRoot(inputIndex, tapMerkleRoot[:]); err != nil {
+			return nil, err
+		}
+		inputIndex++
+	}
+
+	// Fee supplier inputs, needed only if the contract does not hold L-BTC
+	if amountsByAsset[lbtc] < fee {
+		for _, unspent := range feeUnspents {
+			if unspentAsset(unspent) != lbtc {
+				return nil, fmt.Errorf("fee unspent %s:%d is not explicit L-BTC", unspent.Txid, unspent.Index)
+			}
+			if _, ok := amountsByAsset[lbtc]; !ok {
+				assets = append(assets, lbtc)
+			}
+			amountsByAsset[lbtc] += unspent.Value
+			inputs = append(inputs, unspent)
+
+			updater.AddInputs([]psetv2.InputArgs{{
+				Txid:    unspent.Txid,
+				TxIndex: uint32(unspent.Index),
+			}})
+			updater.AddInWitnessUtxo(inputIndex, unspent.Prevout)
+			updater.AddInSighashType(inputIndex, txscript.SigHashAll)
+			inputIndex++
+		}
+	}
+	if amountsByAsset[lbtc] < fee {
+		return nil, fmt.Errorf("not enough L-BTC to pay the fees, at least %d sats of fee unspents are needed", fee)
+	}
+	amountsByAsset[lbtc] -= fee
+
+	//outputs
+	for _, asset := range assets {
+		amount := amountsByAsset[asset]
+		if amount == 0 {
+			continue
+		}
+		updater.AddOutputs([]psetv2.OutputArgs{{
+			Asset:  asset,
+			Amount: amount,
+			Script: order.TraderScript,
+		}})
+	}
+
+	updater.AddOutputs([]psetv2.OutputArgs{{
+		Asset:  lbtc,
+		Amount: fee,
+	}})
+
+	// Everything goes back to the maker, blinded to its key
+	if order.IsConfidential() {
+		blindingKeys := outputBlindingKeys(ptx, map[string][]byte{
+			hex.EncodeToString(order.TraderScript): order.MakerBlindingKey,
+		})
+		if err := blindTransaction(ptx, order, inputs, blindingKeys, false); err != nil {
+			return nil, fmt.Errorf("failed to blind cancel transaction: %w", err)
+		}
+	}
+
+	return ptx, nil
 }
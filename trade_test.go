@@ -1,19 +1,27 @@
 package main
 
 import (
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"strconv"
 	"testing"
+	"time"
 
 	"math/rand"
 
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
 	"github.com/stretchr/testify/assert"
+	"github.com/vulpemventures/go-elements/address"
+	"github.com/vulpemventures/go-elements/elementsutil"
 	"github.com/vulpemventures/go-elements/network"
+	"github.com/vulpemventures/go-elements/transaction"
 )
 
 func TestTrade_ExecuteTrade(t *testing.T) {
 	// setup ocean wallet client
-	walletSvc, err := NewWalletService("localhost:18000", "default")
+	walletSvc, err := NewWalletService("localhost:18000", "default", false)
 	if err != nil {
 		t.Fatal("newOrder", err)
 	}
@@ -44,7 +52,7 @@ func TestTrade_ExecuteTrade(t *testing.T) {
 
 func TestTrade_CancelTrade(t *testing.T) {
 	// setup ocean wallet client
-	walletSvc, err := NewWalletService("http://localhost:18000", "default")
+	walletSvc, err := NewWalletService("http://localhost:18000", "default", false)
 	if err != nil {
 		t.Fatal("newOrder")
 	}
@@ -70,6 +78,390 @@ func TestTrade_CancelTrade(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestTrade_PrepareCancelTransaction(t *testing.T) {
+	order := newTestOrder(t, testOrder{})
+
+	trade, err := FromFundedOrder(nil, order, dummyFundingUnspent(t, order))
+	if err != nil {
+		t.Fatal("FromFundedOrder", err)
+	}
+
+	feeUnspent := *dummyFundingUnspent(t, order)
+	feeUnspent.Index = 1
+	feeUnspents := []UTXO{feeUnspent}
+	ptx, err := trade.PrepareCancelTransaction(&feeUnspents, traderScriptExpected, 1000)
+	if err != nil {
+		t.Fatal("PrepareCancelTransaction", err)
+	}
+
+	assert.Len(t, ptx.Inputs, 2)
+	assert.Len(t, ptx.Inputs[0].TapLeafScript, 1)
+	assert.Equal(t, order.RefundScript, ptx.Inputs[0].TapLeafScript[0].Script)
+
+	// refund output, fee change and fee
+	assert.Len(t, ptx.Outputs, 3)
+	assert.Equal(t, order.TraderScript, ptx.Outputs[0].Script)
+	assert.Equal(t, order.Input.Amount, ptx.Outputs[0].Value)
+	assert.Equal(t, uint64(FEE_AMOUNT), ptx.Outputs[2].Value)
+}
+
+func TestTrade_PrepareExpiryRefundTransaction(t *testing.T) {
+	order := newTestOrder(t, testOrder{expiry: time.Hour})
+	assert.False(t, order.IsExpired(time.Now()))
+	assert.True(t, order.IsExpired(order.Expiry))
+
+	withoutExpiry := newTestOrder(t, testOrder{})
+	assert.Empty(t, withoutExpiry.ExpiryScript)
+	assert.False(t, withoutExpiry.IsExpired(time.Now().Add(time.Hour)))
+	assert.NotEqual(t, withoutExpiry.Address, order.Address)
+
+	trade, err := FromFundedOrder(nil, order, dummyFundingUnspent(t, order))
+	if err != nil {
+		t.Fatal("FromFundedOrder", err)
+	}
+
+	feeUnspent := *dummyFundingUnspent(t, order)
+	feeUnspent.Index = 1
+	feeUnspents := []UTXO{feeUnspent}
+	ptx, err := trade.PrepareExpiryRefundTransaction(&feeUnspents, traderScriptExpected, 1000)
+	if err != nil {
+		t.Fatal("PrepareExpiryRefundTransaction", err)
+	}
+
+	assert.Len(t, ptx.Inputs, 2)
+	assert.Equal(t, order.ExpiryScript, ptx.Inputs[0].TapLeafScript[0].Script)
+	assert.Less(t, ptx.Inputs[0].Sequence, uint32(transaction.DefaultSequence))
+
+	utx, err := ptx.UnsignedTx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint32(order.Expiry.Unix()), utx.Locktime)
+
+	// same outputs of the cancel transaction
+	assert.Len(t, ptx.Outputs, 3)
+	assert.Equal(t, order.TraderScript, ptx.Outputs[0].Script)
+	assert.Equal(t, order.Input.Amount, ptx.Outputs[0].Value)
+
+	// under-funded deposits are refunded too, with their whole value
+	assert.True(t, order.RefundsWholeValue(order.ExpiryScript))
+	assert.False(t, order.RefundsWholeValue(order.RefundScript))
+	under := dummyFundingUnspent(t, order)
+	under.Value = 40000
+	under.Prevout.Value, _ = elementsutil.ValueToBytes(under.Value)
+	trade, err = FromFundedOrder(nil, order, under)
+	if err != nil {
+		t.Fatal("FromFundedOrder", err)
+	}
+	ptx, err = trade.PrepareExpiryRefundTransaction(&feeUnspents, traderScriptExpected, 1000)
+	if err != nil {
+		t.Fatal("PrepareExpiryRefundTransaction", err)
+	}
+	assert.Equal(t, uint64(40000), ptx.Outputs[0].Value)
+
+	trade, err = FromFundedOrder(nil, withoutExpiry, dummyFundingUnspent(t, withoutExpiry))
+	if err != nil {
+		t.Fatal("FromFundedOrder", err)
+	}
+	_, err = trade.PrepareExpiryRefundTransaction(&feeUnspents, traderScriptExpected, 1000)
+	assert.Error(t, err)
+}
+
+func TestTrade_PrepareFulfillTransactionReturnsExcess(t *testing.T) {
+	order := newTestOrder(t, testOrder{})
+
+	// an over-funded deposit split in two unspents can't be fulfilled at
+	// once, each contract input is paid on its own
+	first := dummyFundingUnspent(t, order)
+	second := dummyFundingUnspent(t, order)
+	second.Index = 1
+	second.Value = 2000
+	trade, err := FromFundedOrder(nil, order, first, second)
+	if err != nil {
+		t.Fatal("FromFundedOrder", err)
+	}
+	providerUnspent := *dummyFundingUnspent(t, order)
+	providerUnspent.Index = 2
+	providerUnspents := []UTXO{providerUnspent}
+	_, err = trade.PrepareFulfillTransaction(&providerUnspents, &[]UTXO{}, traderScriptExpected, traderScriptExpected, traderScriptExpected, 0, 0)
+	assert.Error(t, err)
+
+	over := dummyFundingUnspent(t, order)
+	over.Value = order.Input.Amount + 2000
+	over.Prevout.Value, _ = elementsutil.ValueToBytes(over.Value)
+	trade, err = FromFundedOrder(nil, order, over)
+	if err != nil {
+		t.Fatal("FromFundedOrder", err)
+	}
+	assert.Equal(t, order.Input.Amount+2000, trade.FundedAmount())
+
+	ptx, err := trade.PrepareFulfillTransaction(&providerUnspents, &[]UTXO{}, traderScriptExpected, traderScriptExpected, traderScriptExpected, 0, 0)
+	if err != nil {
+		t.Fatal("PrepareFulfillTransaction", err)
+	}
+
+	assert.Len(t, ptx.Inputs, 2)
+	assert.Equal(t, order.FulfillLeaf(), ptx.Inputs[0].TapLeafScript[0].Script)
+	// trade output, provider output, excess and fee
+	assert.Len(t, ptx.Outputs, 4)
+	assert.Equal(t, order.Output.Amount, ptx.Outputs[0].Value)
+	assert.Equal(t, uint64(2000), ptx.Outputs[2].Value)
+	assert.Equal(t, order.TraderScript, ptx.Outputs[2].Script)
+}
+
+func TestTrade_PreparePartialFillTransaction(t *testing.T) {
+	order := newTestOrder(t, testOrder{partialFill: true})
+	assert.True(t, order.IsPartial())
+
+	// half of the deposit at the 101/100 price of the order
+	paid, taken, err := order.PartialFillAmounts(order.Input.Amount, 50500)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(50500), paid)
+	assert.Equal(t, uint64(50000), taken)
+
+	// the whole deposit if the limit allows it
+	paid, taken, err = order.PartialFillAmounts(order.Input.Amount, order.Output.Amount*2)
+	assert.NoError(t, err)
+	assert.Equal(t, order.Output.Amount, paid)
+	assert.Equal(t, order.Input.Amount, taken)
+
+	trade, err := FromFundedOrder(nil, order, dummyFundingUnspent(t, order))
+	if err != nil {
+		t.Fatal("FromFundedOrder", err)
+	}
+
+	providerUnspent := *dummyFundingUnspent(t, order)
+	providerUnspent.Index = 2
+	providerUnspents := []UTXO{providerUnspent}
+	ptx, err := trade.PreparePartialFillTransaction(50500, &providerUnspents, &[]UTXO{}, traderScriptExpected, traderScriptExpected, traderScriptExpected, 0, 0)
+	if err != nil {
+		t.Fatal("PreparePartialFillTransaction", err)
+	}
+
+	assert.Len(t, ptx.Inputs, 2)
+	assert.Equal(t, order.PartialFillScript, ptx.Inputs[0].TapLeafScript[0].Script)
+	// trade output, remainder, provider output and fee
+	assert.Len(t, ptx.Outputs, 4)
+	assert.Equal(t, uint64(50500), ptx.Outputs[0].Value)
+	assert.Equal(t, order.TraderScript, ptx.Outputs[0].Script)
+	assert.Equal(t, uint64(50000), ptx.Outputs[1].Value)
+	assert.Equal(t, trade.FundingPayment.Script, ptx.Outputs[1].Script)
+	assert.Equal(t, uint64(50000), ptx.Outputs[2].Value)
+
+	// paying more than needed for the whole deposit is refused
+	_, err = trade.PreparePartialFillTransaction(order.Output.Amount+1, &providerUnspents, &[]UTXO{}, traderScriptExpected, traderScriptExpected, traderScriptExpected, 0, 0)
+	assert.Error(t, err)
+
+	// partial fills are not supported by confidential orders
+	makerBlindingKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = testOrder{makerBlindingKey: hex.EncodeToString(makerBlindingKey.PubKey().SerializeCompressed()), partialFill: true}.new()
+	assert.Error(t, err)
+}
+
+func TestPrepareKeyPathCancelTransaction(t *testing.T) {
+	makerKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	makerPubKeyHex := hex.EncodeToString(schnorr.SerializePubKey(makerKey.PubKey()))
+
+	order := newTestOrder(t, testOrder{makerPubKey: makerPubKeyHex})
+
+	withoutKey := newTestOrder(t, testOrder{})
+	assert.NotEqual(t, withoutKey.Address, order.Address)
+
+	// an under-funded deposit split in two unspents
+	first := dummyFundingUnspent(t, order)
+	first.Value = 40000
+	second := dummyFundingUnspent(t, order)
+	second.Index = 1
+	second.Value = 30000
+
+	ptx, err := PrepareKeyPathCancelTransaction(order, []*UTXO{first, second}, nil, &network.Testnet, 0)
+	if err != nil {
+		t.Fatal("PrepareKeyPathCancelTransaction", err)
+	}
+
+	assert.Len(t, ptx.Inputs, 2)
+	for _, input := range ptx.Inputs {
+		assert.Equal(t, order.MakerPubKey, input.TapInternalKey)
+		assert.NotEmpty(t, input.TapMerkleRoot)
+	}
+	assert.Len(t, ptx.Outputs, 2)
+	assert.Equal(t, order.TraderScript, ptx.Outputs[0].Script)
+	assert.Equal(t, uint64(70000-FEE_AMOUNT), ptx.Outputs[0].Value)
+
+	// with a fee rate the fee is sized on the transaction
+	sized, err := PrepareKeyPathCancelTransaction(order, []*UTXO{first, second}, nil, &network.Testnet, 0.1)
+	if err != nil {
+		t.Fatal("PrepareKeyPathCancelTransaction", err)
+	}
+	vsize, err := estimateVsize(sized, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, sized.Outputs, 2) {
+		fee := sized.Outputs[1].Value
+		assert.Equal(t, feeForVsize(vsize, 0.1), fee)
+		assert.Less(t, fee, uint64(FEE_AMOUNT))
+		assert.Equal(t, 70000-fee, sized.Outputs[0].Value)
+	}
+
+	_, err = PrepareKeyPathCancelTransaction(withoutKey, []*UTXO{first}, nil, &network.Testnet, 0)
+	assert.Error(t, err)
+
+	// contracts without L-BTC are funded by the maker fee unspents
+	usdtOrder := newTestOrder(t, testOrder{makerPubKey: makerPubKeyHex, quote: usdtBuyQuote})
+	deposit := dummyFundingUnspent(t, usdtOrder)
+	_, err = PrepareKeyPathCancelTransaction(usdtOrder, []*UTXO{deposit}, nil, &network.Testnet, 0)
+	assert.Error(t, err)
+
+	lbtcAsset, _ := elementsutil.AssetHashToBytes(currencyToAsset["L-BTC"].AssetHash)
+	feeValue, _ := elementsutil.ValueToBytes(1000)
+	feeUnspent := &UTXO{Txid: deposit.Txid, Index: 3, Value: 1000, Prevout: transaction.NewTxOutput(lbtcAsset, feeValue, traderScriptExpected)}
+	funded, err := PrepareKeyPathCancelTransaction(usdtOrder, []*UTXO{deposit}, []*UTXO{feeUnspent}, &network.Testnet, 0)
+	if err != nil {
+		t.Fatal("PrepareKeyPathCancelTransaction", err)
+	}
+	assert.Len(t, funded.Inputs, 2)
+	// the deposit and the fee change back to the trader, and the fee
+	if assert.Len(t, funded.Outputs, 3) {
+		assert.Equal(t, usdtOrder.Input.Amount, funded.Outputs[0].Value)
+		assert.Equal(t, uint64(1000-FEE_AMOUNT), funded.Outputs[1].Value)
+		assert.Equal(t, uint64(FEE_AMOUNT), funded.Outputs[2].Value)
+	}
+	_, err = PrepareKeyPathCancelTransaction(usdtOrder, []*UTXO{deposit}, []*UTXO{deposit}, &network.Testnet, 0)
+	assert.Error(t, err)
+
+	// the contract inputs must be signed via key-path by the maker
+	trade, err := FromFundedOrder(nil, order, first, second)
+	if err != nil {
+		t.Fatal("FromFundedOrder", err)
+	}
+	unsigned, err := ptx.ToBase64()
+	assert.NoError(t, err)
+	err = trade.CancelTradeWithSignedPset(unsigned)
+	assert.ErrorContains(t, err, "not signed via key-path")
+
+	// finalized by the wallet with a single signature witness element
+	keyPathWitness := append([]byte{0x01, 0x40}, make([]byte, 64)...)
+	ptx.Inputs[0].FinalScriptWitness = keyPathWitness
+	ptx.Inputs[1].FinalScriptWitness = keyPathWitness
+	other, err := FromFundedOrder(nil, order, second, first)
+	if err != nil {
+		t.Fatal("FromFundedOrder", err)
+	}
+	signed, err := ptx.ToBase64()
+	assert.NoError(t, err)
+	err = other.CancelTradeWithSignedPset(signed)
+	assert.ErrorContains(t, err, "does not spend the contract unspent")
+}
+
+func TestOrder_VerifyOwnership(t *testing.T) {
+	makerKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	makerPubKey := schnorr.SerializePubKey(makerKey.PubKey())
+	otherKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signOrder := func(order *Order, key *btcec.PrivateKey) string {
+		hash := sha256.Sum256([]byte(order.ID))
+		signature, err := schnorr.Sign(key, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hex.EncodeToString(signature.Serialize())
+	}
+
+	order := newTestOrder(t, testOrder{makerPubKey: hex.EncodeToString(makerPubKey)})
+	assert.NoError(t, order.VerifyOwnership(signOrder(order, makerKey)))
+	assert.Error(t, order.VerifyOwnership(signOrder(order, otherKey)))
+	assert.Error(t, order.VerifyOwnership(""))
+	assert.Error(t, order.VerifyOwnership("zz"))
+
+	// without maker key, the one of the taproot trader script
+	taprootScript := append([]byte{0x51, 0x20}, makerPubKey...)
+	withoutKey := newTestOrder(t, testOrder{traderScript: taprootScript})
+	assert.NoError(t, withoutKey.VerifyOwnership(signOrder(withoutKey, makerKey)))
+	assert.Error(t, withoutKey.VerifyOwnership(signOrder(order, makerKey)))
+
+	segwitScript := append([]byte{0x00, 0x14}, make([]byte, 20)...)
+	withoutOwner := newTestOrder(t, testOrder{traderScript: segwitScript})
+	assert.Error(t, withoutOwner.VerifyOwnership(signOrder(withoutOwner, makerKey)))
+}
+
+func dummyFundingUnspent(t *testing.T, order *Order) *UTXO {
+	script, err := address.ToOutputScript(order.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	asset, _ := elementsutil.AssetHashToBytes(order.Input.Asset)
+	value, _ := elementsutil.ValueToBytes(order.Input.Amount)
+
+	return &UTXO{
+		Txid:    "b7e6664c79fc4229504fc0521c661d431e0be2cb25be230bbaf6d8112fc89efe",
+		Index:   0,
+		Value:   order.Input.Amount,
+		Prevout: transaction.NewTxOutput(asset, value, script),
+	}
+}
+
+// testOrder are the terms of an order of the tests, the zero ones replaced
+// by the defaults: the quote of lbtcQuote, at the market rate of its price,
+// paid to traderScriptExpected on testnet.
+type testOrder struct {
+	traderScript     []byte
+	makerPubKey      string
+	makerBlindingKey string
+	partialFill      bool
+	expiry           time.Duration
+	quote            *Quote
+	rate             float64
+	net              *network.Network
+}
+
+// lbtcQuote trades 0.001 L-BTC for 0.00101 L-BTC at rate 1.
+var lbtcQuote = &Quote{Type: "Buy", Price: 1, InputCurrency: "L-BTC", InputValue: 0.001, OutputCurrency: "L-BTC", OutputValue: 0.00101}
+
+// usdtBuyQuote buys 0.001 L-BTC for 30 USDT, on a pair whose price is not 1.
+var usdtBuyQuote = &Quote{Type: "Buy", Price: 30000, InputCurrency: "USDT", InputValue: 30, OutputCurrency: "L-BTC", OutputValue: 0.001}
+
+func (o testOrder) new() (*Order, error) {
+	traderScript := o.traderScript
+	if traderScript == nil {
+		traderScript = traderScriptExpected
+	}
+	quote := o.quote
+	if quote == nil {
+		quote = lbtcQuote
+	}
+	rate := o.rate
+	if rate == 0 {
+		rate = quote.Price
+	}
+	net := o.net
+	if net == nil {
+		net = &network.Testnet
+	}
+	return NewOrder(hex.EncodeToString(traderScript), o.makerPubKey, o.makerBlindingKey, o.partialFill, o.expiry, quote, rate, net)
+}
+
+// newTestOrder returns the order with the given terms, failing the test if
+// it can't be created.
+func newTestOrder(t *testing.T, o testOrder) *Order {
+	t.Helper()
+	order, err := o.new()
+	if err != nil {
+		t.Fatal("newOrder", err)
+	}
+	return order
+}
+
 func submitDummyOrder() (*Order, error) {
 	// Generate random traderScript
 	traderScript := make([]byte, 32)
@@ -84,7 +476,10 @@ func submitDummyOrder() (*Order, error) {
 	outputValue := generateRandomValue()
 
 	// Create the order with the generated values
-	return NewOrder(traderScriptHex, inputCurrency, inputValue, outputCurrency, outputValue, 2, &network.Testnet)
+	inputValueFloat, _ := strconv.ParseFloat(inputValue, 64)
+	outputValueFloat, _ := strconv.ParseFloat(outputValue, 64)
+	quote := &Quote{Type: "Buy", Price: 2, InputCurrency: inputCurrency, InputValue: inputValueFloat, OutputCurrency: outputCurrency, OutputValue: outputValueFloat}
+	return NewOrder(traderScriptHex, "", "", false, 0, quote, quote.Price, &network.Testnet)
 }
 
 func generateRandomCurrency() string {
@@ -102,3 +497,18 @@ func generateRandomValue() string {
 	randomValue := minValue + rand.Float64()*(maxValue-minValue)
 	return fmt.Sprintf("%.2f", randomValue)
 }
+
+func TestFromFundedOrder_UsesOrderNetwork(t *testing.T) {
+	order := newTestOrder(t, testOrder{net: &network.Liquid})
+	trade, err := FromFundedOrder(nil, order, dummyFundingUnspent(t, order))
+	if err != nil {
+		t.Fatal("FromFundedOrder", err)
+	}
+	addr, err := trade.FundingPayment.TaprootAddress()
+	assert.NoError(t, err)
+	assert.Equal(t, order.Address, addr)
+
+	order.Network = nil
+	_, err = FromFundedOrder(nil, order, dummyFundingUnspent(t, order))
+	assert.Error(t, err)
+}
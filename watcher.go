@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/vulpemventures/go-elements/address"
+	"github.com/vulpemventures/go-elements/network"
+	"github.com/vulpemventures/go-elements/transaction"
+)
+
+// defaultWatchInterval is the reconciliation interval of the watchers
+// created with no positive one.
+const defaultWatchInterval = time.Minute
+
+// Watcher fulfills the orders as soon as the wallet notifies a deposit to
+// their contract. Every interval it reconciles all the orders to fulfill, in
+// case a notification is missed, and marks and sweeps the expired ones.
+type Watcher struct {
+	repo         OrderRepository
+	walletSvc    WalletService
+	hub          *NotificationHub
+	chain        ChainSource
+	feeEstimator *FeeEstimator
+	net          *network.Network
+	interval     time.Duration
+
+	// one round at a time, or the same deposit could be spent twice
+	lock sync.Mutex
+}
+
+func NewWatcher(repo OrderRepository, walletSvc WalletService, hub *NotificationHub, chain ChainSource, feeEstimator *FeeEstimator, net *network.Network, interval time.Duration) *Watcher {
+	// the expired orders are found only by reconciling
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	return &Watcher{
+		repo:         repo,
+		walletSvc:    walletSvc,
+		hub:          hub,
+		chain:        chain,
+		feeEstimator: feeEstimator,
+		net:          net,
+		interval:     interval,
+	}
+}
+
+// Run watches the orders until the context is done.
+func (w *Watcher) Run(ctx context.Context) {
+	sub := w.hub.Subscribe(nil)
+	defer sub.Unsubscribe()
+	notifications := sub.C
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.Reconcile()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification, ok := <-notifications:
+			if !ok {
+				// keep reconciling without the notifications
+				notifications = nil
+				continue
+			}
+			w.HandleNotification(notification)
+		case <-ticker.C:
+			w.Reconcile()
+		}
+	}
+}
+
+// Reconcile fulfills the funded orders and sweeps the expired ones whose
+// timelock matured.
+func (w *Watcher) Reconcile() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	feeRate := w.feeEstimator.FeeRate()
+	orders, err := w.repo.FetchOrdersToFulfill(w.net.Name)
+	if err != nil {
+		log.Printf("error in fetching orders: %v", err)
+		return
+	}
+	w.fulfill(orders, nil, feeRate)
+
+	expired, err := w.repo.FetchOrdersToSweep(w.net.Name)
+	if err != nil {
+		log.Printf("error in fetching expired orders: %v", err)
+		return
+	}
+	for _, order := range expired {
+		err = sweepExpiredOrder(w.repo, order, w.walletSvc, w.chain, feeRate)
+		if err != nil {
+			log.Printf("error in sweeping expired order ID %s : %v", order.ID, err)
+		}
+	}
+}
+
+// CancelOrder refunds the deposits of the order via the refund leaf. It waits
+// for the running round, if any, or a deposit being fulfilled could be
+// cancelled too.
+func (w *Watcher) CancelOrder(order *Order) (string, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	// the order could have been fulfilled in the meantime
+	if err := w.checkStatus(order, isCancellable); err != nil {
+		return "", err
+	}
+	return cancelOrder(w.repo, order, w.walletSvc, w.chain, w.feeEstimator.FeeRate())
+}
+
+// CancelOrderWithSignedPset broadcasts the cancel transaction of the order
+// signed by the maker via key-path, in between the rounds as CancelOrder.
+func (w *Watcher) CancelOrderWithSignedPset(order *Order, signedPsetBase64 string) (string, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if err := w.checkStatus(order, isKeyPathCancellable); err != nil {
+		return "", err
+	}
+	return cancelOrderWithSignedPset(w.repo, order, w.walletSvc, w.chain, signedPsetBase64)
+}
+
+func (w *Watcher) checkStatus(order *Order, cancellable func(status string) bool) error {
+	_, status, err := w.repo.FetchOrderByID(order.ID)
+	if err != nil {
+		return fmt.Errorf("error fetching order: %w", err)
+	}
+	if !cancellable(status) {
+		return fmt.Errorf("order is %s and cannot be cancelled", status)
+	}
+	return nil
+}
+
+// HandleNotification fulfills the orders funded by the notified transaction.
+// The other orders are left to the next reconciliation.
+func (w *Watcher) HandleNotification(notification *TransactionNotification) {
+	if notification.TxHex == "" {
+		return
+	}
+	tx, err := transaction.NewTxFromHex(notification.TxHex)
+	if err != nil {
+		log.Printf("error parsing notified tx %s: %v", notification.TxId, err)
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	orders, err := w.repo.FetchOrdersToFulfill(w.net.Name)
+	if err != nil {
+		log.Printf("error in fetching orders: %v", err)
+		return
+	}
+
+	funded := []*Order{}
+	notified := make(map[string][]*UTXO)
+	for _, order := range orders {
+		script, err := address.ToOutputScript(order.Address)
+		if err != nil {
+			continue
+		}
+		unspents := notifiedUnspents(tx, script)
+		if len(unspents) == 0 {
+			continue
+		}
+		funded = append(funded, order)
+		// once confirmed the chain source knows the deposits, and whether they are
+		// already spent
+		if !notification.Confirmed {
+			notified[order.ID] = unspents
+		}
+	}
+	if len(funded) == 0 {
+		return
+	}
+
+	w.fulfill(funded, notified, w.feeEstimator.FeeRate())
+}
+
+func (w *Watcher) fulfill(orders []*Order, notified map[string][]*UTXO, feeRate float64) {
+	errs := watchForTradesInBatch(w.repo, orders, notified, w.walletSvc, w.chain, feeRate)
+	for _, order := range orders {
+		if err, ok := errs[order.ID]; ok {
+			log.Printf("error in fulfilling order of %f %s: ID %s : %v", float64(order.Output.Amount), order.Output.Asset, order.ID, err)
+		}
+	}
+}
+
+// notifiedUnspents returns the outputs of the transaction paying to the
+// script, as unspents of the contract.
+func notifiedUnspents(tx *transaction.Transaction, script []byte) []*UTXO {
+	unspents := []*UTXO{}
+	txid := tx.TxHash().String()
+	for i, output := range tx.Outputs {
+		if !bytes.Equal(output.Script, script) {
+			continue
+		}
+		unspent, err := unspentFromPrevout(txid, i, output, false)
+		if err != nil {
+			continue
+		}
+		unspents = append(unspents, unspent)
+	}
+	return unspents
+}
+
+// withNotifiedUnspents adds to the unspents of the contract the notified ones
+// the chain source does not know yet.
+func withNotifiedUnspents(unspents, notified []*UTXO) []*UTXO {
+	for _, n := range notified {
+		known := false
+		for _, unspent := range unspents {
+			if unspent.Txid == n.Txid && unspent.Index == n.Index {
+				known = true
+				break
+			}
+		}
+		if !known {
+			unspents = append(unspents, n)
+		}
+	}
+	return unspents
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vulpemventures/go-elements/elementsutil"
+	"github.com/vulpemventures/go-elements/network"
+	"github.com/vulpemventures/go-elements/transaction"
+)
+
+// emptyWallet can't fulfill any trade.
+type emptyWallet struct {
+	WalletService
+}
+
+func (w *emptyWallet) GetAddress(ctx context.Context, isChange bool) (string, []byte, error) {
+	return "", nil, fmt.Errorf("wallet locked")
+}
+
+func (w *emptyWallet) SelectUtxos(ctx context.Context, asset string, amount uint64) ([]UTXO, uint64, error) {
+	return nil, 0, fmt.Errorf("not enough funds")
+}
+
+func TestNotifiedUnspents(t *testing.T) {
+	order := newTestOrder(t, testOrder{})
+	funding := dummyFundingUnspent(t, order)
+	tx, _ := transaction.NewTxFromHex(fundingTxHex(t, order))
+
+	unspents := notifiedUnspents(tx, funding.Prevout.Script)
+	if assert.Len(t, unspents, 1) {
+		assert.Equal(t, tx.TxHash().String(), unspents[0].Txid)
+		assert.Equal(t, 0, unspents[0].Index)
+		assert.Equal(t, order.Input.Amount, unspents[0].Value)
+		assert.NoError(t, verifyUnspent(order, unspents[0]))
+	}
+	assert.Empty(t, notifiedUnspents(tx, traderScriptExpected))
+
+	// the unspents already known by Esplora are not added twice
+	known := &UTXO{Txid: unspents[0].Txid, Index: 0}
+	assert.Equal(t, []*UTXO{known}, withNotifiedUnspents([]*UTXO{known}, unspents))
+	assert.Equal(t, unspents, withNotifiedUnspents(nil, unspents))
+}
+
+func TestWatcher_HandleNotification(t *testing.T) {
+	// Esplora has not indexed the deposit yet
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+	esplora := &Esplora{BaseAPIURL: server.URL, NetworkName: network.Testnet.Name}
+
+	repo := NewInMemoryOrderRepository()
+	order := newTestOrder(t, testOrder{expiry: time.Hour})
+	assert.NoError(t, repo.SaveOrder(order))
+
+	watcher := NewWatcher(repo, &emptyWallet{}, NewNotificationHub(&emptyWallet{}), esplora, NewFeeEstimator(esplora, 0.1, 1), &network.Testnet, time.Minute)
+
+	// unrelated transactions are ignored
+	other := transaction.NewTx(2)
+	other.AddOutput(dummyFundingUnspent(t, order).Prevout)
+	other.Outputs[0].Script = traderScriptExpected
+	otherHex, _ := other.ToHex()
+	watcher.HandleNotification(&TransactionNotification{TxId: "other", TxHex: otherHex})
+	_, status, err := repo.FetchOrderByID(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Pending", status)
+
+	// the notified deposit is reconciled right away
+	txHex := fundingTxHex(t, order)
+	tx, _ := transaction.NewTxFromHex(txHex)
+	watcher.HandleNotification(&TransactionNotification{TxId: tx.TxHash().String(), TxHex: txHex})
+	_, status, err = repo.FetchOrderByID(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Funded", status)
+	statuses, err := repo.FetchOrderStatuses(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, tx.TxHash().String(), statuses[len(statuses)-1].TxHash)
+}
+
+func TestWatcher_RunHandlesNotifications(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+	esplora := &Esplora{BaseAPIURL: server.URL, NetworkName: network.Testnet.Name}
+
+	repo := NewInMemoryOrderRepository()
+	order := newTestOrder(t, testOrder{expiry: time.Hour})
+	assert.NoError(t, repo.SaveOrder(order))
+
+	// the notifications are handled right away, not at the next reconciliation
+	hub := NewNotificationHub(&emptyWallet{})
+	watcher := NewWatcher(repo, &emptyWallet{}, hub, esplora, NewFeeEstimator(esplora, 0.1, 1), &network.Testnet, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx)
+
+	txHex := fundingTxHex(t, order)
+	tx, _ := transaction.NewTxFromHex(txHex)
+	assert.Eventually(t, func() bool {
+		hub.publish(&TransactionNotification{TxId: tx.TxHash().String(), TxHex: txHex})
+		_, status, err := repo.FetchOrderByID(order.ID)
+		return err == nil && status == "Funded"
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestWatcher_RunMarksExpiredOrders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+	esplora := &Esplora{BaseAPIURL: server.URL, NetworkName: network.Testnet.Name}
+
+	repo := NewInMemoryOrderRepository()
+	hub := NewNotificationHub(&emptyWallet{})
+	// with no interval the orders are reconciled every default one
+	assert.Equal(t, defaultWatchInterval, NewWatcher(repo, &emptyWallet{}, hub, esplora, nil, &network.Testnet, -time.Second).interval)
+
+	watcher := NewWatcher(repo, &emptyWallet{}, hub, esplora, NewFeeEstimator(esplora, 0.1, 1), &network.Testnet, 10*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx)
+
+	// expired after the first reconciliation, with no deposit notified
+	order := newTestOrder(t, testOrder{expiry: time.Hour})
+	order.Expiry = time.Now().Add(-time.Second)
+	assert.NoError(t, repo.SaveOrder(order))
+	assert.Eventually(t, func() bool {
+		_, status, err := repo.FetchOrderByID(order.ID)
+		return err == nil && status == "Expired"
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestWatcher_CancelOrder(t *testing.T) {
+	repo := NewInMemoryOrderRepository()
+	order := newTestOrder(t, testOrder{})
+	assert.NoError(t, repo.SaveOrder(order))
+	watcher := NewWatcher(repo, &emptyWallet{}, NewNotificationHub(&emptyWallet{}), nil, nil, &network.Testnet, time.Minute)
+
+	// fulfilled while the cancel request was waiting for the round to end
+	assert.NoError(t, repo.UpdateOrderStatusWithTxHash(order.ID, "Fulfilled", "txid"))
+	_, err := watcher.CancelOrder(order)
+	assert.ErrorContains(t, err, "order is Fulfilled and cannot be cancelled")
+	_, err = watcher.CancelOrderWithSignedPset(order, "")
+	assert.ErrorContains(t, err, "order is Fulfilled and cannot be cancelled")
+}
+
+func TestWatcher_WrongAssetBeforeDeposit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+	esplora := &Esplora{BaseAPIURL: server.URL, NetworkName: network.Testnet.Name}
+
+	repo := NewInMemoryOrderRepository()
+	order := newTestOrder(t, testOrder{expiry: time.Hour})
+	assert.NoError(t, repo.SaveOrder(order))
+	watcher := NewWatcher(repo, &emptyWallet{}, NewNotificationHub(&emptyWallet{}), esplora, NewFeeEstimator(esplora, 0.1, 1), &network.Testnet, time.Minute)
+
+	// anyone can send dust of another asset to the contract
+	dust := transaction.NewTx(2)
+	output := dummyFundingUnspent(t, order).Prevout
+	output.Asset, _ = elementsutil.AssetHashToBytes(currencyToAsset["USDT"].AssetHash)
+	output.Value, _ = elementsutil.ValueToBytes(1)
+	dust.AddOutput(output)
+	dustHex, _ := dust.ToHex()
+	watcher.HandleNotification(&TransactionNotification{TxId: dust.TxHash().String(), TxHex: dustHex})
+	flagged, status, err := repo.FetchOrderByID(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Pending", status)
+	assert.Equal(t, 1, flagged.Mismatched)
+
+	// the valid deposit that follows is still reconciled
+	txHex := fundingTxHex(t, order)
+	tx, _ := transaction.NewTxFromHex(txHex)
+	watcher.HandleNotification(&TransactionNotification{TxId: tx.TxHash().String(), TxHex: txHex})
+	_, status, err = repo.FetchOrderByID(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Funded", status)
+}
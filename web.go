@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// offerStatusData returns the data of the status.html section of the offer
+// page: the current status, what it means for the maker and the history.
+func offerStatusData(order *Order, status string, statuses []*StatusChange, networkName string) gin.H {
+	var timeline []map[string]interface{}
+	for _, change := range statuses {
+		entry := map[string]interface{}{
+			"status": change.Status,
+			"date":   change.Timestamp.Format("2006-01-02 15:04:05"),
+		}
+		if len(change.TxHash) > 0 {
+			entry["txID"] = change.TxHash
+			entry["txIDShort"] = shortTxID(change.TxHash)
+			entry["explorerTxURL"] = fmt.Sprintf("%s/tx/%s", EsploraURLs[networkName], change.TxHash)
+		}
+		timeline = append(timeline, entry)
+	}
+
+	expiry := ""
+	if len(order.ExpiryScript) > 0 {
+		expiry = order.Expiry.UTC().Format("2006-01-02 15:04:05")
+	}
+	return gin.H{
+		"id":            order.ID,
+		"inputValue":    order.InputValue(),
+		"inputCurrency": assetToCurrency[order.Input.Asset],
+		"status":        status,
+		"partial":       order.IsPartial(),
+		"topUp":         order.CombinesDeposits(),
+		"keyPathCancel": len(order.MakerPubKey) > 0,
+		"mismatched":    order.Mismatched,
+		"expiry":        expiry,
+		"timeline":      timeline,
+	}
+}
+
+// offerTransactionsData returns the data of the transactions.html section of
+// the offer page, split into confirmed and pending transactions.
+func offerTransactionsData(transactions []Transaction, networkName string) gin.H {
+	var confirmedTransactions, pendingTransactions []map[string]interface{}
+	for _, tx := range transactions {
+		transaction := map[string]interface{}{
+			"txID":          tx.TxID,
+			"txIDShort":     shortTxID(tx.TxID),
+			"confirmed":     tx.Status.Confirmed,
+			"date":          time.Unix(int64(tx.Status.BlockTime), 0).Format("2006-01-02 15:04:05"),
+			"explorerTxURL": fmt.Sprintf("%s/tx/%s", EsploraURLs[networkName], tx.TxID),
+		}
+
+		if tx.Status.Confirmed {
+			confirmedTransactions = append(confirmedTransactions, transaction)
+		} else {
+			pendingTransactions = append(pendingTransactions, transaction)
+		}
+	}
+
+	return gin.H{
+		"confirmedTransactions": confirmedTransactions,
+		"pendingTransactions":   pendingTransactions,
+	}
+}
+
+// withNotifiedTransaction adds the notified transaction to the ones of the
+// address, or updates its confirmation, in case Esplora is lagging behind.
+func withNotifiedTransaction(transactions []Transaction, notification *TransactionNotification) []Transaction {
+	for i, tx := range transactions {
+		if tx.TxID == notification.TxId {
+			if notification.Confirmed && !tx.Status.Confirmed {
+				transactions[i].Status.Confirmed = true
+				transactions[i].Status.BlockTime = int(notification.Timestamp)
+			}
+			return transactions
+		}
+	}
+
+	tx := Transaction{TxID: notification.TxId}
+	tx.Status.Confirmed = notification.Confirmed
+	tx.Status.BlockTime = int(notification.Timestamp)
+	return append(transactions, tx)
+}
+
+// renderFragment executes a template of the web directory on a single line,
+// to be sent as SSE data.
+func renderFragment(webDir, name string, data interface{}) (string, error) {
+	tmpl, err := template.ParseFiles(filepath.Join(webDir, name))
+	if err != nil {
+		return "", err
+	}
+	var html bytes.Buffer
+	err = tmpl.Execute(&html, data)
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(html.String(), "\n", " "), nil
+}
+
+func shortTxID(txid string) string {
+	if len(txid) <= 12 {
+		return txid
+	}
+	return txid[:6] + "..." + txid[len(txid)-6:]
+}